@@ -0,0 +1,310 @@
+// Package backup writes and restores portable archives of the whole store:
+// a gzip-compressed tar holding a manifest, one JSON Lines file per
+// collection and the blob store files the records point at. Records are
+// stored under their field names independent of the storage backend, so an
+// archive taken from Mongo restores into Postgres or bolt and vice versa.
+//
+// Layout:
+//
+//	manifest.json
+//	data/<collection>.jsonl
+//	blobs/<blob key>
+package backup
+
+import (
+	"archive/tar"
+	"backend/db"
+	"backend/storage"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Format identifies backup archives in the manifest
+const Format = "placement-backup"
+
+// Version is the archive layout written by Write. Read accepts this and
+// every earlier version.
+const Version = 1
+
+// maxRecordSize bounds a single JSON Lines record
+const maxRecordSize = 16 << 20
+
+// Manifest describes an archive. It is always the first entry.
+type Manifest struct {
+	Format    string `json:"format"`
+	Version   int    `json:"version"`
+	CreatedAt string `json:"created_at"`
+	// Source is the storage mode the archive was taken from
+	Source      string         `json:"source"`
+	Collections map[string]int `json:"collections"`
+	Blobs       int            `json:"blobs"`
+	// MissingBlobs are keys referenced by records but absent from the blob
+	// store when the archive was written
+	MissingBlobs []string `json:"missing_blobs,omitempty"`
+}
+
+// String summarises the record and blob counts
+func (m Manifest) String() string {
+	parts := make([]string, 0, len(m.Collections)+1)
+	for _, name := range db.Collections() {
+		if n := m.Collections[name]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, name))
+		}
+	}
+	parts = append(parts, fmt.Sprintf("%d blobs", m.Blobs))
+	return strings.Join(parts, ", ")
+}
+
+// Write exports the store and writes it to w as an archive
+func Write(ctx context.Context, w io.Writer) (Manifest, error) {
+	data, err := db.Export(ctx)
+	if err != nil {
+		return Manifest{}, err
+	}
+	now := time.Now().UTC()
+	m := Manifest{
+		Format:      Format,
+		Version:     Version,
+		CreatedAt:   now.Format(time.RFC3339),
+		Source:      db.Mode(),
+		Collections: map[string]int{},
+	}
+	blobs := make([]string, 0)
+	for _, key := range db.BlobKeys(data) {
+		if _, err := storage.Size(key); err != nil {
+			m.MissingBlobs = append(m.MissingBlobs, key)
+			continue
+		}
+		blobs = append(blobs, key)
+	}
+	m.Blobs = len(blobs)
+	for _, name := range db.Collections() {
+		m.Collections[name] = len(data[name])
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	put := func(name string, body []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(body)), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(body)
+		return err
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return m, err
+	}
+	if err := put("manifest.json", manifest); err != nil {
+		return m, err
+	}
+	for _, name := range db.Collections() {
+		var buf bytes.Buffer
+		for _, rec := range data[name] {
+			line, err := db.EncodeRecord(rec)
+			if err != nil {
+				return m, fmt.Errorf("%s: %w", name, err)
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		if err := put("data/"+name+".jsonl", buf.Bytes()); err != nil {
+			return m, err
+		}
+	}
+	for _, key := range blobs {
+		if err := writeBlob(tw, key, now); err != nil {
+			return m, fmt.Errorf("blob %s: %w", key, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return m, err
+	}
+	return m, gz.Close()
+}
+
+func writeBlob(tw *tar.Writer, key string, modTime time.Time) error {
+	size, err := storage.Size(key)
+	if err != nil {
+		return err
+	}
+	f, err := storage.Open(key)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hdr := &tar.Header{Name: "blobs/" + key, Mode: 0o644, Size: size, ModTime: modTime}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	// a blob that changed size since Size fails the copy rather than
+	// corrupting the archive
+	_, err = io.CopyN(tw, f, size)
+	return err
+}
+
+// invalid reports a malformed archive as a validation error
+func invalid(field, format string, args ...interface{}) error {
+	return &db.ValidationError{Fields: []db.FieldError{{Field: field, Message: fmt.Sprintf(format, args...)}}}
+}
+
+// Read restores an archive, replacing all data. The whole archive is read
+// and checked (version, record decoding, referential integrity, blobs)
+// before anything changes; blobs are written to the blob store after the
+// records are imported. It returns the manifest with the restored counts.
+func Read(ctx context.Context, r io.Reader) (Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Manifest{}, invalid("archive", "not a backup archive")
+	}
+	defer gz.Close()
+
+	staging, err := os.MkdirTemp("", "restore-*")
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer os.RemoveAll(staging)
+
+	var m Manifest
+	data := db.Dataset{}
+	staged := map[string]string{}
+	tr := tar.NewReader(gz)
+	for first := true; ; first = false {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return m, invalid("archive", "corrupt archive: %v", err)
+		}
+		if first {
+			if hdr.Name != "manifest.json" {
+				return m, invalid("archive", "not a backup archive")
+			}
+			if err := readManifest(tr, &m); err != nil {
+				return m, err
+			}
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(hdr.Name, "data/") && path.Ext(hdr.Name) == ".jsonl":
+			name := strings.TrimSuffix(path.Base(hdr.Name), ".jsonl")
+			rows, err := readRecords(tr, hdr.Name, name)
+			if err != nil {
+				return m, err
+			}
+			data[name] = rows
+		case strings.HasPrefix(hdr.Name, "blobs/"):
+			key := strings.TrimPrefix(hdr.Name, "blobs/")
+			if key == "" || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") || path.IsAbs(key) {
+				return m, invalid(hdr.Name, "invalid blob key")
+			}
+			f, err := os.CreateTemp(staging, "blob-*")
+			if err != nil {
+				return m, err
+			}
+			_, err = io.Copy(f, tr)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return m, err
+			}
+			staged[key] = f.Name()
+		}
+	}
+	if m.Format == "" {
+		return m, invalid("archive", "not a backup archive")
+	}
+
+	missing := map[string]bool{}
+	for _, key := range m.MissingBlobs {
+		missing[key] = true
+	}
+	verr := &db.ValidationError{}
+	for _, key := range db.BlobKeys(data) {
+		if _, ok := staged[key]; !ok && !missing[key] {
+			verr.Fields = append(verr.Fields, db.FieldError{Field: "blobs/" + key, Message: "missing from archive"})
+		}
+	}
+	if len(verr.Fields) > 0 {
+		return m, verr
+	}
+
+	if err := db.Import(ctx, data); err != nil {
+		return m, err
+	}
+	for key, file := range staged {
+		if err := installBlob(key, file); err != nil {
+			return m, fmt.Errorf("records restored but blob %s failed: %w", key, err)
+		}
+	}
+
+	m.Collections = map[string]int{}
+	for _, name := range db.Collections() {
+		m.Collections[name] = len(data[name])
+	}
+	m.Blobs = len(staged)
+	return m, nil
+}
+
+func readManifest(r io.Reader, m *Manifest) error {
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return invalid("manifest.json", "invalid manifest: %v", err)
+	}
+	if m.Format != Format {
+		return invalid("manifest.json", "not a backup archive (format %q)", m.Format)
+	}
+	if m.Version < 1 || m.Version > Version {
+		return invalid("manifest.json", "archive version %d is not supported (this server reads up to %d)", m.Version, Version)
+	}
+	return nil
+}
+
+func readRecords(r io.Reader, file, collection string) ([]interface{}, error) {
+	rows := make([]interface{}, 0)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), maxRecordSize)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		rec, err := db.DecodeRecord(collection, sc.Bytes())
+		if err != nil {
+			return nil, invalid(fmt.Sprintf("%s:%d", file, line), "%v", err)
+		}
+		rows = append(rows, rec)
+	}
+	if err := sc.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, invalid(file, "record larger than %d bytes", maxRecordSize)
+		}
+		return nil, err
+	}
+	return rows, nil
+}
+
+func installBlob(key, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = storage.Put(key, f)
+	return err
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"backend/backup"
+	"backend/config"
+	"backend/db"
+	"backend/health"
+	"backend/middleware"
+	"backend/migrations"
+	"backend/seed"
+	"backend/storage"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// runCommand handles administrative subcommands (`backend migrate up`,
+// `backend seed`, `backend backup`, `backend healthcheck`, `backend contract`). It returns the process exit code.
+func runCommand(cfg *config.Config, args []string) int {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(cfg, args[1:])
+	case "seed":
+		return runSeed(cfg, args[1:])
+	case "backup":
+		return runBackup(cfg, args[1:])
+	case "restore":
+		return runRestore(cfg, args[1:])
+	case "healthcheck":
+		return runHealthcheck(cfg, args[1:])
+	case "contract":
+		return runContract(cfg, args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\nusage: backend [flags] [migrate up|down [n]|status] [seed [flags]] [backup [-o file]] [restore [-force] file] [healthcheck [-server|-url url]] [contract [-v]]\n", args[0])
+	return 2
+}
+
+// runMigrate applies, reverts or lists schema migrations. Migrations need a
+// real Mongo connection; the in-memory fallback is refused. The Postgres
+// schema is managed by supabase/migrations instead.
+func runMigrate(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: backend migrate up|down [n]|status")
+		return 2
+	}
+	db.Init(cfg.DB)
+	defer db.Close()
+	if db.Mode() == db.ModePostgres {
+		fmt.Fprintln(os.Stderr, "migrate: the postgres schema is managed by supabase/migrations (supabase db push or psql)")
+		return 1
+	}
+	if db.UseInMemory() {
+		fmt.Fprintln(os.Stderr, "migrate: MongoDB is not reachable")
+		return 1
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	switch args[0] {
+	case "up":
+		n, err := migrations.Up(ctx, db.DB, os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate up:", err)
+			return 1
+		}
+		fmt.Printf("%d migration(s) applied\n", n)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			v, err := strconv.Atoi(args[1])
+			if err != nil || v < 1 {
+				fmt.Fprintln(os.Stderr, "migrate down: step count must be a positive integer")
+				return 2
+			}
+			steps = v
+		}
+		n, err := migrations.Down(ctx, db.DB, steps, os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate down:", err)
+			return 1
+		}
+		fmt.Printf("%d migration(s) reverted\n", n)
+	case "status":
+		if err := migrations.Status(ctx, db.DB, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "migrate status:", err)
+			return 1
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "migrate: unknown action %q\n", args[0])
+		return 2
+	}
+	return 0
+}
+
+// runSeed fills the configured store with a generated campus. In-memory data
+// would vanish when the command exits, so that mode requires -snapshot,
+// which writes a bbolt image usable as BOLT_PATH or with the admin restore
+// endpoint.
+func runSeed(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	seedFlag := fs.Int64("seed", 0, "random seed; the same seed reproduces the same data (default: random)")
+	students := fs.Int("students", 60, "number of students")
+	companies := fs.Int("companies", 8, "number of companies")
+	snapshot := fs.String("snapshot", "", "also write a snapshot of the seeded store to this file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	explicit := false
+	fs.Visit(func(f *flag.Flag) { explicit = explicit || f.Name == "seed" })
+	if !explicit {
+		*seedFlag = time.Now().UnixNano()
+	}
+
+	db.Init(cfg.DB)
+	if db.Mode() == db.ModeMemory && *snapshot == "" {
+		fmt.Fprintln(os.Stderr, "seed: the in-memory store does not outlive this command; pass -snapshot or use DB_DRIVER=bolt")
+		return 1
+	}
+	defer db.Close()
+
+	sum, err := seed.Run(context.Background(), seed.Options{Seed: *seedFlag, Students: *students, Companies: *companies})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "seed:", err)
+		return 1
+	}
+	fmt.Printf("seed %d: created %s\n", *seedFlag, sum)
+	fmt.Printf("admin login: %s\n", seed.AdminEmail)
+
+	if *snapshot != "" {
+		f, err := os.Create(*snapshot)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "seed:", err)
+			return 1
+		}
+		err = db.Snapshot(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "seed: snapshot:", err)
+			return 1
+		}
+		fmt.Println("snapshot written to", *snapshot)
+	}
+	return 0
+}
+
+// runBackup writes a backup archive of the configured store and its blobs.
+// -o - writes the archive to stdout.
+func runBackup(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	out := fs.String("o", "", "archive to write (default: placement-backup-<time>.tar.gz, - for stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *out == "" {
+		*out = fmt.Sprintf("placement-backup-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	}
+
+	db.Init(cfg.DB)
+	defer db.Close()
+	storage.Init(cfg.BlobDir)
+
+	w := os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "backup:", err)
+			return 1
+		}
+		w = f
+	}
+	m, err := backup.Write(context.Background(), w)
+	if w != os.Stdout {
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "backup:", err)
+		if *out != "-" {
+			_ = os.Remove(*out)
+		}
+		return 1
+	}
+	for _, key := range m.MissingBlobs {
+		fmt.Fprintln(os.Stderr, "backup: warning: blob missing from the blob store:", key)
+	}
+	fmt.Fprintf(os.Stderr, "backup of %s store written to %s: %s\n", m.Source, *out, m)
+	return 0
+}
+
+// runRestore replaces the configured store with a backup archive. A store
+// that already holds data is only replaced with -force. The in-memory
+// store is refused since the restored data would vanish on exit.
+func runRestore(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	force := fs.Bool("force", false, "replace a store that already holds data")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: backend restore [-force] archive.tar.gz")
+		return 2
+	}
+
+	db.Init(cfg.DB)
+	if db.Mode() == db.ModeMemory {
+		fmt.Fprintln(os.Stderr, "restore: the in-memory store does not outlive this command; use DB_DRIVER=bolt or the admin restore endpoint")
+		return 1
+	}
+	defer db.Close()
+	storage.Init(cfg.BlobDir)
+
+	if !*force {
+		current, err := db.Export(context.Background())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "restore:", err)
+			return 1
+		}
+		for _, name := range db.Collections() {
+			if len(current[name]) > 0 {
+				fmt.Fprintf(os.Stderr, "restore: the %s store already holds data (%s); pass -force to replace it\n", db.Mode(), name)
+				return 1
+			}
+		}
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "restore:", err)
+		return 1
+	}
+	defer f.Close()
+	m, err := backup.Read(context.Background(), f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "restore:", err)
+		return 1
+	}
+	fmt.Printf("restored %s backup from %s into the %s store: %s\n", m.Source, m.CreatedAt, db.Mode(), m)
+	return 0
+}
+
+// runHealthcheck runs the readiness checks against the configured
+// dependencies, or with -server/-url asks a running server's readiness
+// endpoint. It exits 0 when ready (degraded included), so it can serve as a
+// container HEALTHCHECK.
+func runHealthcheck(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	server := fs.Bool("server", false, "probe the server on this host's PORT instead of the dependencies")
+	url := fs.String("url", "", "probe the readiness endpoint at this URL")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *server && *url == "" {
+		*url = fmt.Sprintf("http://127.0.0.1:%d/api/health/ready", cfg.Port)
+	}
+
+	var rep health.Report
+	if *url != "" {
+		var err error
+		if rep, err = probeReadiness(*url); err != nil {
+			fmt.Fprintln(os.Stderr, "healthcheck:", err)
+			return 1
+		}
+	} else {
+		db.Init(cfg.DB)
+		defer db.Close()
+		storage.Init(cfg.BlobDir)
+		middleware.InitFirebase(cfg.FirebaseServiceAccount, cfg.JWTSecret)
+		rep = health.Run(context.Background(), dependencyChecks())
+	}
+
+	for _, res := range rep.Checks {
+		fmt.Printf("%-18s %-4s %8.1fms  %s\n", res.Name, res.Status, res.DurationMS, res.Error)
+	}
+	fmt.Printf("status: %s (version %s, up %ds)\n", rep.Status, rep.Version, rep.UptimeSeconds)
+	if !rep.Ready() {
+		return 1
+	}
+	return 0
+}
+
+// probeReadiness fetches a readiness report. A 503 still carries a report.
+func probeReadiness(url string) (health.Report, error) {
+	var rep health.Report
+	client := &http.Client{Timeout: 2 * health.Timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return rep, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&rep); err != nil {
+		return rep, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK && rep.Status == "" {
+		return rep, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return rep, nil
+}
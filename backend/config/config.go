@@ -0,0 +1,530 @@
+// Package config loads the backend's settings. Each setting has a built-in
+// default for the active profile and can be overridden, in increasing order
+// of precedence, by the config file (KEY=VALUE lines, .env by default), the
+// process environment and command-line flags. Load validates the result so
+// a misconfigured server refuses to start instead of failing later.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Profiles, selected with APP_ENV
+const (
+	Development = "development"
+	Test        = "test"
+	Production  = "production"
+)
+
+// Sources a setting's value can come from, reported by Effective
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// devJWTSecret signs tokens outside production when JWT_SECRET is unset
+const devJWTSecret = "your-secret-key"
+
+// minSecretLen is the shortest JWT_SECRET accepted in production
+const minSecretLen = 32
+
+// Config is the typed, validated configuration
+type Config struct {
+	Env             string
+	Port            int
+	FrontendOrigins []string
+	// TrustedProxies may set X-Forwarded-For; without any the peer address
+	// identifies the client
+	TrustedProxies         []string
+	JWTSecret              string
+	FirebaseServiceAccount string
+	BlobDir                string
+	AuditRetentionDays     int
+	DB                     DB
+
+	// LogFormat is json or text; LogLevel is debug, info, warn or error
+	LogFormat string
+	LogLevel  string
+	// OTLPEndpoint is the OTLP/HTTP collector traces are sent to, empty to
+	// disable export
+	OTLPEndpoint string
+
+	// RateLimits are the request budgets of the rate-limited route groups
+	RateLimits RateLimits
+	// MaxBodyBytes caps request bodies; MaxUploadBytes caps uploaded
+	// documents, whose routes are exempt from MaxBodyBytes
+	MaxBodyBytes   int
+	MaxUploadBytes int
+
+	// OpenAPIValidation is off, warn or enforce: whether payloads are
+	// checked against the OpenAPI document
+	OpenAPIValidation string
+
+	// Webhooks holds the policy for company webhook receivers
+	Webhooks Webhooks
+
+	// File is the config file that was read, empty if none
+	File    string
+	sources map[string]string
+}
+
+// DB holds the storage settings handed to db.Init
+type DB struct {
+	// Driver is mongo, memory, bolt or postgres
+	Driver string
+	// Fallback is memory, readonly or none: what to do when the driver's
+	// backend is unreachable
+	Fallback    string
+	MongoURI    string
+	MongoDB     string
+	BoltPath    string
+	DatabaseURL string
+}
+
+// Webhooks holds the settings handed to webhooks.Init
+type Webhooks struct {
+	// AllowPrivate lets receivers use plain http and sit at loopback,
+	// private and link-local addresses, so local receivers can be used
+	AllowPrivate bool
+}
+
+// RateLimits holds the budget of each rate-limited route group
+type RateLimits struct {
+	// Auth covers sign-in
+	Auth RateLimit
+	// Write covers every other POST, PUT and DELETE under /api
+	Write RateLimit
+	// Upload covers document uploads
+	Upload RateLimit
+}
+
+// RateLimit is a pair of token buckets, one per client IP and one per
+// signed-in user. A zero Rate leaves that side unlimited.
+type RateLimit struct {
+	IP   Rate
+	User Rate
+}
+
+// Rate allows Count requests per Per, in bursts of up to Count
+type Rate struct {
+	Count int
+	Per   time.Duration
+}
+
+// IsZero reports whether the rate is unlimited
+func (r Rate) IsZero() bool { return r.Count == 0 }
+
+var ratePeriods = map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+
+func (r Rate) String() string {
+	for unit, d := range ratePeriods {
+		if r.Per == d {
+			return fmt.Sprintf("%d/%s", r.Count, unit)
+		}
+	}
+	return fmt.Sprintf("%d/%s", r.Count, r.Per)
+}
+
+// String renders the limit in the form ParseRateLimit reads
+func (l RateLimit) String() string {
+	var parts []string
+	if !l.IP.IsZero() {
+		parts = append(parts, "ip:"+l.IP.String())
+	}
+	if !l.User.IsZero() {
+		parts = append(parts, "user:"+l.User.String())
+	}
+	if len(parts) == 0 {
+		return "off"
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseRateLimit reads "ip:60/m,user:20/m": per-IP and per-user budgets of
+// N requests per s, m or h. Either side may be left out; "off" disables both.
+func ParseRateLimit(v string) (RateLimit, error) {
+	var l RateLimit
+	if v = strings.TrimSpace(v); v == "off" || v == "" {
+		return l, nil
+	}
+	for _, part := range strings.Split(v, ",") {
+		scope, spec, _ := strings.Cut(strings.TrimSpace(part), ":")
+		count, unit, _ := strings.Cut(spec, "/")
+		n, err := strconv.Atoi(count)
+		per, ok := ratePeriods[unit]
+		if err != nil || n < 1 || !ok {
+			return l, fmt.Errorf("%q: want ip:N/unit or user:N/unit with unit s, m or h", part)
+		}
+		switch scope {
+		case "ip":
+			l.IP = Rate{Count: n, Per: per}
+		case "user":
+			l.User = Rate{Count: n, Per: per}
+		default:
+			return l, fmt.Errorf("%q: scope must be ip or user", part)
+		}
+	}
+	return l, nil
+}
+
+// field reads and writes one Config field as a string
+type field struct {
+	set func(c *Config, v string) error
+	get func(c *Config) string
+}
+
+// setting binds one key (the env var name) to its field in Config
+type setting struct {
+	field
+	key   string
+	usage string
+	// def returns the default for a profile
+	def func(env string) string
+	// redact hides the value in logs and the admin view; nil shows it as is
+	redact func(v string) string
+	// noFlag keeps secrets off the command line, where ps would show them
+	noFlag bool
+}
+
+func constant(v string) func(string) string { return func(string) string { return v } }
+
+// byProfile returns the default for env, falling back to dev
+func byProfile(dev, test, prod string) func(string) string {
+	return func(env string) string {
+		switch env {
+		case Test:
+			return test
+		case Production:
+			return prod
+		}
+		return dev
+	}
+}
+
+func text(p func(c *Config) *string) field {
+	return field{
+		set: func(c *Config, v string) error { *p(c) = v; return nil },
+		get: func(c *Config) string { return *p(c) },
+	}
+}
+
+func oneOf(p func(c *Config) *string, allowed ...string) field {
+	return field{
+		set: func(c *Config, v string) error {
+			for _, a := range allowed {
+				if v == a {
+					*p(c) = v
+					return nil
+				}
+			}
+			return fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+		},
+		get: func(c *Config) string { return *p(c) },
+	}
+}
+
+func integer(p func(c *Config) *int, min, max int) field {
+	return field{
+		set: func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < min || n > max {
+				return fmt.Errorf("must be an integer between %d and %d", min, max)
+			}
+			*p(c) = n
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Itoa(*p(c)) },
+	}
+}
+
+func boolean(p func(c *Config) *bool) field {
+	return field{
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return errors.New("must be true or false")
+			}
+			*p(c) = b
+			return nil
+		},
+		get: func(c *Config) string { return strconv.FormatBool(*p(c)) },
+	}
+}
+
+func list(p func(c *Config) *[]string) field {
+	return field{
+		set: func(c *Config, v string) error {
+			out := make([]string, 0)
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					out = append(out, item)
+				}
+			}
+			*p(c) = out
+			return nil
+		},
+		get: func(c *Config) string { return strings.Join(*p(c), ",") },
+	}
+}
+
+func rateLimit(p func(c *Config) *RateLimit) field {
+	return field{
+		set: func(c *Config, v string) error {
+			l, err := ParseRateLimit(v)
+			if err != nil {
+				return err
+			}
+			*p(c) = l
+			return nil
+		},
+		get: func(c *Config) string { return p(c).String() },
+	}
+}
+
+func redactSecret(v string) string {
+	if v == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// redactURL hides the password of a connection string
+func redactURL(v string) string {
+	u, err := url.Parse(v)
+	if err != nil {
+		return redactSecret(v)
+	}
+	return u.Redacted()
+}
+
+// settings lists every setting. APP_ENV comes first: it picks the defaults
+// for the rest.
+var settings = []setting{
+	{key: "APP_ENV", usage: "profile: development, test or production", def: constant(Development),
+		field: oneOf(func(c *Config) *string { return &c.Env }, Development, Test, Production)},
+	{key: "PORT", usage: "HTTP port", def: constant("8081"),
+		field: integer(func(c *Config) *int { return &c.Port }, 1, 65535)},
+	{key: "FRONTEND_ORIGINS", usage: "comma-separated origins allowed by CORS",
+		def:   byProfile("http://localhost:5173,http://localhost:5174", "http://localhost:5173", ""),
+		field: list(func(c *Config) *[]string { return &c.FrontendOrigins })},
+	{key: "TRUSTED_PROXIES", usage: "comma-separated proxy IPs or CIDRs whose X-Forwarded-For is believed", def: constant(""),
+		field: list(func(c *Config) *[]string { return &c.TrustedProxies })},
+	{key: "JWT_SECRET", usage: "key signing the API's JWTs", def: byProfile(devJWTSecret, devJWTSecret, ""),
+		field: text(func(c *Config) *string { return &c.JWTSecret }), redact: redactSecret, noFlag: true},
+	{key: "FIREBASE_SERVICE_ACCOUNT", usage: "path of the Firebase service account JSON", def: constant("firebase_service_account.json"),
+		field: text(func(c *Config) *string { return &c.FirebaseServiceAccount })},
+	{key: "BLOB_DIR", usage: "directory for uploaded files", def: constant("uploads"),
+		field: text(func(c *Config) *string { return &c.BlobDir })},
+	{key: "AUDIT_RETENTION_DAYS", usage: "days to keep audit entries, 0 keeps them forever", def: constant("365"),
+		field: integer(func(c *Config) *int { return &c.AuditRetentionDays }, 0, 100000)},
+	{key: "DB_DRIVER", usage: "storage backend: mongo, memory, bolt or postgres", def: byProfile("mongo", "memory", "mongo"),
+		field: oneOf(func(c *Config) *string { return &c.DB.Driver }, "mongo", "memory", "bolt", "postgres")},
+	{key: "DB_FALLBACK", usage: "when the backend is unreachable: memory, readonly or none", def: byProfile("memory", "memory", "none"),
+		field: oneOf(func(c *Config) *string { return &c.DB.Fallback }, "memory", "readonly", "none")},
+	{key: "MONGO_URI", usage: "MongoDB connection string", def: constant("mongodb://127.0.0.1:27017"),
+		field: text(func(c *Config) *string { return &c.DB.MongoURI }), redact: redactURL},
+	{key: "MONGO_DB", usage: "MongoDB database name", def: constant("placement_portal"),
+		field: text(func(c *Config) *string { return &c.DB.MongoDB })},
+	{key: "BOLT_PATH", usage: "bbolt data file used when DB_DRIVER=bolt", def: constant("data/placement.db"),
+		field: text(func(c *Config) *string { return &c.DB.BoltPath })},
+	{key: "DATABASE_URL", usage: "Postgres connection string used when DB_DRIVER=postgres", def: constant(""),
+		field: text(func(c *Config) *string { return &c.DB.DatabaseURL }), redact: redactURL},
+	{key: "LOG_FORMAT", usage: "log output: json or text", def: byProfile("text", "text", "json"),
+		field: oneOf(func(c *Config) *string { return &c.LogFormat }, "json", "text")},
+	{key: "LOG_LEVEL", usage: "minimum log level: debug, info, warn or error", def: constant("info"),
+		field: oneOf(func(c *Config) *string { return &c.LogLevel }, "debug", "info", "warn", "error")},
+	{key: "OTEL_EXPORTER_OTLP_ENDPOINT", usage: "OTLP/HTTP collector for traces, e.g. http://localhost:4318", def: constant(""),
+		field: text(func(c *Config) *string { return &c.OTLPEndpoint })},
+	{key: "RATE_LIMIT_AUTH", usage: "sign-in budget, e.g. ip:10/m; off disables", def: byProfile("ip:10/m", "off", "ip:10/m"),
+		field: rateLimit(func(c *Config) *RateLimit { return &c.RateLimits.Auth })},
+	{key: "RATE_LIMIT_WRITE", usage: "budget for POST, PUT and DELETE, e.g. ip:300/m,user:60/m", def: byProfile("ip:300/m,user:60/m", "off", "ip:300/m,user:60/m"),
+		field: rateLimit(func(c *Config) *RateLimit { return &c.RateLimits.Write })},
+	{key: "RATE_LIMIT_UPLOAD", usage: "document upload budget, e.g. ip:60/h,user:20/h", def: byProfile("ip:60/h,user:20/h", "off", "ip:60/h,user:20/h"),
+		field: rateLimit(func(c *Config) *RateLimit { return &c.RateLimits.Upload })},
+	{key: "OPENAPI_VALIDATION", usage: "check payloads against the OpenAPI document: off, warn or enforce", def: byProfile("warn", "enforce", "off"),
+		field: oneOf(func(c *Config) *string { return &c.OpenAPIValidation }, "off", "warn", "enforce")},
+	{key: "MAX_BODY_BYTES", usage: "largest request body accepted, uploads and restores excepted", def: constant("1048576"),
+		field: integer(func(c *Config) *int { return &c.MaxBodyBytes }, 1024, 1<<30)},
+	{key: "MAX_UPLOAD_BYTES", usage: "largest resume or verification document accepted", def: constant("10485760"),
+		field: integer(func(c *Config) *int { return &c.MaxUploadBytes }, 1024, 1<<30)},
+	{key: "WEBHOOK_ALLOW_PRIVATE", usage: "let webhook receivers use http and non-public addresses", def: byProfile("true", "true", "false"),
+		field: boolean(func(c *Config) *bool { return &c.Webhooks.AllowPrivate })},
+}
+
+// flagName is the command-line form of a key: DB_DRIVER becomes -db-driver
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+// Load builds the configuration from args (the command line without the
+// program name), the environment and the config file. Flags end at the
+// first non-flag argument; the rest is returned for subcommands.
+func Load(args []string) (*Config, []string, error) {
+	fs := flag.NewFlagSet("backend", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	file := fs.String("config", "", "config file of KEY=VALUE lines (default .env, or CONFIG_FILE)")
+	flags := map[string]*string{}
+	for _, s := range settings {
+		if !s.noFlag {
+			flags[s.key] = fs.String(flagName(s.key), "", s.usage)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stderr)
+			fs.PrintDefaults()
+		}
+		return nil, nil, err
+	}
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	c := &Config{sources: map[string]string{}}
+	path, required := *file, given["config"]
+	if !required {
+		if path = os.Getenv("CONFIG_FILE"); path != "" {
+			required = true
+		} else {
+			path = ".env"
+		}
+	}
+	fileVals, err := readFile(path)
+	switch {
+	case err == nil:
+		c.File = path
+	case required || !errors.Is(err, os.ErrNotExist):
+		return nil, nil, fmt.Errorf("config file: %w", err)
+	}
+
+	var errs []error
+	for _, s := range settings {
+		v, src := s.def(c.Env), SourceDefault
+		if fv, ok := fileVals[s.key]; ok {
+			v, src = fv, SourceFile
+		}
+		if ev, ok := os.LookupEnv(s.key); ok && ev != "" {
+			v, src = ev, SourceEnv
+		}
+		if !s.noFlag && given[flagName(s.key)] {
+			v, src = *flags[s.key], SourceFlag
+		}
+		if err := s.set(c, v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.key, err))
+			continue
+		}
+		c.sources[s.key] = src
+	}
+	errs = append(errs, c.validate()...)
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return c, fs.Args(), nil
+}
+
+// validate checks rules spanning settings and the production requirements
+func (c *Config) validate() []error {
+	var errs []error
+	switch c.DB.Driver {
+	case "postgres":
+		if c.DB.DatabaseURL == "" {
+			errs = append(errs, errors.New("DATABASE_URL: is required when DB_DRIVER=postgres"))
+		}
+	case "bolt":
+		if c.DB.BoltPath == "" {
+			errs = append(errs, errors.New("BOLT_PATH: is required when DB_DRIVER=bolt"))
+		}
+	case "mongo":
+		if c.DB.MongoURI == "" || c.DB.MongoDB == "" {
+			errs = append(errs, errors.New("MONGO_URI, MONGO_DB: are required when DB_DRIVER=mongo"))
+		}
+	}
+	if c.Env != Production {
+		return errs
+	}
+	if c.JWTSecret == "" || c.JWTSecret == devJWTSecret {
+		errs = append(errs, errors.New("JWT_SECRET: must be set in production"))
+	} else if len(c.JWTSecret) < minSecretLen {
+		errs = append(errs, fmt.Errorf("JWT_SECRET: must be at least %d characters in production", minSecretLen))
+	}
+	if len(c.FrontendOrigins) == 0 {
+		errs = append(errs, errors.New("FRONTEND_ORIGINS: must be set in production"))
+	}
+	if c.DB.Driver == "memory" {
+		errs = append(errs, errors.New("DB_DRIVER: memory loses all data on restart and is not allowed in production"))
+	}
+	if c.Webhooks.AllowPrivate {
+		errs = append(errs, errors.New("WEBHOOK_ALLOW_PRIVATE: webhooks must not reach internal services in production"))
+	}
+	return errs
+}
+
+// readFile parses a file of KEY=VALUE lines. Blank lines and lines starting
+// with # are skipped, and values may be wrapped in single or double quotes.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]string{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// split on first '=' only
+		key, val, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, i+1)
+		}
+		val = strings.TrimSpace(val)
+		if len(val) >= 2 && ((val[0] == '"' && val[len(val)-1] == '"') || (val[0] == '\'' && val[len(val)-1] == '\'')) {
+			val = val[1 : len(val)-1]
+		}
+		out[key] = val
+	}
+	return out, nil
+}
+
+// Entry is one setting as reported by Effective
+type Entry struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Source   string `json:"source"`
+	Redacted bool   `json:"redacted,omitempty"`
+}
+
+// Effective lists every setting with its value and where it came from.
+// Secrets are redacted, so the result is safe to log or show to admins.
+func (c *Config) Effective() []Entry {
+	out := make([]Entry, 0, len(settings))
+	for _, s := range settings {
+		v := s.get(c)
+		e := Entry{Key: s.key, Value: v, Source: c.sources[s.key]}
+		if s.redact != nil {
+			e.Value = s.redact(v)
+			e.Redacted = e.Value != v
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// String renders the effective configuration on one line, secrets redacted
+func (c *Config) String() string {
+	parts := make([]string, 0, len(settings))
+	for _, e := range c.Effective() {
+		parts = append(parts, fmt.Sprintf("%s=%q (%s)", e.Key, e.Value, e.Source))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"backend/config"
+	"backend/db"
+	"backend/events"
+	"backend/handlers"
+	"backend/middleware"
+	"backend/openapi"
+	"backend/seed"
+	"backend/storage"
+	"backend/telemetry"
+	"backend/webhooks"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// runContract is the contract subcommand, the same check as TestContract
+// for a server binary outside the source tree. Exit status 1 means the Go
+// types, the route table in handlers/openapi.go or a handler have drifted
+// apart.
+func runContract(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("contract", flag.ContinueOnError)
+	verbose := fs.Bool("v", false, "print every call")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	failures, err := checkContract(cfg, *verbose, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "contract:", err)
+		return 1
+	}
+	if len(failures) > 0 {
+		for _, f := range failures {
+			fmt.Fprintln(os.Stderr, "FAIL", f)
+		}
+		fmt.Fprintf(os.Stderr, "contract: %d failure(s)\n", len(failures))
+		return 1
+	}
+	fmt.Println("contract: ok")
+	return 0
+}
+
+// checkContract checks the handlers against the OpenAPI document. It serves
+// the API in process over a freshly seeded in-memory store, walks the main
+// flows through the documented routes and validates every request body and
+// JSON response. Routes registered with gin but missing from the document,
+// or the reverse, are departures too. The calls and the routes left
+// unexercised are reported to out; err is a failure to set up the check.
+func checkContract(cfg *config.Config, verbose bool, out io.Writer) (failures []string, err error) {
+	// an isolated store without rate limits; payloads are checked here
+	// rather than by the validation middleware
+	cfg.DB = config.DB{Driver: db.ModeMemory, Fallback: "memory"}
+	cfg.RateLimits = config.RateLimits{}
+	cfg.OpenAPIValidation = middleware.ValidationOff
+	if err := telemetry.InitLogging(cfg.LogFormat, "warn"); err != nil {
+		return nil, err
+	}
+	blobs, err := os.MkdirTemp("", "contract-blobs-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(blobs)
+	db.Init(cfg.DB)
+	defer db.Close()
+	storage.Init(blobs)
+	webhooks.Init(cfg.Webhooks)
+	middleware.InitTokens(cfg.JWTSecret)
+	gin.SetMode(gin.ReleaseMode)
+
+	ctx := context.Background()
+	if _, err := seed.Run(ctx, seed.Options{Seed: 1, Students: 6, Companies: 2}); err != nil {
+		return nil, fmt.Errorf("seed: %w", err)
+	}
+	admins, err := db.ListProfiles(ctx, map[string]interface{}{"email": seed.AdminEmail})
+	if err != nil || len(admins) == 0 {
+		return nil, errors.New("seeded admin not found")
+	}
+	token, err := middleware.SignToken(jwt.MapClaims{"user_id": admins[0].ID, "exp": time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		return nil, err
+	}
+
+	r := newRouter(cfg)
+	k := &contract{spec: handlers.OpenAPISpec(), router: r, token: token, verbose: verbose, out: out, called: map[string]bool{}}
+	k.checkCoverage(r.Routes())
+	k.walk()
+	k.walkV1()
+
+	var skipped []string
+	for _, rt := range k.spec.Routes() {
+		if !k.called[rt] {
+			skipped = append(skipped, rt)
+		}
+	}
+	fmt.Fprintf(out, "contract: %d calls, %d documented routes, %d not exercised\n", k.calls, len(k.spec.Routes()), len(skipped))
+	for _, rt := range skipped {
+		fmt.Fprintln(out, "  not exercised:", rt)
+	}
+	return k.failures, nil
+}
+
+// contract drives the router and collects departures from the document
+type contract struct {
+	spec     *openapi.Spec
+	router   http.Handler
+	token    string
+	verbose  bool
+	out      io.Writer
+	calls    int
+	called   map[string]bool
+	failures []string
+}
+
+func (k *contract) fail(format string, args ...interface{}) {
+	k.failures = append(k.failures, fmt.Sprintf(format, args...))
+}
+
+// checkCoverage compares the registered /api routes with the document
+func (k *contract) checkCoverage(routes gin.RoutesInfo) {
+	registered := map[string]bool{}
+	for _, rt := range routes {
+		if !strings.HasPrefix(rt.Path, "/api/") {
+			continue
+		}
+		key := rt.Method + " " + rt.Path
+		registered[key] = true
+		if _, ok := k.spec.Operation(rt.Method, rt.Path); !ok {
+			k.fail("%s: registered but not documented", key)
+		}
+	}
+	for _, key := range k.spec.Routes() {
+		if !registered[key] {
+			k.fail("%s: documented but not registered", key)
+		}
+	}
+}
+
+// call sends a JSON request as the admin, checks both bodies against the
+// operation for route and returns the response's "data", or nil
+func (k *contract) call(method, route, path string, body interface{}) interface{} {
+	_, data := k.request(method, route, path, body, nil, 0)
+	return data
+}
+
+// request is call with extra headers, expecting status want (any success
+// when zero); it also returns the recorded response
+func (k *contract) request(method, route, path string, body interface{}, header http.Header, want int) (*httptest.ResponseRecorder, interface{}) {
+	var raw []byte
+	if body != nil {
+		raw, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	return k.send(method, route, req, raw, want)
+}
+
+// upload sends a multipart form with content as its "file"
+func (k *contract) upload(route, path, name string, content []byte, fields map[string]string) interface{} {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for f, v := range fields {
+		mw.WriteField(f, v)
+	}
+	fw, _ := mw.CreateFormFile("file", name)
+	fw.Write(content)
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, path, &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	_, data := k.send(http.MethodPost, route, req, nil, 0)
+	return data
+}
+
+func (k *contract) send(method, route string, req *http.Request, body []byte, want int) (*httptest.ResponseRecorder, interface{}) {
+	key := method + " " + route
+	k.calls++
+	k.called[key] = true
+	rec := httptest.NewRecorder()
+	op, ok := k.spec.Operation(method, route)
+	if !ok {
+		k.fail("%s: not documented", key)
+		return rec, nil
+	}
+	if body != nil {
+		for _, v := range k.spec.ValidateRequest(op, body) {
+			k.fail("%s request: %s", key, v)
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+k.token)
+	k.router.ServeHTTP(rec, req)
+	if k.verbose {
+		fmt.Fprintf(k.out, "%d %s %s\n", rec.Code, method, req.URL.Path)
+	}
+	if want == 0 && rec.Code >= 300 || want != 0 && rec.Code != want {
+		k.fail("%s: %s returned %d: %s", key, req.URL.Path, rec.Code, strings.TrimSpace(rec.Body.String()))
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		return rec, nil
+	}
+	for _, v := range k.spec.ValidateResponse(op, rec.Code, rec.Body.Bytes()) {
+		k.fail("%s response %d: %s", key, rec.Code, v)
+	}
+	var env struct {
+		Data interface{} `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &env)
+	return rec, env.Data
+}
+
+// idOf returns the "id" of a decoded record, or the first record of a list
+func idOf(v interface{}) string {
+	if list, ok := v.([]interface{}); ok {
+		if len(list) == 0 {
+			return ""
+		}
+		v = list[0]
+	}
+	m, _ := v.(map[string]interface{})
+	id, _ := m["id"].(string)
+	return id
+}
+
+// samplePDF is the smallest upload the type sniffing accepts
+var samplePDF = []byte("%PDF-1.4\n%contract\n")
+
+// walk exercises the documented routes in the order a campus uses them:
+// reads of the seeded data, then a company from sign-up to an interview
+func (k *contract) walk() {
+	k.call("GET", "/api/openapi.json", "/api/openapi.json", nil)
+	k.call("GET", "/api/health", "/api/health", nil)
+	k.call("GET", "/api/health/live", "/api/health/live", nil)
+	k.call("GET", "/api/profiles", "/api/profiles", nil)
+	student := idOf(k.call("GET", "/api/student_profiles", "/api/student_profiles", nil))
+	seeded := idOf(k.call("GET", "/api/companies", "/api/companies", nil))
+	k.call("GET", "/api/companies/:id/members", "/api/companies/"+seeded+"/members", nil)
+	k.call("GET", "/api/job_postings", "/api/job_postings", nil)
+	k.call("GET", "/api/applications", "/api/applications", nil)
+	k.call("GET", "/api/applications", "/api/applications?company_id="+seeded, nil)
+	k.call("GET", "/api/notifications", "/api/notifications", nil)
+	k.call("GET", "/api/admin/config", "/api/admin/config", nil)
+
+	recruiter := idOf(k.call("POST", "/api/profiles", "/api/profiles",
+		gin.H{"email": "contract.recruiter@campus.test", "full_name": "Contract Recruiter", "role": "recruiter"}))
+	k.call("PUT", "/api/profiles/:id", "/api/profiles/"+recruiter, gin.H{"full_name": "Contract Recruiter Jr"})
+	grad := idOf(k.call("POST", "/api/profiles", "/api/profiles",
+		gin.H{"email": "contract.student@campus.test", "full_name": "Contract Student", "role": "student"}))
+	sp := idOf(k.call("POST", "/api/student_profiles", "/api/student_profiles",
+		gin.H{"user_id": grad, "roll_number": "CT001", "cgpa": 8.1, "branch": "Computer Science", "graduation_year": 2026}))
+	k.call("PUT", "/api/student_profiles/:id", "/api/student_profiles/"+sp, gin.H{"cgpa": "8.4", "skills": []string{"go"}})
+
+	co := idOf(k.call("POST", "/api/companies", "/api/companies", gin.H{"name": "Contract Labs", "email": "hr@contract.test"}))
+	k.call("PUT", "/api/companies/:id", "/api/companies/"+co, gin.H{"description": "Keeps promises"})
+	k.call("POST", "/api/companies/:id/members", "/api/companies/"+co+"/members", gin.H{"user_id": recruiter, "role": "recruiter"})
+	k.call("DELETE", "/api/companies/:id/members/:user_id", "/api/companies/"+co+"/members/"+recruiter, nil)
+	hook := idOf(k.call("POST", "/api/companies/:id/webhooks", "/api/companies/"+co+"/webhooks",
+		gin.H{"url": "https://hooks.contract.test/ats", "event_types": []string{db.EventApplicationStatusChanged, db.EventJobPublished}}))
+	k.call("GET", "/api/companies/:id/webhooks", "/api/companies/"+co+"/webhooks", nil)
+
+	vr := idOf(k.call("POST", "/api/companies/:id/verification", "/api/companies/"+co+"/verification",
+		gin.H{"registration_number": "U72200KA2020PTC000001", "address": "Bengaluru", "contact_name": "HR"}))
+	k.call("PUT", "/api/verification_requests/:id", "/api/verification_requests/"+vr, gin.H{"contact_phone": "+91 80 0000 0000"})
+	doc := idOf(k.upload("/api/verification_requests/:id/documents", "/api/verification_requests/"+vr+"/documents", "registration.pdf", samplePDF, nil))
+	k.call("GET", "/api/verification_requests/:id/documents/:doc_id", "/api/verification_requests/"+vr+"/documents/"+doc, nil)
+	k.call("GET", "/api/verification_requests", "/api/verification_requests?company_id="+co, nil)
+	k.call("POST", "/api/admin/verification_requests/:id/review", "/api/admin/verification_requests/"+vr+"/review", gin.H{"decision": "approve"})
+
+	job := idOf(k.call("POST", "/api/job_postings", "/api/job_postings",
+		gin.H{"company_id": co, "title": "Backend Engineer", "description": "Go services", "openings": 2, "salary_min": 900000}))
+	k.call("PUT", "/api/job_postings/:id", "/api/job_postings/"+job, gin.H{"job_location": "Remote", "openings": "3"})
+	k.call("POST", "/api/job_postings/:id/publish", "/api/job_postings/"+job+"/publish", nil)
+
+	resume := idOf(k.upload("/api/resumes/upload", "/api/resumes/upload", "cv.pdf", samplePDF, map[string]string{"student_id": student}))
+	k.call("GET", "/api/resumes", "/api/resumes?student_id="+student, nil)
+	k.call("GET", "/api/resumes/:id/file", "/api/resumes/"+resume+"/file", nil)
+	app := idOf(k.call("POST", "/api/applications", "/api/applications", gin.H{"job_id": job, "student_id": student, "resume_id": resume}))
+	k.call("PUT", "/api/applications/:id", "/api/applications/"+app, gin.H{"status": "shortlisted"})
+	k.call("POST", "/api/interviews", "/api/interviews",
+		gin.H{"application_id": app, "scheduled_at": time.Now().Add(72 * time.Hour).Format(time.RFC3339), "mode": "online"})
+
+	// the dispatcher turns the events so far into webhook deliveries; they
+	// are not sent, so the log shows them pending
+	if err := events.NewDispatcher(handlers.EventSubscribers()...).Run(context.Background(), time.Now()); err != nil {
+		k.fail("event dispatch: %v", err)
+	}
+	delivery := idOf(k.call("GET", "/api/companies/:id/webhooks/deliveries", "/api/companies/"+co+"/webhooks/deliveries?webhook_id="+hook, nil))
+	if delivery == "" {
+		k.fail("GET /api/companies/:id/webhooks/deliveries: no delivery for the job and application events")
+	}
+	k.request("POST", "/api/companies/:id/webhooks/deliveries/:delivery_id/replay",
+		"/api/companies/"+co+"/webhooks/deliveries/"+delivery+"/replay", nil, nil, http.StatusAccepted)
+	k.call("DELETE", "/api/companies/:id/webhooks/:webhook_id", "/api/companies/"+co+"/webhooks/"+hook, nil)
+
+	k.call("POST", "/api/job_postings/:id/close", "/api/job_postings/"+job+"/close", nil)
+	k.call("DELETE", "/api/job_postings/:id", "/api/job_postings/"+job, nil)
+	k.call("GET", "/api/job_postings", "/api/job_postings?include_deleted=true", nil)
+	k.call("POST", "/api/admin/job_postings/:id/restore", "/api/admin/job_postings/"+job+"/restore", nil)
+	k.call("GET", "/api/admin/audit", "/api/admin/audit?limit=20", nil)
+}
+
+// walkV1 covers what /api/v1 changes: reads by id, updates answered with the
+// stored resource and its ETag, If-Match, 404s and 204s
+func (k *contract) walkV1() {
+	k.call("GET", "/api/v1/profiles", "/api/v1/profiles", nil)
+	student := idOf(k.call("GET", "/api/v1/student_profiles", "/api/v1/student_profiles", nil))
+	k.call("GET", "/api/v1/student_profiles/:id", "/api/v1/student_profiles/"+student, nil)
+	k.call("PUT", "/api/v1/student_profiles/:id", "/api/v1/student_profiles/"+student, gin.H{"skills": []string{"go", "sql"}})
+	k.request("GET", "/api/v1/companies/:id", "/api/v1/companies/missing", nil, nil, http.StatusNotFound)
+
+	recruiter := idOf(k.call("POST", "/api/v1/profiles", "/api/v1/profiles",
+		gin.H{"email": "v1.recruiter@campus.test", "full_name": "Versioned Recruiter", "role": "recruiter"}))
+	k.call("GET", "/api/v1/profiles/:id", "/api/v1/profiles/"+recruiter, nil)
+	k.call("PUT", "/api/v1/profiles/:id", "/api/v1/profiles/"+recruiter, gin.H{"full_name": "Versioned Recruiter Sr"})
+
+	co := idOf(k.call("POST", "/api/v1/companies", "/api/v1/companies", gin.H{"name": "Versioned Labs", "email": "hr@versioned.test"}))
+	rec, _ := k.request("GET", "/api/v1/companies/:id", "/api/v1/companies/"+co, nil, nil, http.StatusOK)
+	tag := rec.Header().Get("ETag")
+	if tag == "" {
+		k.fail("GET /api/v1/companies/:id: no ETag")
+	}
+	rec, updated := k.request("PUT", "/api/v1/companies/:id", "/api/v1/companies/"+co,
+		gin.H{"description": "Versioned"}, http.Header{"If-Match": {tag}}, http.StatusOK)
+	if m, _ := updated.(map[string]interface{}); m["name"] != "Versioned Labs" || rec.Header().Get("ETag") == tag {
+		k.fail("PUT /api/v1/companies/:id: answered without the stored company and its new ETag")
+	}
+	// the tag read before the update is stale now
+	k.request("PUT", "/api/v1/companies/:id", "/api/v1/companies/"+co,
+		gin.H{"description": "Lost update"}, http.Header{"If-Match": {tag}}, http.StatusConflict)
+	k.call("POST", "/api/v1/companies/:id/members", "/api/v1/companies/"+co+"/members", gin.H{"user_id": recruiter, "role": "recruiter"})
+	k.call("GET", "/api/v1/companies/:id/members", "/api/v1/companies/"+co+"/members", nil)
+	k.request("DELETE", "/api/v1/companies/:id/members/:user_id", "/api/v1/companies/"+co+"/members/"+recruiter, nil, nil, http.StatusNoContent)
+	hook := idOf(k.call("POST", "/api/v1/companies/:id/webhooks", "/api/v1/companies/"+co+"/webhooks",
+		gin.H{"url": "https://hooks.versioned.test/ats", "event_types": []string{db.EventJobPublished}}))
+	k.call("GET", "/api/v1/companies/:id/webhooks/deliveries", "/api/v1/companies/"+co+"/webhooks/deliveries", nil)
+	k.request("DELETE", "/api/v1/companies/:id/webhooks/:webhook_id", "/api/v1/companies/"+co+"/webhooks/"+hook, nil, nil, http.StatusNoContent)
+
+	vr := idOf(k.call("POST", "/api/v1/companies/:id/verification", "/api/v1/companies/"+co+"/verification",
+		gin.H{"registration_number": "U72200KA2021PTC000002", "address": "Pune", "contact_name": "HR"}))
+	k.call("GET", "/api/v1/verification_requests/:id", "/api/v1/verification_requests/"+vr, nil)
+	k.call("PUT", "/api/v1/verification_requests/:id", "/api/v1/verification_requests/"+vr, gin.H{"contact_phone": "+91 20 0000 0000"})
+	k.call("POST", "/api/v1/admin/verification_requests/:id/review", "/api/v1/admin/verification_requests/"+vr+"/review", gin.H{"decision": "approve"})
+
+	job := idOf(k.call("POST", "/api/v1/job_postings", "/api/v1/job_postings",
+		gin.H{"company_id": co, "title": "Platform Engineer", "description": "Versioned APIs", "openings": 1}))
+	k.call("PUT", "/api/v1/job_postings/:id", "/api/v1/job_postings/"+job, gin.H{"job_location": "Pune"})
+	k.call("POST", "/api/v1/job_postings/:id/publish", "/api/v1/job_postings/"+job+"/publish", nil)
+	k.call("GET", "/api/v1/job_postings/:id", "/api/v1/job_postings/"+job, nil)
+	app := idOf(k.call("POST", "/api/v1/applications", "/api/v1/applications", gin.H{"job_id": job, "student_id": student}))
+	k.call("GET", "/api/v1/applications/:id", "/api/v1/applications/"+app, nil)
+	k.call("PUT", "/api/v1/applications/:id", "/api/v1/applications/"+app, gin.H{"status": "shortlisted"})
+	k.request("DELETE", "/api/v1/job_postings/:id", "/api/v1/job_postings/"+job, nil, nil, http.StatusNoContent)
+	k.request("GET", "/api/v1/job_postings/:id", "/api/v1/job_postings/"+job, nil, nil, http.StatusOK)
+}
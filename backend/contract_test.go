@@ -0,0 +1,35 @@
+package main
+
+import (
+	"backend/config"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestContract fails when the handlers, the registered routes and the
+// OpenAPI document disagree; see checkContract
+func TestContract(t *testing.T) {
+	// defaults of the test profile only, whatever .env holds
+	empty := filepath.Join(t.TempDir(), "empty.env")
+	if err := os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP_ENV", config.Test)
+	cfg, _, err := config.Load([]string{"-config", empty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out io.Writer = io.Discard
+	if testing.Verbose() {
+		out = os.Stdout
+	}
+	failures, err := checkContract(cfg, testing.Verbose(), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range failures {
+		t.Error(f)
+	}
+}
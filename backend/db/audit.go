@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var inMemoryAudit []AuditEntry
+
+// inMemoryAuditIDs holds the ids in inMemoryAudit, so that appending checks
+// for a duplicate without a scan
+var inMemoryAuditIDs map[string]struct{}
+
+// AuditChange holds the value of a single field before and after an action
+type AuditChange struct {
+	Before interface{} `bson:"before" json:"before"`
+	After  interface{} `bson:"after" json:"after"`
+}
+
+// AuditEntry is an append-only record of who did what to which entity
+type AuditEntry struct {
+	ID        string                 `bson:"_id,omitempty" json:"id"`
+	ActorID   string                 `bson:"actor_id,omitempty" json:"actor_id"`
+	ActorRole string                 `bson:"actor_role,omitempty" json:"actor_role"`
+	Action    string                 `bson:"action" json:"action"`
+	Entity    string                 `bson:"entity" json:"entity"`
+	EntityID  string                 `bson:"entity_id" json:"entity_id"`
+	Diff      map[string]AuditChange `bson:"diff,omitempty" json:"diff"`
+	IP        string                 `bson:"ip,omitempty" json:"ip"`
+	RequestID string                 `bson:"request_id,omitempty" json:"request_id"`
+	CreatedAt string                 `bson:"created_at" json:"created_at"`
+}
+
+// AuditFilter narrows ListAuditEntries. Since/Until are RFC3339 timestamps
+// compared against created_at; empty fields are ignored.
+type AuditFilter struct {
+	ActorID  string
+	Entity   string
+	EntityID string
+	Action   string
+	Since    string
+	Until    string
+	Limit    int
+}
+
+func (f AuditFilter) matches(e AuditEntry) bool {
+	if f.ActorID != "" && e.ActorID != f.ActorID {
+		return false
+	}
+	if f.Entity != "" && e.Entity != f.Entity {
+		return false
+	}
+	if f.EntityID != "" && e.EntityID != f.EntityID {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	if f.Since != "" && e.CreatedAt < f.Since {
+		return false
+	}
+	if f.Until != "" && e.CreatedAt > f.Until {
+		return false
+	}
+	return true
+}
+
+// CreateAuditEntry appends an entry to the audit trail. Entries are never
+// updated; the only removal path is PurgeAuditEntries for retention.
+func CreateAuditEntry(ctx context.Context, e AuditEntry) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if e.CreatedAt == "" {
+		e.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		// ids are unique as in the other modes; subscribers rely on the
+		// conflict to recognise a redelivered event
+		if _, ok := inMemoryAuditIDs[e.ID]; ok {
+			return &ConflictError{Field: "id", Message: "duplicate audit_log record"}
+		}
+		if err := persistLocked("audit_log", e.ID, e); err != nil {
+			return err
+		}
+		inMemoryAudit = append(inMemoryAudit, e)
+		inMemoryAuditIDs[e.ID] = struct{}{}
+		return nil
+	}
+	if Mode() == ModePostgres {
+		return pgInsert(ctx, PG, "audit_log", e)
+	}
+	_, err := DB.Collection("audit_log").InsertOne(ctx, e)
+	return uniqueErr("audit_log", err)
+}
+
+// ListAuditEntries returns matching entries, newest first
+func ListAuditEntries(ctx context.Context, f AuditFilter) ([]AuditEntry, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		out := make([]AuditEntry, 0)
+		for _, e := range inMemoryAudit {
+			if f.matches(e) {
+				out = append(out, e)
+			}
+		}
+		sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt > out[j].CreatedAt })
+		if f.Limit > 0 && len(out) > f.Limit {
+			out = out[:f.Limit]
+		}
+		return out, nil
+	}
+	if Mode() == ModePostgres {
+		return pgListAuditEntries(ctx, f)
+	}
+	filter := bson.M{}
+	if f.ActorID != "" {
+		filter["actor_id"] = f.ActorID
+	}
+	if f.Entity != "" {
+		filter["entity"] = f.Entity
+	}
+	if f.EntityID != "" {
+		filter["entity_id"] = f.EntityID
+	}
+	if f.Action != "" {
+		filter["action"] = f.Action
+	}
+	if f.Since != "" || f.Until != "" {
+		rng := bson.M{}
+		if f.Since != "" {
+			rng["$gte"] = f.Since
+		}
+		if f.Until != "" {
+			rng["$lte"] = f.Until
+		}
+		filter["created_at"] = rng
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if f.Limit > 0 {
+		opts.SetLimit(int64(f.Limit))
+	}
+	cur, err := DB.Collection("audit_log").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]AuditEntry, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PurgeAuditEntries removes entries created before the given RFC3339 timestamp
+// and reports how many were removed.
+func PurgeAuditEntries(ctx context.Context, before string) (int64, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		kept := inMemoryAudit[:0]
+		var removed int64
+		var firstErr error
+		for _, e := range inMemoryAudit {
+			if e.CreatedAt < before {
+				// entries that fail to delete from disk stay and are retried next run
+				err := unpersistLocked("audit_log", e.ID)
+				if err == nil {
+					delete(inMemoryAuditIDs, e.ID)
+					removed++
+					continue
+				}
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+			kept = append(kept, e)
+		}
+		inMemoryAudit = kept
+		return removed, firstErr
+	}
+	if Mode() == ModePostgres {
+		return pgPurgeAuditEntries(ctx, before)
+	}
+	res, err := DB.Collection("audit_log").DeleteMany(ctx, bson.M{"created_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
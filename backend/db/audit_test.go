@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// Event subscribers derive record ids from the event, so storing a record
+// again on a redelivery must conflict rather than duplicate it
+func TestRedeliveredRecordsConflict(t *testing.T) {
+	withMemory(t)
+	ctx := context.Background()
+
+	entry := AuditEntry{ID: "3f1c2b5e-0000-4000-8000-000000000001", ActorID: "system", Action: "event.job.published"}
+	if err := CreateAuditEntry(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateAuditEntry(ctx, entry); !errors.Is(err, ErrConflict) {
+		t.Fatalf("second audit entry error = %v, want ErrConflict", err)
+	}
+	if got, _ := ListAuditEntries(ctx, AuditFilter{}); len(got) != 1 {
+		t.Fatalf("audit trail has %d entries, want 1", len(got))
+	}
+	// a purged id is free again
+	if n, err := PurgeAuditEntries(ctx, "9999"); err != nil || n != 1 {
+		t.Fatalf("PurgeAuditEntries = %d, %v", n, err)
+	}
+	if err := CreateAuditEntry(ctx, entry); err != nil {
+		t.Fatalf("audit entry after the purge: %v", err)
+	}
+
+	n := Notification{ID: "3f1c2b5e-0000-4000-8000-000000000002", UserID: "u1", Title: "t", Message: "m"}
+	if err := CreateNotification(ctx, n); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateNotification(ctx, n); !errors.Is(err, ErrConflict) {
+		t.Fatalf("second notification error = %v, want ErrConflict", err)
+	}
+}
@@ -0,0 +1,344 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Dataset is a complete copy of the store: every record of every
+// collection, keyed by collection name. Records have the types the store
+// keeps (the entity structs, or maps for interviews).
+type Dataset map[string][]interface{}
+
+// maxIntegrityErrors caps the problems CheckIntegrity reports
+const maxIntegrityErrors = 50
+
+// reference is a foreign key between collections. Optional references may
+// be empty; set ones must still resolve.
+type reference struct {
+	Collection string
+	Field      string
+	Target     string
+	Optional   bool
+}
+
+// references mirrors the foreign keys of the SQL schema
+var references = []reference{
+	{Collection: "student_profiles", Field: "user_id", Target: "profiles"},
+	{Collection: "companies", Field: "recruiter_id", Target: "profiles", Optional: true},
+	{Collection: "company_members", Field: "company_id", Target: "companies"},
+	{Collection: "company_members", Field: "user_id", Target: "profiles"},
+	{Collection: "job_postings", Field: "company_id", Target: "companies"},
+	{Collection: "resumes", Field: "student_id", Target: "student_profiles"},
+	{Collection: "applications", Field: "job_id", Target: "job_postings"},
+	{Collection: "applications", Field: "student_id", Target: "student_profiles"},
+	{Collection: "applications", Field: "resume_id", Target: "resumes", Optional: true},
+	{Collection: "interviews", Field: "application_id", Target: "applications"},
+	{Collection: "notifications", Field: "user_id", Target: "profiles"},
+	{Collection: "verification_requests", Field: "company_id", Target: "companies"},
+	{Collection: "verification_requests", Field: "submitted_by", Target: "profiles", Optional: true},
+	{Collection: "verification_requests", Field: "reviewed_by", Target: "profiles", Optional: true},
+	{Collection: "webhooks", Field: "company_id", Target: "companies"},
+	{Collection: "webhook_deliveries", Field: "webhook_id", Target: "webhooks"},
+}
+
+// Collections returns every collection name in dependency order: records
+// only reference collections listed before their own.
+func Collections() []string {
+	out := make([]string, 0, len(tables))
+	for _, t := range tables {
+		out = append(out, t.name)
+	}
+	return out
+}
+
+func tableNamed(name string) (table, bool) {
+	for _, t := range tables {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return table{}, false
+}
+
+// DecodeRecord parses one relaxed extended JSON document (as written by
+// EncodeRecord) into the record type kept for collection
+func DecodeRecord(collection string, data []byte) (interface{}, error) {
+	t, ok := tableNamed(collection)
+	if !ok {
+		return nil, fmt.Errorf("unknown collection %q", collection)
+	}
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON(data, false, &doc); err != nil {
+		return nil, err
+	}
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	v, err := t.decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeRecord(v), nil
+}
+
+// EncodeRecord renders a record as relaxed extended JSON on a single line.
+// Field names are the stored (bson) names, so nothing tagged json:"-" is
+// lost. Map records are written with sorted keys so output is stable.
+func EncodeRecord(v interface{}) ([]byte, error) {
+	if m, ok := v.(map[string]interface{}); ok {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		doc := make(bson.D, 0, len(keys))
+		for _, k := range keys {
+			doc = append(doc, bson.E{Key: k, Value: m[k]})
+		}
+		v = doc
+	}
+	return bson.MarshalExtJSON(v, false, false)
+}
+
+// normalizeRecord gives interview maps read from Mongo, which key the id as
+// _id, the "id" key the other backends use
+func normalizeRecord(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	if id, ok := m["_id"]; ok {
+		if _, has := m["id"]; !has {
+			m["id"] = fmt.Sprint(id)
+		}
+		delete(m, "_id")
+	}
+	return m
+}
+
+// recordField returns a string field of a record by its json name
+func recordField(v interface{}, field string) string {
+	if m, ok := v.(map[string]interface{}); ok {
+		s, _ := m[field].(string)
+		return s
+	}
+	return stringFields(v)[field]
+}
+
+// Export reads every collection. Records are sorted by id, except the audit
+// log, which keeps its insertion order.
+func Export(ctx context.Context) (Dataset, error) {
+	var out Dataset
+	var err error
+	switch {
+	case UseInMemory():
+		out = exportMemory()
+	case Mode() == ModePostgres:
+		out, err = pgExport(ctx)
+	default:
+		out, err = exportMongo(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for name, rows := range out {
+		if name == "audit_log" {
+			continue
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			return recordField(rows[i], "id") < recordField(rows[j], "id")
+		})
+	}
+	return out, nil
+}
+
+func exportMemory() Dataset {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := Dataset{}
+	for _, t := range tables {
+		rows := make([]interface{}, 0)
+		_ = t.each(func(_ string, v interface{}) error {
+			rows = append(rows, v)
+			return nil
+		})
+		out[t.name] = rows
+	}
+	return out
+}
+
+func exportMongo(ctx context.Context) (Dataset, error) {
+	out := Dataset{}
+	for _, t := range tables {
+		cur, err := DB.Collection(t.name).Find(ctx, bson.M{})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.name, err)
+		}
+		rows := make([]interface{}, 0)
+		for cur.Next(ctx) {
+			v, err := t.decode(cur.Current)
+			if err != nil {
+				cur.Close(ctx)
+				return nil, fmt.Errorf("%s: %w", t.name, err)
+			}
+			rows = append(rows, normalizeRecord(v))
+		}
+		err = cur.Err()
+		cur.Close(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.name, err)
+		}
+		out[t.name] = rows
+	}
+	return out, nil
+}
+
+// CheckIntegrity validates a dataset before it replaces the store: every
+// collection is known, ids are present and distinct, references resolve and
+// unique indexes hold. It returns a *ValidationError listing the problems.
+func CheckIntegrity(d Dataset) error {
+	verr := &ValidationError{}
+	report := func(field, format string, args ...interface{}) {
+		if len(verr.Fields) < maxIntegrityErrors {
+			verr.add(field, format, args...)
+		}
+	}
+	for name := range d {
+		if _, ok := tableNamed(name); !ok {
+			report(name, "unknown collection")
+		}
+	}
+
+	ids := map[string]map[string]bool{}
+	for _, name := range Collections() {
+		ids[name] = map[string]bool{}
+		for i, rec := range d[name] {
+			id := recordField(rec, "id")
+			switch {
+			case id == "":
+				report(fmt.Sprintf("%s[%d].id", name, i), "is required")
+			case ids[name][id]:
+				report(fmt.Sprintf("%s/%s", name, id), "duplicate id")
+			}
+			ids[name][id] = true
+		}
+	}
+
+	for _, ref := range references {
+		for _, rec := range d[ref.Collection] {
+			v := recordField(rec, ref.Field)
+			field := fmt.Sprintf("%s/%s.%s", ref.Collection, recordField(rec, "id"), ref.Field)
+			switch {
+			case v == "" && !ref.Optional:
+				report(field, "is required")
+			case v != "" && !ids[ref.Target][v]:
+				report(field, "references missing %s %s", ref.Target, v)
+			}
+		}
+	}
+
+	for _, idx := range uniqueIndexes {
+		seen := map[string]string{}
+		for _, rec := range d[idx.Collection] {
+			key := uniqueKey(idx, rec)
+			if key == "" {
+				continue
+			}
+			id := recordField(rec, "id")
+			if other, ok := seen[key]; ok {
+				report(fmt.Sprintf("%s/%s.%s", idx.Collection, id, idx.Field), "%s (also %s)", idx.Message, other)
+				continue
+			}
+			seen[key] = id
+		}
+	}
+	return verr.orNil()
+}
+
+// Import replaces the contents of every collection with d after checking
+// its integrity. Postgres replaces everything in one transaction; the other
+// backends validate and decode up front so a rejected dataset changes
+// nothing.
+func Import(ctx context.Context, d Dataset) error {
+	if err := CheckIntegrity(d); err != nil {
+		return err
+	}
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		resetTablesLocked()
+		for _, t := range tables {
+			for _, rec := range d[t.name] {
+				t.set(recordField(rec, "id"), rec)
+			}
+		}
+		rebuildUniqueLocked()
+		if boltDB != nil {
+			return boltDB.Update(dumpTx)
+		}
+		return nil
+	}
+	if Mode() == ModePostgres {
+		return pgImport(ctx, d)
+	}
+	return importMongo(ctx, d)
+}
+
+func importMongo(ctx context.Context, d Dataset) error {
+	names := Collections()
+	// children first, so a failure part way never leaves dangling references
+	for i := len(names) - 1; i >= 0; i-- {
+		if _, err := DB.Collection(names[i]).DeleteMany(ctx, bson.M{}); err != nil {
+			return fmt.Errorf("%s: %w", names[i], err)
+		}
+	}
+	for _, name := range names {
+		rows := d[name]
+		if len(rows) == 0 {
+			continue
+		}
+		docs := make([]interface{}, 0, len(rows))
+		for _, rec := range rows {
+			if m, ok := rec.(map[string]interface{}); ok {
+				doc := make(map[string]interface{}, len(m))
+				for k, v := range m {
+					doc[k] = v
+				}
+				doc["_id"] = doc["id"]
+				delete(doc, "id")
+				rec = doc
+			}
+			docs = append(docs, rec)
+		}
+		if _, err := DB.Collection(name).InsertMany(ctx, docs); err != nil {
+			return fmt.Errorf("%s: %w", name, uniqueErr(name, err))
+		}
+	}
+	return nil
+}
+
+// BlobKeys lists the blob store keys the records of d point at
+func BlobKeys(d Dataset) []string {
+	keys := make([]string, 0)
+	for _, rec := range d["resumes"] {
+		if r, ok := rec.(Resume); ok && r.BlobKey != "" {
+			keys = append(keys, r.BlobKey)
+		}
+	}
+	for _, rec := range d["verification_requests"] {
+		if v, ok := rec.(VerificationRequest); ok {
+			for _, doc := range v.Documents {
+				if doc.BlobKey != "" {
+					keys = append(keys, doc.BlobKey)
+				}
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
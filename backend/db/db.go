@@ -1,20 +1,28 @@
 package db
 
 import (
+	"backend/config"
+	"backend/telemetry"
 	"context"
-	"errors"
-	"log"
-	"os"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strings"
 	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var DB *mongo.Database
-var UseInMemory bool
+
+// mongoName is the configured Mongo database
+var mongoName string
 
 var mu sync.RWMutex
 var inMemoryProfiles map[string]Profile
@@ -25,6 +33,30 @@ var inMemoryResumes map[string]Resume
 var inMemoryApplications map[string]Application
 var inMemoryInterviews map[string]map[string]interface{}
 
+// OpTimeout bounds a single storage operation. Every db function derives its
+// context from the caller's with this deadline, so queries stop when the
+// request that issued them is cancelled or the database hangs.
+var OpTimeout = 10 * time.Second
+
+// opCtx also opens a span named after the calling function and times the
+// call; the returned cancel ends both
+func opCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	op := "unknown"
+	if pc, _, _, ok := runtime.Caller(1); ok {
+		name := runtime.FuncForPC(pc).Name()
+		op = name[strings.LastIndex(name, ".")+1:]
+	}
+	backend := Mode()
+	ctx, span := telemetry.Tracer().Start(ctx, "db."+op, trace.WithAttributes(attribute.String("db.system", backend)))
+	ctx, cancel := context.WithTimeout(ctx, OpTimeout)
+	start := time.Now()
+	return ctx, func() {
+		cancel()
+		span.End()
+		opDuration.ObserveSince(start, op, backend)
+	}
+}
+
 // Profile represents a simple user profile stored in Mongo
 type Profile struct {
 	ID        string `bson:"_id,omitempty" json:"id"`
@@ -33,6 +65,7 @@ type Profile struct {
 	Role      string `bson:"role,omitempty" json:"role"`
 	CreatedAt string `bson:"created_at,omitempty" json:"created_at"`
 	UpdatedAt string `bson:"updated_at,omitempty" json:"updated_at"`
+	Version   int64  `bson:"version" json:"version"`
 }
 
 // StudentProfile represents student-specific data
@@ -46,8 +79,11 @@ type StudentProfile struct {
 	Skills         interface{} `bson:"skills,omitempty" json:"skills"`
 	Projects       interface{} `bson:"projects,omitempty" json:"projects"`
 	Internships    interface{} `bson:"internships,omitempty" json:"internships"`
+	Backlogs       int         `bson:"backlogs" json:"backlogs"`
+	GapMonths      int         `bson:"gap_months" json:"gap_months"`
 	CreatedAt      string      `bson:"created_at,omitempty" json:"created_at"`
 	UpdatedAt      string      `bson:"updated_at,omitempty" json:"updated_at"`
+	Version        int64       `bson:"version" json:"version"`
 }
 
 // Generic minimal models for companies, jobs, resumes, applications
@@ -63,23 +99,46 @@ type Company struct {
 	Verified    bool   `bson:"verified,omitempty" json:"verified"`
 	CreatedAt   string `bson:"created_at,omitempty" json:"created_at"`
 	UpdatedAt   string `bson:"updated_at,omitempty" json:"updated_at"`
+	Version     int64  `bson:"version" json:"version"`
 }
 
+// JobPosting moves through draft -> active -> closed. PublishAt schedules a
+// draft for automatic publishing; ApplicationDeadline and Openings drive
+// automatic closing (see RunJobLifecycle in handlers).
 type JobPosting struct {
 	ID                  string      `bson:"_id,omitempty" json:"id"`
 	CompanyID           string      `bson:"company_id" json:"company_id"`
 	Title               string      `bson:"title" json:"title"`
 	Description         string      `bson:"description" json:"description"`
+	Role                string      `bson:"role,omitempty" json:"role"`
+	Openings            int         `bson:"openings,omitempty" json:"openings"`
+	SalaryMin           float64     `bson:"salary_min,omitempty" json:"salary_min"`
+	SalaryMax           float64     `bson:"salary_max,omitempty" json:"salary_max"`
+	JobLocation         string      `bson:"job_location,omitempty" json:"job_location"`
+	BondTerms           string      `bson:"bond_terms,omitempty" json:"bond_terms"`
 	EligibilityCriteria interface{} `bson:"eligibility_criteria,omitempty" json:"eligibility_criteria"`
+	ApplicationDeadline string      `bson:"application_deadline,omitempty" json:"application_deadline"`
 	Status              string      `bson:"status" json:"status"`
+	PublishAt           string      `bson:"publish_at,omitempty" json:"publish_at,omitempty"`
+	PublishedAt         string      `bson:"published_at,omitempty" json:"published_at,omitempty"`
+	ClosedAt            string      `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
 	CreatedAt           string      `bson:"created_at,omitempty" json:"created_at"`
+	UpdatedAt           string      `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	DeletedAt           string      `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	Version             int64       `bson:"version" json:"version"`
 }
 
+// Resume is an uploaded resume. The file itself lives in the blob store
+// under BlobKey; FileURL is its download route.
 type Resume struct {
-	ID        string `bson:"_id,omitempty" json:"id"`
-	StudentID string `bson:"student_id" json:"student_id"`
-	FileURL   string `bson:"file_url" json:"file_url"`
-	CreatedAt string `bson:"created_at,omitempty" json:"created_at"`
+	ID          string `bson:"_id,omitempty" json:"id"`
+	StudentID   string `bson:"student_id" json:"student_id"`
+	FileName    string `bson:"file_name,omitempty" json:"file_name"`
+	FileURL     string `bson:"file_url" json:"file_url"`
+	ContentType string `bson:"content_type,omitempty" json:"content_type,omitempty"`
+	Size        int64  `bson:"size,omitempty" json:"size,omitempty"`
+	BlobKey     string `bson:"blob_key,omitempty" json:"-"`
+	CreatedAt   string `bson:"created_at,omitempty" json:"created_at"`
 }
 
 type Application struct {
@@ -93,224 +152,505 @@ type Application struct {
 	AppliedAt         string `bson:"applied_at,omitempty" json:"applied_at"`
 	CreatedAt         string `bson:"created_at,omitempty" json:"created_at"`
 	UpdatedAt         string `bson:"updated_at,omitempty" json:"updated_at"`
+	Version           int64  `bson:"version" json:"version"`
 }
 
-func Init() {
-	uri := os.Getenv("MONGO_URI")
-	if uri == "" {
-		// prefer 127.0.0.1 to avoid IPv6 (::1) resolution issues on some systems
-		uri = "mongodb://127.0.0.1:27017"
+func Init(cfg config.DB) {
+	// Driver selects the backend: mongo, memory, bolt (embedded file at
+	// BoltPath) or postgres (the Supabase schema at DatabaseURL). What happens
+	// when it is unreachable is up to Fallback, see connectPrimary.
+	fallback = cfg.Fallback
+	mongoName = cfg.MongoDB
+	switch cfg.Driver {
+	case ModePostgres:
+		connectPrimary(ModePostgres, func(ctx context.Context) error { return switchToPostgres(ctx, cfg.DatabaseURL) })
+	case ModeMemory:
+		setPrimary(ModeMemory, nil)
+		switchToInMemory()
+	case ModeBolt:
+		connectPrimary(ModeBolt, func(context.Context) error { return switchToBolt(cfg.BoltPath) })
+	default:
+		connectPrimary(ModeMongo, func(ctx context.Context) error { return switchToMongo(ctx, cfg.MongoURI, cfg.MongoDB) })
 	}
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Name returns the name of the database in use, or the configured Mongo
+// database while an embedded store or the fallback serves instead
+func Name() string {
+	if DB != nil {
+		return DB.Name()
+	}
+	if PG != nil {
+		return PG.Config().ConnConfig.Database
+	}
+	return mongoName
+}
+
+// switchToMongo connects to the Mongo deployment at uri and makes dbName the
+// active database. Callers fall back to the memory mode if it fails.
+func switchToMongo(ctx context.Context, uri, dbName string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	clientOpts := options.Client().ApplyURI(uri)
-	client, err := mongo.Connect(ctx, clientOpts)
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetMonitor(commandMonitor))
 	if err != nil {
-		log.Println("warning: failed to connect to mongo, switching to in-memory DB:", err)
-		switchToInMemory()
-		return
+		return err
 	}
-
 	if err := client.Ping(ctx, nil); err != nil {
-		log.Println("warning: failed to ping mongo, switching to in-memory DB:", err)
-		switchToInMemory()
-		return
-	}
-
-	dbName := os.Getenv("MONGO_DB")
-	if dbName == "" {
-		dbName = "placement_portal"
+		client.Disconnect(ctx)
+		return err
 	}
 
 	DB = client.Database(dbName)
+	EnsureIndexes(ctx)
+	mongoTransactions.Store(detectMongoTransactions(ctx, client))
+	setMode(ModeMongo)
 
 	// Log successful connection so it's obvious in startup logs whether
 	// the app is using MongoDB or the in-memory fallback.
-	log.Println("connected to mongo:", uri, "db:", dbName)
+	if u, err := url.Parse(uri); err == nil {
+		uri = u.Redacted()
+	}
+	slog.Info("connected to mongo", "uri", uri, "db", dbName, "transactions", mongoTransactions.Load())
+	return nil
 }
 
 func switchToInMemory() {
-	UseInMemory = true
-	inMemoryProfiles = make(map[string]Profile)
-	inMemoryStudentProfiles = make(map[string]StudentProfile)
-	inMemoryCompanies = make(map[string]Company)
-	inMemoryJobPostings = make(map[string]JobPosting)
-	inMemoryResumes = make(map[string]Resume)
-	inMemoryApplications = make(map[string]Application)
-	inMemoryInterviews = make(map[string]map[string]interface{})
-	log.Println("Using in-memory DB (development fallback)")
+	mu.Lock()
+	defer mu.Unlock()
+	resetTablesLocked()
+	setMode(ModeMemory)
+	slog.Info("using in-memory DB")
 }
 
 // Companies
-func GetCompanies(filter map[string]interface{}) ([]Company, error) {
-	if UseInMemory {
+// GetCompanies lists companies. Besides plain field filters it understands
+// "member_id", which matches companies the user belongs to through a team
+// membership or as the legacy recruiter of record.
+func GetCompanies(ctx context.Context, filter map[string]interface{}) ([]Company, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
 		mu.RLock()
 		defer mu.RUnlock()
 		out := make([]Company, 0)
+		if uid, ok := filter["member_id"].(string); ok && uid != "" {
+			member := map[string]bool{}
+			for _, m := range memberRowsLocked(map[string]interface{}{"user_id": uid}) {
+				member[m.CompanyID] = true
+			}
+			for _, c := range inMemoryCompanies {
+				if c.RecruiterID == uid || member[c.ID] {
+					out = append(out, c)
+				}
+			}
+			return out, nil
+		}
 		if rid, ok := filter["recruiter_id"].(string); ok && rid != "" {
 			for _, c := range inMemoryCompanies {
 				if c.RecruiterID == rid {
-					// keep verified in sync with approved for older entries
-					if !c.Verified && c.Approved {
-						c.Verified = true
-					}
 					out = append(out, c)
 				}
 			}
 			return out, nil
 		}
 		for _, c := range inMemoryCompanies {
-			if !c.Verified && c.Approved {
-				c.Verified = true
-			}
 			out = append(out, c)
 		}
 		return out, nil
 	}
+	if Mode() == ModePostgres {
+		return pgGetCompanies(ctx, filter)
+	}
+	if uid, ok := filter["member_id"].(string); ok {
+		ids, err := memberCompanyIDs(ctx, uid)
+		if err != nil {
+			return nil, err
+		}
+		q := bson.M{}
+		for k, v := range filter {
+			if k != "member_id" {
+				q[k] = v
+			}
+		}
+		q["$or"] = bson.A{bson.M{"recruiter_id": uid}, bson.M{"_id": bson.M{"$in": ids}}}
+		filter = q
+	}
 	col := DB.Collection("companies")
-	cur, err := col.Find(context.Background(), filter)
+	cur, err := col.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	var out []Company
-	if err := cur.All(context.Background(), &out); err != nil {
+	if err := cur.All(ctx, &out); err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func CreateCompany(c Company) error {
-	if UseInMemory {
+// GetCompany returns a single company by id
+func GetCompany(ctx context.Context, id string) (Company, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		if co, ok := inMemoryCompanies[id]; ok {
+			return co, nil
+		}
+		return Company{}, ErrNotFound
+	}
+	if Mode() == ModePostgres {
+		return pgGet[Company](ctx, "companies", id)
+	}
+	var co Company
+	if err := DB.Collection("companies").FindOne(ctx, bson.M{"_id": id}).Decode(&co); err != nil {
+		return Company{}, notFound(err)
+	}
+	return co, nil
+}
+
+func CreateCompany(ctx context.Context, c Company) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	c.Version = FirstVersion
+	// verified and approved name the same flag; store them in agreement
+	c.Verified = c.Verified || c.Approved
+	c.Approved = c.Verified
+	if UseInMemory() {
 		mu.Lock()
 		defer mu.Unlock()
+		if _, ok := inMemoryCompanies[c.ID]; ok {
+			return &ConflictError{Field: "id", Message: "duplicate companies record"}
+		}
+		if err := persistLocked("companies", c.ID, c); err != nil {
+			return err
+		}
 		inMemoryCompanies[c.ID] = c
 		return nil
 	}
-	_, err := DB.Collection("companies").InsertOne(context.Background(), c)
-	return err
+	if Mode() == ModePostgres {
+		return pgInsert(ctx, PG, "companies", c)
+	}
+	_, err := DB.Collection("companies").InsertOne(ctx, c)
+	return uniqueErr("companies", err)
 }
 
-func UpdateCompany(id string, patch map[string]interface{}) error {
-	if UseInMemory {
+func UpdateCompany(ctx context.Context, id string, p CompanyPatch, events ...Event) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	fields := patchFields(p)
+	if _, ok := fields["updated_at"]; !ok {
+		fields["updated_at"] = time.Now().Format(time.RFC3339)
+	}
+	if UseInMemory() {
 		mu.Lock()
 		defer mu.Unlock()
 		co, ok := inMemoryCompanies[id]
 		if !ok {
-			return errors.New("not found")
-		}
-		if v, ok := patch["name"].(string); ok {
-			co.Name = v
-		}
-		if v, ok := patch["approved"].(bool); ok {
-			co.Approved = v
-		}
-		// support 'verified' patch key used by frontend admin UI
-		if v, ok := patch["verified"].(bool); ok {
-			co.Verified = v
-			// keep approved in sync for legacy/alternate naming
-			co.Approved = v
-		}
-		if v, ok := patch["description"].(string); ok {
-			co.Description = v
-		}
-		if v, ok := patch["website"].(string); ok {
-			co.Website = v
-		}
-		if v, ok := patch["industry"].(string); ok {
-			co.Industry = v
-		}
-		if v, ok := patch["email"].(string); ok {
-			co.Email = v
+			return ErrNotFound
 		}
-		if v, ok := patch["recruiter_id"].(string); ok {
-			co.RecruiterID = v
+		if err := checkVersion(co.Version, p.IfVersion); err != nil {
+			return err
 		}
-		if v, ok := patch["updated_at"].(string); ok {
-			co.UpdatedAt = v
-		}
-		inMemoryCompanies[id] = co
-		return nil
+		applyFields(&co, fields)
+		co.Version++
+		var batch memBatch
+		batch.put("companies", id, co, func() { inMemoryCompanies[id] = co })
+		batch.publish(events)
+		return batch.commitLocked()
 	}
-	_, err := DB.Collection("companies").UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": patch})
-	return err
+	if Mode() == ModePostgres {
+		return pgUpdatePublishing(ctx, "companies", reflect.TypeOf(Company{}), id, fields, p.IfVersion, events)
+	}
+	return mongoUpdatePublishing(ctx, "companies", id, bson.M{"$set": fields}, p.IfVersion, events)
 }
 
 // Job postings
-func GetJobPostings(filter map[string]interface{}) ([]JobPosting, error) {
-	if UseInMemory {
+
+// GetJobPostings lists job postings. Soft-deleted postings are skipped unless
+// the filter carries "include_deleted": true.
+func GetJobPostings(ctx context.Context, filter map[string]interface{}) ([]JobPosting, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	includeDeleted, _ := filter["include_deleted"].(bool)
+	if UseInMemory() {
 		mu.RLock()
 		defer mu.RUnlock()
 		out := make([]JobPosting, 0)
-		if cid, ok := filter["company_id"].(string); ok && cid != "" {
-			for _, j := range inMemoryJobPostings {
-				if j.CompanyID == cid {
-					out = append(out, j)
-				}
-			}
-			return out, nil
-		}
+		cid, _ := filter["company_id"].(string)
+		status, _ := filter["status"].(string)
 		for _, j := range inMemoryJobPostings {
+			if cid != "" && j.CompanyID != cid {
+				continue
+			}
+			if status != "" && j.Status != status {
+				continue
+			}
+			if j.DeletedAt != "" && !includeDeleted {
+				continue
+			}
 			out = append(out, j)
 		}
 		return out, nil
 	}
+	if Mode() == ModePostgres {
+		return pgGetJobPostings(ctx, filter)
+	}
+	q := bson.M{}
+	for k, v := range filter {
+		if k != "include_deleted" {
+			q[k] = v
+		}
+	}
+	if !includeDeleted {
+		q["deleted_at"] = bson.M{"$exists": false}
+	}
 	col := DB.Collection("job_postings")
-	cur, err := col.Find(context.Background(), filter)
+	cur, err := col.Find(ctx, q)
 	if err != nil {
 		return nil, err
 	}
 	var out []JobPosting
-	if err := cur.All(context.Background(), &out); err != nil {
+	if err := cur.All(ctx, &out); err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func CreateJobPosting(j JobPosting) error {
-	if UseInMemory {
+// GetJobPosting returns a single job posting by id
+func GetJobPosting(ctx context.Context, id string) (JobPosting, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		if j, ok := inMemoryJobPostings[id]; ok {
+			return j, nil
+		}
+		return JobPosting{}, ErrNotFound
+	}
+	if Mode() == ModePostgres {
+		return pgGet[JobPosting](ctx, "job_postings", id)
+	}
+	var j JobPosting
+	if err := DB.Collection("job_postings").FindOne(ctx, bson.M{"_id": id}).Decode(&j); err != nil {
+		return JobPosting{}, notFound(err)
+	}
+	return j, nil
+}
+
+func CreateJobPosting(ctx context.Context, j JobPosting) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	j.Version = FirstVersion
+	if UseInMemory() {
 		mu.Lock()
 		defer mu.Unlock()
+		if _, ok := inMemoryJobPostings[j.ID]; ok {
+			return &ConflictError{Field: "id", Message: "duplicate job_postings record"}
+		}
+		if err := persistLocked("job_postings", j.ID, j); err != nil {
+			return err
+		}
 		inMemoryJobPostings[j.ID] = j
 		return nil
 	}
-	_, err := DB.Collection("job_postings").InsertOne(context.Background(), j)
-	return err
+	if Mode() == ModePostgres {
+		return pgInsert(ctx, PG, "job_postings", j)
+	}
+	_, err := DB.Collection("job_postings").InsertOne(ctx, j)
+	return uniqueErr("job_postings", err)
 }
 
-func UpdateJobPosting(id string, patch map[string]interface{}) error {
-	if UseInMemory {
+func UpdateJobPosting(ctx context.Context, id string, p JobPostingPatch, events ...Event) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	fields := patchFields(p)
+	if _, ok := fields["updated_at"]; !ok {
+		fields["updated_at"] = time.Now().Format(time.RFC3339)
+	}
+	if UseInMemory() {
 		mu.Lock()
 		defer mu.Unlock()
 		jp, ok := inMemoryJobPostings[id]
 		if !ok {
-			return errors.New("not found")
+			return ErrNotFound
 		}
-		if v, ok := patch["status"].(string); ok {
-			jp.Status = v
+		if err := checkVersion(jp.Version, p.IfVersion); err != nil {
+			return err
 		}
-		inMemoryJobPostings[id] = jp
-		return nil
+		applyFields(&jp, fields)
+		jp.Version++
+		var batch memBatch
+		batch.put("job_postings", id, jp, func() { inMemoryJobPostings[id] = jp })
+		batch.publish(events)
+		return batch.commitLocked()
+	}
+	if Mode() == ModePostgres {
+		return pgUpdatePublishing(ctx, "job_postings", reflect.TypeOf(JobPosting{}), id, fields, p.IfVersion, events)
+	}
+	return mongoUpdatePublishing(ctx, "job_postings", id, bson.M{"$set": fields}, p.IfVersion, events)
+}
+
+// openApplicationStatuses are the application states that a job deletion
+// cascades to; decided applications (selected, rejected, offers) are kept as-is.
+var openApplicationStatuses = []string{"applied", "shortlisted", "interview_scheduled"}
+
+func isOpenApplicationStatus(s string) bool {
+	for _, o := range openApplicationStatuses {
+		if s == o {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteJobPosting soft-deletes a job posting by stamping deleted_at and
+// closing it. Mirroring the schema's ON DELETE CASCADE intent, open
+// applications on the job are moved to "closed", each publishing an
+// application status event; the closed applications are returned.
+func DeleteJobPosting(ctx context.Context, id string) ([]Application, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	now := time.Now().Format(time.RFC3339)
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		jp, ok := inMemoryJobPostings[id]
+		if !ok || jp.DeletedAt != "" {
+			return nil, ErrNotFound
+		}
+		jp.DeletedAt = now
+		jp.Status = "closed"
+		jp.Version++
+		var batch memBatch
+		batch.put("job_postings", id, jp, func() { inMemoryJobPostings[id] = jp })
+		closed := make([]Application, 0)
+		events := make([]Event, 0)
+		for aid, a := range inMemoryApplications {
+			if a.JobID == id && isOpenApplicationStatus(a.Status) {
+				from := a.Status
+				a.Status = "closed"
+				a.UpdatedAt = now
+				a.Version++
+				batch.put("applications", aid, a, func() { inMemoryApplications[aid] = a })
+				closed = append(closed, a)
+				events = append(events, ApplicationStatusEvent(a, jp.CompanyID, from))
+			}
+		}
+		batch.publish(events)
+		if err := batch.commitLocked(); err != nil {
+			return nil, err
+		}
+		return closed, nil
+	}
+	if Mode() == ModePostgres {
+		return pgDeleteJobPosting(ctx, id, now)
+	}
+	var closed []Application
+	err := mongoTx(ctx, func(u *mongoUnit) error {
+		var job JobPosting
+		err := u.write(func(ctx context.Context) error {
+			return notFound(DB.Collection("job_postings").FindOneAndUpdate(ctx,
+				bson.M{"_id": id, "deleted_at": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"deleted_at": now, "status": "closed"}, "$inc": bson.M{"version": 1}}).Decode(&job))
+		}, "job_postings/"+id)
+		if err != nil {
+			return err
+		}
+		apps := DB.Collection("applications")
+		cur, err := apps.Find(u.ctx, bson.M{"job_id": id, "status": bson.M{"$in": openApplicationStatuses}})
+		if err != nil {
+			return err
+		}
+		closed = make([]Application, 0)
+		if err := cur.All(u.ctx, &closed); err != nil {
+			return err
+		}
+		if len(closed) == 0 {
+			return nil
+		}
+		ids := make([]string, 0, len(closed))
+		records := make([]string, 0, len(closed))
+		events := make([]Event, 0, len(closed))
+		for i := range closed {
+			from := closed[i].Status
+			closed[i].Status = "closed"
+			closed[i].UpdatedAt = now
+			closed[i].Version++
+			ids = append(ids, closed[i].ID)
+			records = append(records, "applications/"+closed[i].ID)
+			events = append(events, ApplicationStatusEvent(closed[i], job.CompanyID, from))
+		}
+		err = u.write(func(ctx context.Context) error {
+			_, err := apps.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}},
+				bson.M{"$set": bson.M{"status": "closed", "updated_at": now}, "$inc": bson.M{"version": 1}})
+			return err
+		}, records...)
+		if err != nil {
+			return err
+		}
+		return u.publish(events)
+	})
+	if err != nil {
+		return nil, err
 	}
-	_, err := DB.Collection("job_postings").UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": patch})
-	return err
+	return closed, nil
 }
 
-func DeleteJobPosting(id string) error {
-	if UseInMemory {
+// RestoreJobPosting clears deleted_at on a soft-deleted posting. The job comes
+// back closed and its auto-closed applications stay closed; reopening is an
+// explicit recruiter decision.
+func RestoreJobPosting(ctx context.Context, id string) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
 		mu.Lock()
 		defer mu.Unlock()
-		delete(inMemoryJobPostings, id)
+		jp, ok := inMemoryJobPostings[id]
+		if !ok {
+			return ErrNotFound
+		}
+		if jp.DeletedAt == "" {
+			return notDeleted()
+		}
+		jp.DeletedAt = ""
+		jp.Version++
+		if err := persistLocked("job_postings", id, jp); err != nil {
+			return err
+		}
+		inMemoryJobPostings[id] = jp
 		return nil
 	}
-	_, err := DB.Collection("job_postings").DeleteOne(context.Background(), bson.M{"_id": id})
-	return err
+	if Mode() == ModePostgres {
+		return pgRestoreJobPosting(ctx, id)
+	}
+	res, err := DB.Collection("job_postings").UpdateOne(ctx,
+		bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"deleted_at": ""}, "$inc": bson.M{"version": 1}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		if n, err := DB.Collection("job_postings").CountDocuments(ctx, bson.M{"_id": id}); err == nil && n > 0 {
+			return notDeleted()
+		}
+		return ErrNotFound
+	}
+	return nil
+}
+
+// notDeleted reports a restore of a job posting that is not deleted
+func notDeleted() error {
+	return &ConflictError{Field: "deleted_at", Message: "job posting is not deleted"}
 }
 
 // Resumes
-func GetResumes(filter map[string]interface{}) ([]Resume, error) {
-	if UseInMemory {
+func GetResumes(ctx context.Context, filter map[string]interface{}) ([]Resume, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
 		mu.RLock()
 		defer mu.RUnlock()
 		out := make([]Resume, 0)
@@ -327,32 +667,70 @@ func GetResumes(filter map[string]interface{}) ([]Resume, error) {
 		}
 		return out, nil
 	}
+	if Mode() == ModePostgres {
+		return pgList[Resume](ctx, "resumes", filter, "")
+	}
 	col := DB.Collection("resumes")
-	cur, err := col.Find(context.Background(), filter)
+	cur, err := col.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	var out []Resume
-	if err := cur.All(context.Background(), &out); err != nil {
+	if err := cur.All(ctx, &out); err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func CreateResume(r Resume) error {
-	if UseInMemory {
+// GetResume returns a resume by id
+func GetResume(ctx context.Context, id string) (Resume, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		if r, ok := inMemoryResumes[id]; ok {
+			return r, nil
+		}
+		return Resume{}, ErrNotFound
+	}
+	if Mode() == ModePostgres {
+		return pgGet[Resume](ctx, "resumes", id)
+	}
+	var r Resume
+	if err := DB.Collection("resumes").FindOne(ctx, bson.M{"_id": id}).Decode(&r); err != nil {
+		return Resume{}, notFound(err)
+	}
+	return r, nil
+}
+
+func CreateResume(ctx context.Context, r Resume) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
 		mu.Lock()
 		defer mu.Unlock()
+		if _, ok := inMemoryResumes[r.ID]; ok {
+			return &ConflictError{Field: "id", Message: "duplicate resumes record"}
+		}
+		if err := persistLocked("resumes", r.ID, r); err != nil {
+			return err
+		}
 		inMemoryResumes[r.ID] = r
 		return nil
 	}
-	_, err := DB.Collection("resumes").InsertOne(context.Background(), r)
-	return err
+	if Mode() == ModePostgres {
+		return pgInsert(ctx, PG, "resumes", r)
+	}
+	_, err := DB.Collection("resumes").InsertOne(ctx, r)
+	return uniqueErr("resumes", err)
 }
 
 // Applications
-func GetApplications(filter map[string]interface{}) ([]Application, error) {
-	if UseInMemory {
+func GetApplications(ctx context.Context, filter map[string]interface{}) ([]Application, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
 		mu.RLock()
 		defer mu.RUnlock()
 		out := make([]Application, 0)
@@ -369,22 +747,53 @@ func GetApplications(filter map[string]interface{}) ([]Application, error) {
 		}
 		return out, nil
 	}
+	if Mode() == ModePostgres {
+		return pgList[Application](ctx, "applications", filter, "")
+	}
 	col := DB.Collection("applications")
-	cur, err := col.Find(context.Background(), filter)
+	cur, err := col.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	var out []Application
-	if err := cur.All(context.Background(), &out); err != nil {
+	if err := cur.All(ctx, &out); err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func CreateApplication(a Application) error {
-	if UseInMemory {
+// GetApplication returns a single application by id
+func GetApplication(ctx context.Context, id string) (Application, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		if a, ok := inMemoryApplications[id]; ok {
+			return a, nil
+		}
+		return Application{}, ErrNotFound
+	}
+	if Mode() == ModePostgres {
+		return pgGet[Application](ctx, "applications", id)
+	}
+	var a Application
+	if err := DB.Collection("applications").FindOne(ctx, bson.M{"_id": id}).Decode(&a); err != nil {
+		return Application{}, notFound(err)
+	}
+	return a, nil
+}
+
+func CreateApplication(ctx context.Context, a Application) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	a.Version = FirstVersion
+	if UseInMemory() {
 		mu.Lock()
 		defer mu.Unlock()
+		if _, ok := inMemoryApplications[a.ID]; ok {
+			return &ConflictError{Field: "id", Message: "duplicate applications record"}
+		}
 		// ensure applied_at/created_at exist for in-memory entries
 		if a.AppliedAt == "" {
 			a.AppliedAt = time.Now().Format(time.RFC3339)
@@ -395,127 +804,245 @@ func CreateApplication(a Application) error {
 		if a.Status == "" {
 			a.Status = "applied"
 		}
+		if err := checkUniqueLocked("applications", a.ID, a); err != nil {
+			return err
+		}
+		if err := persistLocked("applications", a.ID, a); err != nil {
+			return err
+		}
+		reindexLocked("applications", a.ID, nil, a)
 		inMemoryApplications[a.ID] = a
 		return nil
 	}
-	_, err := DB.Collection("applications").InsertOne(context.Background(), a)
-	return err
+	if Mode() == ModePostgres {
+		return pgInsert(ctx, PG, "applications", a)
+	}
+	_, err := DB.Collection("applications").InsertOne(ctx, a)
+	return uniqueErr("applications", err)
+}
+
+// CountApplications counts applications on a job whose status is one of statuses
+func CountApplications(ctx context.Context, jobID string, statuses ...string) (int64, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		var n int64
+		for _, a := range inMemoryApplications {
+			if a.JobID != jobID {
+				continue
+			}
+			for _, s := range statuses {
+				if a.Status == s {
+					n++
+					break
+				}
+			}
+		}
+		return n, nil
+	}
+	if Mode() == ModePostgres {
+		return pgCountApplications(ctx, jobID, statuses)
+	}
+	return DB.Collection("applications").CountDocuments(ctx, bson.M{"job_id": jobID, "status": bson.M{"$in": statuses}})
 }
 
-func UpdateApplication(id string, patch map[string]interface{}) error {
-	if UseInMemory {
+func UpdateApplication(ctx context.Context, id string, p ApplicationPatch, events ...Event) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	fields := patchFields(p)
+	if _, ok := fields["updated_at"]; !ok {
+		fields["updated_at"] = time.Now().Format(time.RFC3339)
+	}
+	if UseInMemory() {
 		mu.Lock()
 		defer mu.Unlock()
-		ap, ok := inMemoryApplications[id]
+		old, ok := inMemoryApplications[id]
 		if !ok {
-			return errors.New("not found")
-		}
-		if v, ok := patch["status"].(string); ok {
-			ap.Status = v
+			return ErrNotFound
 		}
-		if v, ok := patch["eligibility_status"].(string); ok {
-			ap.EligibilityStatus = v
+		if err := checkVersion(old.Version, p.IfVersion); err != nil {
+			return err
 		}
-		if v, ok := patch["eligibility_notes"].(string); ok {
-			ap.EligibilityNotes = v
+		ap := old
+		applyFields(&ap, fields)
+		ap.Version++
+		if err := checkUniqueLocked("applications", id, ap); err != nil {
+			return err
 		}
-		if v, ok := patch["updated_at"].(string); ok {
-			ap.UpdatedAt = v
-		}
-		if v, ok := patch["applied_at"].(string); ok {
-			ap.AppliedAt = v
-		}
-		inMemoryApplications[id] = ap
-		return nil
+		var batch memBatch
+		batch.put("applications", id, ap, func() {
+			reindexLocked("applications", id, old, ap)
+			inMemoryApplications[id] = ap
+		})
+		batch.publish(events)
+		return batch.commitLocked()
+	}
+	if Mode() == ModePostgres {
+		return pgUpdatePublishing(ctx, "applications", reflect.TypeOf(Application{}), id, fields, p.IfVersion, events)
 	}
-	_, err := DB.Collection("applications").UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": patch})
-	return err
+	return uniqueErr("applications", mongoUpdatePublishing(ctx, "applications", id, bson.M{"$set": fields}, p.IfVersion, events))
 }
 
 // Interviews
-func CreateInterviewRecord(rec map[string]interface{}) error {
-	if UseInMemory {
+
+// CreateInterviewRecord stores an interview, moves its application to
+// interview_scheduled and publishes events as one unit of work
+func CreateInterviewRecord(ctx context.Context, rec map[string]interface{}, events ...Event) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	id, _ := rec["id"].(string)
+	if id == "" {
+		id = time.Now().Format(time.RFC3339Nano)
+	}
+	rec["id"] = id
+	aid, _ := rec["application_id"].(string)
+	now := time.Now().Format(time.RFC3339)
+	if UseInMemory() {
 		mu.Lock()
 		defer mu.Unlock()
-		id, _ := rec["id"].(string)
-		if id == "" {
-			id = time.Now().Format(time.RFC3339Nano)
-		}
-		rec["id"] = id
-		inMemoryInterviews[id] = rec
-		// update related application status if provided
-		if aid, ok := rec["application_id"].(string); ok && aid != "" {
-			if ap, ok := inMemoryApplications[aid]; ok {
-				ap.Status = "interview_scheduled"
-				inMemoryApplications[aid] = ap
+		if _, ok := inMemoryInterviews[id]; ok {
+			return &ConflictError{Field: "id", Message: "duplicate interviews record"}
+		}
+		var batch memBatch
+		batch.put("interviews", id, rec, func() { inMemoryInterviews[id] = rec })
+		if aid != "" {
+			ap, ok := inMemoryApplications[aid]
+			if !ok {
+				return ErrNotFound
 			}
+			ap.Status = "interview_scheduled"
+			ap.UpdatedAt = now
+			ap.Version++
+			batch.put("applications", aid, ap, func() { inMemoryApplications[aid] = ap })
 		}
-		return nil
+		batch.publish(events)
+		return batch.commitLocked()
+	}
+	if Mode() == ModePostgres {
+		return pgCreateInterview(ctx, rec, events)
 	}
-	_, err := DB.Collection("interviews").InsertOne(context.Background(), rec)
-	return err
+	doc := bson.M{"_id": id}
+	for k, v := range rec {
+		if k != "id" {
+			doc[k] = v
+		}
+	}
+	return mongoTx(ctx, func(u *mongoUnit) error {
+		// the application goes first: it is the write that can miss, and
+		// without a transaction a miss must not leave an interview behind
+		if aid != "" {
+			err := u.write(func(ctx context.Context) error {
+				set := bson.M{"status": "interview_scheduled", "updated_at": now}
+				return mongoUpdateVersioned(ctx, "applications", aid, bson.M{"$set": set}, nil)
+			}, "applications/"+aid)
+			if err != nil {
+				return err
+			}
+		}
+		err := u.write(func(ctx context.Context) error {
+			_, err := DB.Collection("interviews").InsertOne(ctx, doc)
+			return uniqueErr("interviews", err)
+		}, "interviews/"+id)
+		if err != nil {
+			return err
+		}
+		return u.publish(events)
+	})
 }
 
 // GetProfile returns a profile by id. If in-memory mode is enabled it reads the map.
-func GetProfile(id string) (Profile, error) {
-	if UseInMemory {
+func GetProfile(ctx context.Context, id string) (Profile, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
 		mu.RLock()
 		defer mu.RUnlock()
 		if p, ok := inMemoryProfiles[id]; ok {
 			return p, nil
 		}
-		return Profile{}, errors.New("not found")
+		return Profile{}, ErrNotFound
+	}
+	if Mode() == ModePostgres {
+		return pgGet[Profile](ctx, "profiles", id)
 	}
 	var p Profile
 	coll := DB.Collection("profiles")
-	err := coll.FindOne(context.Background(), bson.M{"_id": id}).Decode(&p)
+	err := coll.FindOne(ctx, bson.M{"_id": id}).Decode(&p)
 	if err != nil {
-		return Profile{}, err
+		return Profile{}, notFound(err)
 	}
 	return p, nil
 }
 
-func CreateProfile(p Profile) error {
-	if UseInMemory {
+func CreateProfile(ctx context.Context, p Profile) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	p.Version = FirstVersion
+	if UseInMemory() {
 		mu.Lock()
 		defer mu.Unlock()
+		if _, ok := inMemoryProfiles[p.ID]; ok {
+			return &ConflictError{Field: "id", Message: "duplicate profiles record"}
+		}
+		if err := checkUniqueLocked("profiles", p.ID, p); err != nil {
+			return err
+		}
+		if err := persistLocked("profiles", p.ID, p); err != nil {
+			return err
+		}
+		reindexLocked("profiles", p.ID, nil, p)
 		inMemoryProfiles[p.ID] = p
 		return nil
 	}
-	_, err := DB.Collection("profiles").InsertOne(context.Background(), p)
-	return err
+	if Mode() == ModePostgres {
+		return pgInsert(ctx, PG, "profiles", p)
+	}
+	_, err := DB.Collection("profiles").InsertOne(ctx, p)
+	return uniqueErr("profiles", err)
 }
 
-func UpdateProfile(id string, patch map[string]interface{}) error {
-	if UseInMemory {
+func UpdateProfile(ctx context.Context, id string, p ProfilePatch) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	fields := patchFields(p)
+	if _, ok := fields["updated_at"]; !ok {
+		fields["updated_at"] = time.Now().Format(time.RFC3339)
+	}
+	if UseInMemory() {
 		mu.Lock()
 		defer mu.Unlock()
-		p, ok := inMemoryProfiles[id]
+		old, ok := inMemoryProfiles[id]
 		if !ok {
-			return errors.New("not found")
-		}
-		// apply patch for a few fields
-		if v, ok := patch["role"].(string); ok {
-			p.Role = v
+			return ErrNotFound
 		}
-		if v, ok := patch["full_name"].(string); ok {
-			p.FullName = v
+		if err := checkVersion(old.Version, p.IfVersion); err != nil {
+			return err
 		}
-		if v, ok := patch["email"].(string); ok {
-			p.Email = v
+		pr := old
+		applyFields(&pr, fields)
+		pr.Version++
+		if err := checkUniqueLocked("profiles", id, pr); err != nil {
+			return err
 		}
-		if v, ok := patch["updated_at"].(string); ok {
-			p.UpdatedAt = v
+		if err := persistLocked("profiles", id, pr); err != nil {
+			return err
 		}
-		inMemoryProfiles[id] = p
+		reindexLocked("profiles", id, old, pr)
+		inMemoryProfiles[id] = pr
 		return nil
 	}
-	_, err := DB.Collection("profiles").UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": patch})
-	return err
+	if Mode() == ModePostgres {
+		return pgUpdate(ctx, PG, "profiles", reflect.TypeOf(Profile{}), id, fields, p.IfVersion)
+	}
+	return uniqueErr("profiles", mongoUpdateVersioned(ctx, "profiles", id, bson.M{"$set": fields}, p.IfVersion))
 }
 
-func ListProfiles(filter map[string]interface{}) ([]Profile, error) {
-	if UseInMemory {
+func ListProfiles(ctx context.Context, filter map[string]interface{}) ([]Profile, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
 		mu.RLock()
 		defer mu.RUnlock()
 		out := make([]Profile, 0)
@@ -525,26 +1052,60 @@ func ListProfiles(filter map[string]interface{}) ([]Profile, error) {
 			}
 			return out, nil
 		}
+		if email, ok := filter["email"].(string); ok && email != "" {
+			for _, p := range inMemoryProfiles {
+				if p.Email == email {
+					out = append(out, p)
+				}
+			}
+			return out, nil
+		}
 		for _, p := range inMemoryProfiles {
 			out = append(out, p)
 		}
 		return out, nil
 	}
+	if Mode() == ModePostgres {
+		return pgList[Profile](ctx, "profiles", filter, "")
+	}
 	coll := DB.Collection("profiles")
-	cur, err := coll.Find(context.Background(), filter)
+	cur, err := coll.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	var out []Profile
-	if err := cur.All(context.Background(), &out); err != nil {
+	if err := cur.All(ctx, &out); err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
 // Student profile helpers
-func GetStudentProfiles(filter map[string]interface{}) ([]StudentProfile, error) {
-	if UseInMemory {
+func GetStudentProfile(ctx context.Context, id string) (StudentProfile, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		if sp, ok := inMemoryStudentProfiles[id]; ok {
+			return sp, nil
+		}
+		return StudentProfile{}, ErrNotFound
+	}
+	if Mode() == ModePostgres {
+		return pgGet[StudentProfile](ctx, "student_profiles", id)
+	}
+	var sp StudentProfile
+	if err := DB.Collection("student_profiles").FindOne(ctx, bson.M{"_id": id}).Decode(&sp); err != nil {
+		return StudentProfile{}, notFound(err)
+	}
+	return sp, nil
+}
+
+func GetStudentProfiles(ctx context.Context, filter map[string]interface{}) ([]StudentProfile, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
 		mu.RLock()
 		defer mu.RUnlock()
 		out := make([]StudentProfile, 0)
@@ -568,58 +1129,80 @@ func GetStudentProfiles(filter map[string]interface{}) ([]StudentProfile, error)
 		}
 		return out, nil
 	}
+	if Mode() == ModePostgres {
+		return pgList[StudentProfile](ctx, "student_profiles", filter, "")
+	}
 	coll := DB.Collection("student_profiles")
-	cur, err := coll.Find(context.Background(), filter)
+	cur, err := coll.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	var out []StudentProfile
-	if err := cur.All(context.Background(), &out); err != nil {
+	if err := cur.All(ctx, &out); err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func CreateStudentProfile(sp StudentProfile) error {
-	if UseInMemory {
+func CreateStudentProfile(ctx context.Context, sp StudentProfile) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	sp.Version = FirstVersion
+	if UseInMemory() {
 		mu.Lock()
 		defer mu.Unlock()
+		if _, ok := inMemoryStudentProfiles[sp.ID]; ok {
+			return &ConflictError{Field: "id", Message: "duplicate student_profiles record"}
+		}
+		if err := checkUniqueLocked("student_profiles", sp.ID, sp); err != nil {
+			return err
+		}
+		if err := persistLocked("student_profiles", sp.ID, sp); err != nil {
+			return err
+		}
+		reindexLocked("student_profiles", sp.ID, nil, sp)
 		inMemoryStudentProfiles[sp.ID] = sp
 		return nil
 	}
-	_, err := DB.Collection("student_profiles").InsertOne(context.Background(), sp)
-	return err
+	if Mode() == ModePostgres {
+		return pgInsert(ctx, PG, "student_profiles", sp)
+	}
+	_, err := DB.Collection("student_profiles").InsertOne(ctx, sp)
+	return uniqueErr("student_profiles", err)
 }
 
-func UpdateStudentProfile(id string, patch map[string]interface{}) error {
-	if UseInMemory {
+func UpdateStudentProfile(ctx context.Context, id string, p StudentProfilePatch) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	fields := patchFields(p)
+	if _, ok := fields["updated_at"]; !ok {
+		fields["updated_at"] = time.Now().Format(time.RFC3339)
+	}
+	if UseInMemory() {
 		mu.Lock()
 		defer mu.Unlock()
-		sp, ok := inMemoryStudentProfiles[id]
+		old, ok := inMemoryStudentProfiles[id]
 		if !ok {
-			return errors.New("not found")
-		}
-		if v, ok := patch["roll_number"].(string); ok {
-			sp.RollNumber = v
+			return ErrNotFound
 		}
-		if v, ok := patch["cgpa"].(float64); ok {
-			sp.CGPA = v
+		if err := checkVersion(old.Version, p.IfVersion); err != nil {
+			return err
 		}
-		if v, ok := patch["branch"].(string); ok {
-			sp.Branch = v
+		sp := old
+		applyFields(&sp, fields)
+		sp.Version++
+		if err := checkUniqueLocked("student_profiles", id, sp); err != nil {
+			return err
 		}
-		if v, ok := patch["graduation_year"].(float64); ok {
-			sp.GraduationYear = int(v)
-		}
-		if v, ok := patch["skills"]; ok {
-			sp.Skills = v
-		}
-		if v, ok := patch["updated_at"].(string); ok {
-			sp.UpdatedAt = v
+		if err := persistLocked("student_profiles", id, sp); err != nil {
+			return err
 		}
+		reindexLocked("student_profiles", id, old, sp)
 		inMemoryStudentProfiles[id] = sp
 		return nil
 	}
-	_, err := DB.Collection("student_profiles").UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": patch})
-	return err
+	if Mode() == ModePostgres {
+		return pgUpdate(ctx, PG, "student_profiles", reflect.TypeOf(StudentProfile{}), id, fields, p.IfVersion)
+	}
+	return uniqueErr("student_profiles", mongoUpdateVersioned(ctx, "student_profiles", id, bson.M{"$set": fields}, p.IfVersion))
 }
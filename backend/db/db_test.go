@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// withMemory gives the test an empty in-memory store
+func withMemory(t *testing.T) {
+	t.Helper()
+	setPrimary(ModeMemory, nil)
+	switchToInMemory()
+}
+
+func TestRestoreJobPosting(t *testing.T) {
+	withMemory(t)
+	ctx := context.Background()
+	if err := CreateJobPosting(ctx, JobPosting{ID: "j1", CompanyID: "c1", Title: "Engineer"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreJobPosting(ctx, "j9"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("restore of a missing job error = %v, want ErrNotFound", err)
+	}
+	var cerr *ConflictError
+	if err := RestoreJobPosting(ctx, "j1"); !errors.As(err, &cerr) || cerr.Field != "deleted_at" {
+		t.Fatalf("restore of a live job error = %v, want a deleted_at conflict", err)
+	}
+	if _, err := DeleteJobPosting(ctx, "j1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreJobPosting(ctx, "j1"); err != nil {
+		t.Fatalf("restore of a deleted job: %v", err)
+	}
+	if got, _ := GetJobPosting(ctx, "j1"); got.DeletedAt != "" {
+		t.Fatalf("job after the restore = %+v", got)
+	}
+}
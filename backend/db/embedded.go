@@ -0,0 +1,355 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Storage modes reported by Mode
+const (
+	ModeMongo    = "mongo"
+	ModeMemory   = "memory"
+	ModeBolt     = "bolt"
+	ModePostgres = "postgres"
+)
+
+var boltDB *bolt.DB
+
+// ErrSnapshotUnsupported is returned when snapshotting the Mongo backend
+var ErrSnapshotUnsupported = errors.New("snapshots are only available for the memory and bolt backends")
+
+// table binds an in-memory collection to its name for persistence
+type table struct {
+	name   string
+	each   func(fn func(id string, v interface{}) error) error
+	decode func(data []byte) (interface{}, error)
+	set    func(id string, v interface{})
+	reset  func()
+}
+
+func mapTable[T any](name string, m *map[string]T) table {
+	return table{
+		name: name,
+		each: func(fn func(string, interface{}) error) error {
+			for id, v := range *m {
+				if err := fn(id, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		decode: func(data []byte) (interface{}, error) {
+			var v T
+			err := bson.Unmarshal(data, &v)
+			return v, err
+		},
+		set:   func(id string, v interface{}) { (*m)[id] = v.(T) },
+		reset: func() { *m = make(map[string]T) },
+	}
+}
+
+// tables lists every collection kept in memory
+var tables = []table{
+	mapTable("profiles", &inMemoryProfiles),
+	mapTable("student_profiles", &inMemoryStudentProfiles),
+	mapTable("companies", &inMemoryCompanies),
+	mapTable("company_members", &inMemoryCompanyMembers),
+	mapTable("job_postings", &inMemoryJobPostings),
+	mapTable("resumes", &inMemoryResumes),
+	mapTable("applications", &inMemoryApplications),
+	mapTable("interviews", &inMemoryInterviews),
+	mapTable("notifications", &inMemoryNotifications),
+	mapTable("verification_requests", &inMemoryVerificationRequests),
+	{
+		name: "audit_log",
+		each: func(fn func(string, interface{}) error) error {
+			for _, e := range inMemoryAudit {
+				if err := fn(e.ID, e); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		decode: func(data []byte) (interface{}, error) {
+			var e AuditEntry
+			err := bson.Unmarshal(data, &e)
+			return e, err
+		},
+		set: func(id string, v interface{}) {
+			inMemoryAudit = append(inMemoryAudit, v.(AuditEntry))
+			inMemoryAuditIDs[id] = struct{}{}
+		},
+		reset: func() {
+			inMemoryAudit = make([]AuditEntry, 0)
+			inMemoryAuditIDs = make(map[string]struct{})
+		},
+	},
+	mapTable("outbox", &inMemoryOutbox),
+	mapTable("webhooks", &inMemoryWebhooks),
+	mapTable("webhook_deliveries", &inMemoryWebhookDeliveries),
+}
+
+func resetTablesLocked() {
+	for _, t := range tables {
+		t.reset()
+	}
+	memUnique = make(map[string]map[string]string)
+}
+
+// rebuildUniqueLocked recomputes the in-memory unique indexes after a load
+func rebuildUniqueLocked() {
+	memUnique = make(map[string]map[string]string)
+	for _, t := range tables {
+		_ = t.each(func(id string, v interface{}) error {
+			reindexLocked(t.name, id, nil, v)
+			return nil
+		})
+	}
+}
+
+// switchToBolt opens (or creates) the bbolt file at path and loads it into
+// memory. Callers fall back to the memory mode if it fails.
+func switchToBolt(path string) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	// a second process on the same file waits at most this long for the lock
+	bdb, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	resetTablesLocked()
+	if err := bdb.View(loadTx); err != nil {
+		bdb.Close()
+		return fmt.Errorf("load %s: %w", path, err)
+	}
+	rebuildUniqueLocked()
+	boltDB = bdb
+	setMode(ModeBolt)
+	slog.Info("using embedded bolt store", "path", path)
+	return nil
+}
+
+// loadTx reads every known bucket into the in-memory tables
+func loadTx(tx *bolt.Tx) error {
+	staged := map[string]map[string]interface{}{}
+	for _, t := range tables {
+		b := tx.Bucket([]byte(t.name))
+		if b == nil {
+			continue
+		}
+		rows := map[string]interface{}{}
+		err := b.ForEach(func(k, v []byte) error {
+			rec, err := t.decode(v)
+			if err != nil {
+				return fmt.Errorf("%s/%s: %w", t.name, k, err)
+			}
+			rows[string(k)] = rec
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		staged[t.name] = rows
+	}
+	// only replace state once every record decoded
+	resetTablesLocked()
+	for _, t := range tables {
+		for id, rec := range staged[t.name] {
+			t.set(id, rec)
+		}
+	}
+	return nil
+}
+
+// dumpTx replaces the contents of every known bucket with the in-memory tables
+func dumpTx(tx *bolt.Tx) error {
+	for _, t := range tables {
+		if tx.Bucket([]byte(t.name)) != nil {
+			if err := tx.DeleteBucket([]byte(t.name)); err != nil {
+				return err
+			}
+		}
+		b, err := tx.CreateBucket([]byte(t.name))
+		if err != nil {
+			return err
+		}
+		err = t.each(func(id string, v interface{}) error {
+			data, err := bson.Marshal(v)
+			if err != nil {
+				return err
+			}
+			return b.Put([]byte(id), data)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// persistLocked writes one record through to the bolt file. It is a no-op in
+// the memory mode. Callers hold mu and persist before mutating the map so a
+// failed write leaves memory unchanged; that includes ErrUnavailable from a
+// read-only fallback.
+func persistLocked(collection, id string, v interface{}) error {
+	if err := Writable(); err != nil {
+		return err
+	}
+	fallbackWrites.Add(1)
+	if boltDB == nil {
+		return nil
+	}
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return boltDB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(collection))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), data)
+	})
+}
+
+// unpersistLocked removes one record from the bolt file
+func unpersistLocked(collection, id string) error {
+	if err := Writable(); err != nil {
+		return err
+	}
+	fallbackWrites.Add(1)
+	if boltDB == nil {
+		return nil
+	}
+	return boltDB.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(collection)); b != nil {
+			return b.Delete([]byte(id))
+		}
+		return nil
+	})
+}
+
+// Snapshot writes a consistent bbolt image of all data to w. In the memory
+// mode the image is built from the maps, so it can seed a bolt deployment.
+func Snapshot(w io.Writer) error {
+	if !UseInMemory() {
+		return ErrSnapshotUnsupported
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	if boltDB != nil {
+		return boltDB.View(func(tx *bolt.Tx) error {
+			_, err := tx.WriteTo(w)
+			return err
+		})
+	}
+	return withTempFile(func(path string) error {
+		img, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+		if err != nil {
+			return err
+		}
+		if err := img.Update(dumpTx); err != nil {
+			img.Close()
+			return err
+		}
+		if err := img.Close(); err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+}
+
+// Restore replaces all data with a snapshot produced by Snapshot. Every
+// record is decoded before anything is replaced.
+func Restore(r io.Reader) error {
+	if !UseInMemory() {
+		return ErrSnapshotUnsupported
+	}
+	return withTempFile(func(path string) error {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, r); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		img, err := bolt.Open(path, 0o600, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+		if err != nil {
+			return &ValidationError{Fields: []FieldError{{Field: "snapshot", Message: "not a valid snapshot file"}}}
+		}
+		defer img.Close()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err := img.View(loadTx); err != nil {
+			return err
+		}
+		rebuildUniqueLocked()
+		if boltDB != nil {
+			return boltDB.Update(dumpTx)
+		}
+		return nil
+	})
+}
+
+// withTempFile runs fn with the path of a scratch file that is removed afterwards
+func withTempFile(fn func(path string) error) error {
+	f, err := os.CreateTemp("", "snapshot-*.db")
+	if err != nil {
+		return err
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+	return fn(path)
+}
+
+// Close disconnects from Mongo, releases the Postgres pool and flushes and
+// closes the embedded store, whichever is open
+func Close() error {
+	if DB != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), OpTimeout)
+		defer cancel()
+		err := DB.Client().Disconnect(ctx)
+		DB = nil
+		if err != nil {
+			return err
+		}
+	}
+	if PG != nil {
+		PG.Close()
+		PG = nil
+	}
+	if boltDB == nil {
+		return nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	err := boltDB.Close()
+	boltDB = nil
+	return err
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Domain errors returned by the storage layer. Callers test them with
+// errors.Is; the HTTP layer maps each to a status code.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	ErrForbidden  = errors.New("forbidden")
+)
+
+// ConflictError reports a write rejected by a uniqueness or state rule,
+// naming the field responsible
+type ConflictError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *ConflictError) Error() string { return e.Message }
+
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+func (e *ValidationError) Is(target error) bool { return target == ErrValidation }
+
+// PartialWriteError reports a unit of work that failed part way on a Mongo
+// deployment without transactions. Written lists the records stored before
+// the failure as collection/id; they were not rolled back.
+type PartialWriteError struct {
+	Written []string
+	Err     error
+}
+
+func (e *PartialWriteError) Error() string {
+	return fmt.Sprintf("partially written (%s): %v", strings.Join(e.Written, ", "), e.Err)
+}
+
+func (e *PartialWriteError) Unwrap() error { return e.Err }
+
+// notFound converts the driver's "no documents" error into ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Joined read models. Both produce the same document shape in every storage
+// mode: the stored fields (with "_id") plus the joined records under the
+// collection name, exactly as the Mongo $lookup stages return them. Postgres
+// builds them with SQL joins (see postgres.go).
+
+// toDoc converts a stored struct into its document form
+func toDoc(v interface{}) bson.M {
+	out := bson.M{}
+	data, err := bson.Marshal(v)
+	if err == nil {
+		_ = bson.Unmarshal(data, &out)
+	}
+	return out
+}
+
+func sortByCreatedDesc(docs []bson.M) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		a, _ := docs[i]["created_at"].(string)
+		b, _ := docs[j]["created_at"].(string)
+		return a > b
+	})
+}
+
+// JobPostingsWithCompany lists job postings (same filters as GetJobPostings)
+// joined with their company under "companies", their applications and
+// applications_count, newest first.
+func JobPostingsWithCompany(ctx context.Context, filter map[string]interface{}) ([]bson.M, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		jobs, err := GetJobPostings(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		mu.RLock()
+		defer mu.RUnlock()
+		byJob := map[string]bson.A{}
+		for _, a := range inMemoryApplications {
+			byJob[a.JobID] = append(byJob[a.JobID], toDoc(a))
+		}
+		out := make([]bson.M, 0, len(jobs))
+		for _, j := range jobs {
+			doc := toDoc(j)
+			if co, ok := inMemoryCompanies[j.CompanyID]; ok {
+				doc["companies"] = toDoc(co)
+			}
+			apps := byJob[j.ID]
+			if apps == nil {
+				apps = bson.A{}
+			}
+			doc["applications"] = apps
+			doc["applications_count"] = len(apps)
+			out = append(out, doc)
+		}
+		sortByCreatedDesc(out)
+		return out, nil
+	}
+
+	if Mode() == ModePostgres {
+		return pgJobPostingsWithCompany(ctx, filter)
+	}
+	includeDeleted, _ := filter["include_deleted"].(bool)
+	match := bson.M{}
+	for k, v := range filter {
+		if k != "include_deleted" {
+			match[k] = v
+		}
+	}
+	if !includeDeleted {
+		match["deleted_at"] = bson.M{"$exists": false}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "companies"}, {Key: "localField", Value: "company_id"}, {Key: "foreignField", Value: "_id"}, {Key: "as", Value: "companies"}}}},
+		{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$companies"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}},
+		// lookup applications to compute count
+		{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "applications"}, {Key: "localField", Value: "_id"}, {Key: "foreignField", Value: "job_id"}, {Key: "as", Value: "applications"}}}},
+		{{Key: "$addFields", Value: bson.D{{Key: "applications_count", Value: bson.D{{Key: "$size", Value: "$applications"}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
+	}
+	cur, err := DB.Collection("job_postings").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]bson.M, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CompanyApplications lists applications to a company's jobs joined with the
+// job under "job_postings" and the student profile under "student_profiles",
+// which in turn carries the user's profile under "profiles". Newest first.
+func CompanyApplications(ctx context.Context, companyID string) ([]bson.M, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		out := make([]bson.M, 0)
+		for _, a := range inMemoryApplications {
+			job, ok := inMemoryJobPostings[a.JobID]
+			if !ok || job.CompanyID != companyID {
+				continue
+			}
+			doc := toDoc(a)
+			doc["job_postings"] = toDoc(job)
+			if sp, ok := inMemoryStudentProfiles[a.StudentID]; ok {
+				spDoc := toDoc(sp)
+				if p, ok := inMemoryProfiles[sp.UserID]; ok {
+					spDoc["profiles"] = toDoc(p)
+				}
+				doc["student_profiles"] = spDoc
+			}
+			out = append(out, doc)
+		}
+		sortByCreatedDesc(out)
+		return out, nil
+	}
+
+	if Mode() == ModePostgres {
+		return pgCompanyApplications(ctx, companyID)
+	}
+	pipeline := []interface{}{
+		// lookup job_postings
+		bson.M{"$lookup": bson.M{
+			"from":         "job_postings",
+			"localField":   "job_id",
+			"foreignField": "_id",
+			"as":           "job_postings",
+		}},
+		bson.M{"$unwind": bson.M{"path": "$job_postings", "preserveNullAndEmptyArrays": true}},
+		bson.M{"$match": bson.M{"job_postings.company_id": companyID}},
+		// lookup student_profiles
+		bson.M{"$lookup": bson.M{
+			"from":         "student_profiles",
+			"localField":   "student_id",
+			"foreignField": "_id",
+			"as":           "student_profiles",
+		}},
+		bson.M{"$unwind": bson.M{"path": "$student_profiles", "preserveNullAndEmptyArrays": true}},
+		// lookup profiles for student_profiles.user_id
+		bson.M{"$lookup": bson.M{
+			"from":         "profiles",
+			"localField":   "student_profiles.user_id",
+			"foreignField": "_id",
+			"as":           "student_profiles.profiles",
+		}},
+		bson.M{"$unwind": bson.M{"path": "$student_profiles.profiles", "preserveNullAndEmptyArrays": true}},
+		bson.M{"$sort": bson.M{"created_at": -1}},
+	}
+	cur, err := DB.Collection("applications").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]bson.M, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
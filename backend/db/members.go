@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var inMemoryCompanyMembers map[string]CompanyMember
+
+// Company team roles
+const (
+	MemberOwner       = "owner"
+	MemberRecruiter   = "recruiter"
+	MemberInterviewer = "interviewer"
+)
+
+// CompanyMember links a profile to a company with a team role. A company's
+// legacy recruiter_id is treated as an implicit owner until backfilled.
+type CompanyMember struct {
+	ID        string `bson:"_id,omitempty" json:"id"`
+	CompanyID string `bson:"company_id" json:"company_id"`
+	UserID    string `bson:"user_id" json:"user_id"`
+	Role      string `bson:"role" json:"role"`
+	InvitedBy string `bson:"invited_by,omitempty" json:"invited_by"`
+	CreatedAt string `bson:"created_at,omitempty" json:"created_at"`
+}
+
+// ValidMemberRole reports whether r is one of the company team roles
+func ValidMemberRole(r string) bool {
+	return r == MemberOwner || r == MemberRecruiter || r == MemberInterviewer
+}
+
+// AddCompanyMember inserts a membership. A user can hold only one role per company.
+func AddCompanyMember(ctx context.Context, m CompanyMember) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := inMemoryCompanyMembers[m.ID]; ok {
+			return &ConflictError{Field: "id", Message: "duplicate company_members record"}
+		}
+		if err := checkUniqueLocked("company_members", m.ID, m); err != nil {
+			return err
+		}
+		if err := persistLocked("company_members", m.ID, m); err != nil {
+			return err
+		}
+		reindexLocked("company_members", m.ID, nil, m)
+		inMemoryCompanyMembers[m.ID] = m
+		return nil
+	}
+	if Mode() == ModePostgres {
+		return pgInsert(ctx, PG, "company_members", m)
+	}
+	_, err := DB.Collection("company_members").InsertOne(ctx, m)
+	return uniqueErr("company_members", err)
+}
+
+// GetCompanyMembers lists memberships. Supports company_id and user_id filters.
+func GetCompanyMembers(ctx context.Context, filter map[string]interface{}) ([]CompanyMember, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		return memberRowsLocked(filter), nil
+	}
+	if Mode() == ModePostgres {
+		return pgList[CompanyMember](ctx, "company_members", filter, "t.created_at")
+	}
+	cur, err := DB.Collection("company_members").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]CompanyMember, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func memberRowsLocked(filter map[string]interface{}) []CompanyMember {
+	cid, _ := filter["company_id"].(string)
+	uid, _ := filter["user_id"].(string)
+	out := make([]CompanyMember, 0)
+	for _, m := range inMemoryCompanyMembers {
+		if cid != "" && m.CompanyID != cid {
+			continue
+		}
+		if uid != "" && m.UserID != uid {
+			continue
+		}
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt < out[j].CreatedAt })
+	return out
+}
+
+// GetCompanyMemberRole returns the caller's role in a company, falling back to
+// owner for the company's legacy recruiter_id. An empty role means no access.
+func GetCompanyMemberRole(ctx context.Context, companyID, userID string) (string, error) {
+	if userID == "" {
+		return "", nil
+	}
+	rows, err := GetCompanyMembers(ctx, map[string]interface{}{"company_id": companyID, "user_id": userID})
+	if err != nil {
+		return "", err
+	}
+	if len(rows) > 0 {
+		return rows[0].Role, nil
+	}
+	co, err := GetCompany(ctx, companyID)
+	if err != nil {
+		return "", err
+	}
+	if co.RecruiterID == userID {
+		return MemberOwner, nil
+	}
+	return "", nil
+}
+
+// RemoveCompanyMember deletes a user's membership in a company
+func RemoveCompanyMember(ctx context.Context, companyID, userID string) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		for id, m := range inMemoryCompanyMembers {
+			if m.CompanyID == companyID && m.UserID == userID {
+				if err := unpersistLocked("company_members", id); err != nil {
+					return err
+				}
+				reindexLocked("company_members", id, m, nil)
+				delete(inMemoryCompanyMembers, id)
+				return nil
+			}
+		}
+		return ErrNotFound
+	}
+	if Mode() == ModePostgres {
+		return pgRemoveCompanyMember(ctx, companyID, userID)
+	}
+	res, err := DB.Collection("company_members").DeleteOne(ctx, bson.M{"company_id": companyID, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// memberCompanyIDs returns the ids of companies a user belongs to
+func memberCompanyIDs(ctx context.Context, userID string) ([]string, error) {
+	rows, err := GetCompanyMembers(ctx, map[string]interface{}{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(rows))
+	for _, m := range rows {
+		ids = append(ids, m.CompanyID)
+	}
+	return ids, nil
+}
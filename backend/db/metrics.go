@@ -0,0 +1,27 @@
+package db
+
+import (
+	"backend/telemetry"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+var (
+	opDuration = telemetry.NewHistogram("db_operation_duration_seconds",
+		"Storage call latency by operation and backend.", telemetry.DurationBuckets, "op", "backend")
+	mongoDuration = telemetry.NewHistogram("mongo_command_duration_seconds",
+		"MongoDB command latency by command and outcome.", telemetry.DurationBuckets, "command", "outcome")
+)
+
+// commandMonitor times every command the Mongo driver sends. A storage call
+// can issue several (a find and its getMores, an insert and an index check).
+var commandMonitor = &event.CommandMonitor{
+	Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+		mongoDuration.Observe(time.Duration(e.DurationNanos).Seconds(), e.CommandName, "ok")
+	},
+	Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+		mongoDuration.Observe(time.Duration(e.DurationNanos).Seconds(), e.CommandName, "error")
+	},
+}
@@ -0,0 +1,287 @@
+package db
+
+import (
+	"backend/telemetry"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Fallback policies (config DB_FALLBACK) for when the configured
+// backend is unreachable
+const (
+	// FallbackMemory serves from an empty in-memory store until the primary
+	// comes back; anything written meanwhile is discarded on reconnect
+	FallbackMemory = "memory"
+	// FallbackReadOnly also serves from memory but refuses writes, so
+	// clients never see data that will later vanish. Writes are refused as
+	// well while a connected primary fails its health checks.
+	FallbackReadOnly = "readonly"
+	// FallbackNone refuses to start without the primary
+	FallbackNone = "none"
+)
+
+// Health states reported by CurrentStatus
+const (
+	StateOK       = "ok"
+	StateDegraded = "degraded"
+)
+
+// Health check cadence. A healthy primary is pinged every healthInterval;
+// after a failure the delay starts at minBackoff and doubles up to maxBackoff.
+var (
+	healthInterval = 15 * time.Second
+	minBackoff     = time.Second
+	maxBackoff     = time.Minute
+	checkTimeout   = 5 * time.Second
+)
+
+// ErrUnavailable is returned for writes while the primary is down and the
+// fallback refuses them
+var ErrUnavailable = errors.New("database unavailable")
+
+// mode is the active storage backend. The bolt mode serves reads from the
+// same maps as the memory mode and writes every change through to an
+// embedded bbolt file, one bucket per collection keyed by id.
+var mode atomic.Value
+
+// inMemory is set whenever mode is memory or bolt
+var inMemory atomic.Bool
+
+// fallbackWrites counts in-memory writes made while standing in for the primary
+var fallbackWrites atomic.Int64
+
+var fallback = FallbackMemory
+
+func init() { mode.Store(ModeMongo) }
+
+// Mode returns the active storage backend
+func Mode() string { return mode.Load().(string) }
+
+// UseInMemory reports whether data is served from the in-memory maps, either
+// as the memory or bolt mode or as the fallback for an unreachable primary
+func UseInMemory() bool { return inMemory.Load() }
+
+// setMode switches the active backend. The backend's handle (DB, PG or
+// boltDB) must be set before calling it.
+func setMode(m string) {
+	mode.Store(m)
+	inMemory.Store(m == ModeMemory || m == ModeBolt)
+}
+
+// Status describes the storage backend for health reporting
+type Status struct {
+	State    string `json:"state"`
+	Mode     string `json:"mode"`
+	Primary  string `json:"primary"`
+	Fallback string `json:"fallback"`
+	// Writable is false while writes are refused with ErrUnavailable
+	Writable bool `json:"writable"`
+	// FallbackActive is set while the in-memory store stands in for the primary
+	FallbackActive      bool       `json:"fallback_active"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastCheck           *time.Time `json:"last_check,omitempty"`
+	NextCheck           *time.Time `json:"next_check,omitempty"`
+	DownSince           *time.Time `json:"down_since,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Reconnects          int        `json:"reconnects"`
+}
+
+// health is the monitor's view of the primary, guarded by healthMu
+var (
+	healthMu  sync.Mutex
+	primary   = ModeMongo
+	reconnect func(ctx context.Context) error
+	health    = Status{State: StateOK}
+)
+
+// setPrimary records the configured backend and how to (re)connect to it
+func setPrimary(m string, connect func(ctx context.Context) error) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	primary = m
+	reconnect = connect
+}
+
+// connectPrimary opens the configured backend. When that fails the fallback
+// policy decides: exit, or serve from memory and keep retrying.
+func connectPrimary(m string, connect func(ctx context.Context) error) {
+	setPrimary(m, connect)
+	err := connect(context.Background())
+	if err == nil {
+		return
+	}
+	if fallback == FallbackNone {
+		telemetry.Fatal("database unavailable and DB_FALLBACK=none", "primary", m, "error", err)
+	}
+	slog.Warn("database unavailable, switching to in-memory DB", "primary", m, "fallback", fallback, "error", err)
+	switchToInMemory()
+	fallbackWrites.Store(0)
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	health.FallbackActive = true
+	markDownLocked(err, time.Now())
+}
+
+// markDownLocked records a failed check and returns the delay before the next
+func markDownLocked(err error, now time.Time) time.Duration {
+	health.State = StateDegraded
+	health.LastError = err.Error()
+	health.LastCheck = &now
+	if health.DownSince == nil {
+		health.DownSince = &now
+	}
+	health.ConsecutiveFailures++
+	delay := minBackoff
+	for i := 1; i < health.ConsecutiveFailures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	next := now.Add(delay)
+	health.NextCheck = &next
+	return delay
+}
+
+// markUpLocked records a successful check
+func markUpLocked(now time.Time) time.Duration {
+	if health.State == StateDegraded {
+		slog.Info("database reachable again", "primary", primary, "down_for", now.Sub(*health.DownSince).Round(time.Second).String())
+	}
+	health.State = StateOK
+	health.LastError = ""
+	health.LastCheck = &now
+	health.DownSince = nil
+	health.ConsecutiveFailures = 0
+	next := now.Add(healthInterval)
+	health.NextCheck = &next
+	return healthInterval
+}
+
+// CurrentStatus returns a copy of the storage health
+func CurrentStatus() Status {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	s := health
+	s.Mode = Mode()
+	s.Primary = primary
+	s.Fallback = fallback
+	s.Writable = writableLocked()
+	return s
+}
+
+// Writable returns ErrUnavailable while writes are refused: the primary is
+// down and DB_FALLBACK is readonly
+func Writable() error {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	if !writableLocked() {
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// writableLocked is false with DB_FALLBACK=readonly while the fallback
+// stands in and while health checks of a connected primary fail; the next
+// successful check clears it
+func writableLocked() bool {
+	if fallback != FallbackReadOnly {
+		return true
+	}
+	return !health.FallbackActive && health.State != StateDegraded
+}
+
+// StartMonitor checks the primary in the background until ctx is cancelled:
+// a ping while connected, a reconnect attempt while the in-memory fallback
+// stands in. Failures are retried with exponential backoff. The returned
+// function blocks until the monitor has stopped.
+func StartMonitor(ctx context.Context) (wait func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		delay := healthInterval
+		if CurrentStatus().State == StateDegraded {
+			delay = minBackoff
+		}
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+			timer.Reset(checkPrimary(ctx))
+		}
+	}()
+	return func() { <-done }
+}
+
+// checkPrimary runs one health check and returns the delay before the next
+func checkPrimary(ctx context.Context) time.Duration {
+	healthMu.Lock()
+	fellBack := health.FallbackActive
+	connect := reconnect
+	healthMu.Unlock()
+
+	var err error
+	if fellBack {
+		err = connect(ctx)
+		if ctx.Err() != nil {
+			return 0
+		}
+		if err == nil {
+			if n := fallbackWrites.Load(); n > 0 {
+				slog.Warn("discarded in-memory writes made while the database was down", "primary", primary, "writes", n)
+			}
+		}
+	} else {
+		err = ping(ctx)
+	}
+
+	now := time.Now()
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	if err != nil {
+		if health.ConsecutiveFailures == 0 {
+			slog.Warn("database health check failed", "primary", primary, "error", err)
+		}
+		return markDownLocked(err, now)
+	}
+	if fellBack {
+		health.FallbackActive = false
+		health.Reconnects++
+	}
+	return markUpLocked(now)
+}
+
+// Ping checks that the configured backend answers. It fails while the
+// in-memory fallback stands in for an unreachable primary.
+func Ping(ctx context.Context) error {
+	healthMu.Lock()
+	fellBack, p := health.FallbackActive, primary
+	healthMu.Unlock()
+	if fellBack {
+		return fmt.Errorf("%s unreachable, serving from the in-memory fallback", p)
+	}
+	return ping(ctx)
+}
+
+// ping checks the connection to the active backend. The embedded modes
+// have nothing to reach.
+func ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	switch Mode() {
+	case ModeMongo:
+		return DB.Client().Ping(ctx, nil)
+	case ModePostgres:
+		return PG.Ping(ctx)
+	}
+	return nil
+}
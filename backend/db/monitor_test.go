@@ -0,0 +1,49 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// With DB_FALLBACK=readonly a primary that fails its health checks refuses
+// writes until a check succeeds, even before the fallback takes over
+func TestReadOnlyWhilePrimaryUnhealthy(t *testing.T) {
+	healthMu.Lock()
+	prevHealth, prevFallback := health, fallback
+	health, fallback = Status{State: StateOK}, FallbackReadOnly
+	healthMu.Unlock()
+	t.Cleanup(func() {
+		healthMu.Lock()
+		health, fallback = prevHealth, prevFallback
+		healthMu.Unlock()
+	})
+
+	if err := Writable(); err != nil {
+		t.Fatalf("healthy primary: Writable() = %v", err)
+	}
+	healthMu.Lock()
+	markDownLocked(errors.New("connection refused"), time.Now())
+	healthMu.Unlock()
+	if err := Writable(); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("unhealthy primary: Writable() = %v, want ErrUnavailable", err)
+	}
+	if CurrentStatus().Writable {
+		t.Error("status reports an unhealthy primary as writable")
+	}
+	healthMu.Lock()
+	markUpLocked(time.Now())
+	healthMu.Unlock()
+	if err := Writable(); err != nil {
+		t.Fatalf("after reconnecting: Writable() = %v", err)
+	}
+
+	// the memory fallback keeps accepting writes
+	healthMu.Lock()
+	fallback = FallbackMemory
+	markDownLocked(errors.New("connection refused"), time.Now())
+	healthMu.Unlock()
+	if err := Writable(); err != nil {
+		t.Fatalf("memory fallback: Writable() = %v", err)
+	}
+}
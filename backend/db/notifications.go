@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var inMemoryNotifications map[string]Notification
+
+// Notification mirrors the notifications table: a message addressed to one user
+type Notification struct {
+	ID        string `bson:"_id,omitempty" json:"id"`
+	UserID    string `bson:"user_id" json:"user_id"`
+	Title     string `bson:"title" json:"title"`
+	Message   string `bson:"message" json:"message"`
+	Type      string `bson:"type,omitempty" json:"type"`
+	Read      bool   `bson:"read" json:"read"`
+	CreatedAt string `bson:"created_at,omitempty" json:"created_at"`
+}
+
+func CreateNotification(ctx context.Context, n Notification) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := inMemoryNotifications[n.ID]; ok {
+			return &ConflictError{Field: "id", Message: "duplicate notifications record"}
+		}
+		if err := persistLocked("notifications", n.ID, n); err != nil {
+			return err
+		}
+		inMemoryNotifications[n.ID] = n
+		return nil
+	}
+	if Mode() == ModePostgres {
+		return pgInsert(ctx, PG, "notifications", n)
+	}
+	_, err := DB.Collection("notifications").InsertOne(ctx, n)
+	return uniqueErr("notifications", err)
+}
+
+// GetNotifications lists notifications, newest first. Supports a user_id filter.
+func GetNotifications(ctx context.Context, filter map[string]interface{}) ([]Notification, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		out := make([]Notification, 0)
+		uid, _ := filter["user_id"].(string)
+		for _, n := range inMemoryNotifications {
+			if uid == "" || n.UserID == uid {
+				out = append(out, n)
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt > out[j].CreatedAt })
+		return out, nil
+	}
+	if Mode() == ModePostgres {
+		return pgList[Notification](ctx, "notifications", filter, "t.created_at DESC")
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cur, err := DB.Collection("notifications").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Notification, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,256 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The outbox holds domain events. An operation that publishes events stores
+// them in the same unit of work as the change they describe, so an event
+// exists exactly when its change does; the events package then delivers
+// them to their subscribers, retrying until each has accepted the event.
+
+var inMemoryOutbox map[string]Event
+
+// Domain event types
+const (
+	EventApplicationStatusChanged = "application.status_changed"
+	EventInterviewScheduled       = "interview.scheduled"
+	EventJobPublished             = "job.published"
+	EventCompanyVerified          = "company.verified"
+)
+
+// Outbox delivery states
+const (
+	EventPending    = "pending"
+	EventDispatched = "dispatched"
+	EventFailed     = "failed"
+)
+
+// Event is a domain event with its delivery state. Delivered names the
+// subscribers that have accepted it, so a retry only goes to the others.
+type Event struct {
+	ID            string                 `bson:"_id,omitempty" json:"id"`
+	Type          string                 `bson:"type" json:"type"`
+	Entity        string                 `bson:"entity" json:"entity"`
+	EntityID      string                 `bson:"entity_id" json:"entity_id"`
+	CompanyID     string                 `bson:"company_id,omitempty" json:"company_id"`
+	Data          map[string]interface{} `bson:"data" json:"data"`
+	CreatedAt     string                 `bson:"created_at" json:"created_at"`
+	Status        string                 `bson:"status" json:"status"`
+	Delivered     []string               `bson:"delivered,omitempty" json:"delivered"`
+	Attempts      int                    `bson:"attempts" json:"attempts"`
+	NextAttemptAt string                 `bson:"next_attempt_at,omitempty" json:"next_attempt_at"`
+	LastError     string                 `bson:"last_error,omitempty" json:"last_error"`
+	DispatchedAt  string                 `bson:"dispatched_at,omitempty" json:"dispatched_at"`
+}
+
+// NewEvent builds a pending event about entity/entityID, due for delivery
+// at once
+func NewEvent(eventType, entity, entityID, companyID string, data map[string]interface{}) Event {
+	now := time.Now().UTC().Format(time.RFC3339)
+	return Event{
+		ID:            uuid.New().String(),
+		Type:          eventType,
+		Entity:        entity,
+		EntityID:      entityID,
+		CompanyID:     companyID,
+		Data:          data,
+		CreatedAt:     now,
+		Status:        EventPending,
+		NextAttemptAt: now,
+	}
+}
+
+// ApplicationStatusEvent describes application a having moved from status
+// from to its current one
+func ApplicationStatusEvent(a Application, companyID, from string) Event {
+	return NewEvent(EventApplicationStatusChanged, "application", a.ID, companyID, map[string]interface{}{
+		"application_id": a.ID,
+		"job_id":         a.JobID,
+		"student_id":     a.StudentID,
+		"from":           from,
+		"to":             a.Status,
+	})
+}
+
+// InterviewScheduledEvent describes the interview record rec of an
+// application of companyID
+func InterviewScheduledEvent(rec map[string]interface{}, companyID string) Event {
+	id, _ := rec["id"].(string)
+	data := map[string]interface{}{"interview_id": id}
+	for _, k := range []string{"application_id", "scheduled_at", "location", "mode"} {
+		data[k] = rec[k]
+	}
+	return NewEvent(EventInterviewScheduled, "interview", id, companyID, data)
+}
+
+// JobPublishedEvent describes job j going live at publishedAt
+func JobPublishedEvent(j JobPosting, publishedAt string) Event {
+	return NewEvent(EventJobPublished, "job_posting", j.ID, j.CompanyID, map[string]interface{}{
+		"job_id":       j.ID,
+		"title":        j.Title,
+		"published_at": publishedAt,
+	})
+}
+
+// CompanyVerifiedEvent describes company co having been verified
+func CompanyVerifiedEvent(co Company) Event {
+	return NewEvent(EventCompanyVerified, "company", co.ID, co.ID, map[string]interface{}{
+		"company_id": co.ID,
+		"name":       co.Name,
+	})
+}
+
+// publish stages events in the batch
+func (b *memBatch) publish(events []Event) {
+	for _, e := range events {
+		b.put("outbox", e.ID, e, func() { inMemoryOutbox[e.ID] = e })
+	}
+}
+
+// publish stores events as the unit's last write
+func (u *mongoUnit) publish(events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(events))
+	records := make([]string, 0, len(events))
+	for _, e := range events {
+		docs = append(docs, e)
+		records = append(records, "outbox/"+e.ID)
+	}
+	return u.write(func(ctx context.Context) error {
+		_, err := DB.Collection("outbox").InsertMany(ctx, docs)
+		return err
+	}, records...)
+}
+
+// mongoUpdatePublishing is mongoUpdateVersioned storing events in the same
+// unit of work
+func mongoUpdatePublishing(ctx context.Context, coll, id string, update bson.M, want *int64, events []Event) error {
+	return mongoTx(ctx, func(u *mongoUnit) error {
+		err := u.write(func(ctx context.Context) error {
+			return mongoUpdateVersioned(ctx, coll, id, update, want)
+		}, coll+"/"+id)
+		if err != nil {
+			return err
+		}
+		return u.publish(events)
+	})
+}
+
+// DueEvents lists up to limit pending events whose next attempt is due at
+// the RFC3339 timestamp now, oldest first
+func DueEvents(ctx context.Context, now string, limit int) ([]Event, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		out := make([]Event, 0)
+		for _, e := range inMemoryOutbox {
+			if e.Status == EventPending && e.NextAttemptAt <= now {
+				out = append(out, e)
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt < out[j].CreatedAt })
+		if limit > 0 && len(out) > limit {
+			out = out[:limit]
+		}
+		return out, nil
+	}
+	if Mode() == ModePostgres {
+		return pgSelect[Event](ctx, PG, "outbox",
+			"SELECT %s FROM outbox t WHERE t.status = $1 AND t.next_attempt_at <= $2 ORDER BY t.created_at LIMIT $3",
+			EventPending, now, limit)
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	cur, err := DB.Collection("outbox").Find(ctx,
+		bson.M{"status": EventPending, "next_attempt_at": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Event, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SaveEventDelivery stores the delivery state of e after an attempt
+func SaveEventDelivery(ctx context.Context, e Event) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := inMemoryOutbox[e.ID]; !ok {
+			return ErrNotFound
+		}
+		if err := persistLocked("outbox", e.ID, e); err != nil {
+			return err
+		}
+		inMemoryOutbox[e.ID] = e
+		return nil
+	}
+	if Mode() == ModePostgres {
+		return pgSaveEventDelivery(ctx, e)
+	}
+	res, err := DB.Collection("outbox").UpdateOne(ctx, bson.M{"_id": e.ID}, bson.M{"$set": bson.M{
+		"status":          e.Status,
+		"delivered":       e.Delivered,
+		"attempts":        e.Attempts,
+		"next_attempt_at": e.NextAttemptAt,
+		"last_error":      e.LastError,
+		"dispatched_at":   e.DispatchedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// PurgeEvents removes dispatched events created before the given RFC3339
+// timestamp and reports how many were removed. Failed events are kept for
+// inspection.
+func PurgeEvents(ctx context.Context, before string) (int64, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		var removed int64
+		for id, e := range inMemoryOutbox {
+			if e.Status != EventDispatched || e.CreatedAt >= before {
+				continue
+			}
+			if err := unpersistLocked("outbox", id); err != nil {
+				return removed, err
+			}
+			delete(inMemoryOutbox, id)
+			removed++
+		}
+		return removed, nil
+	}
+	if Mode() == ModePostgres {
+		return pgPurgeEvents(ctx, before)
+	}
+	res, err := DB.Collection("outbox").DeleteMany(ctx,
+		bson.M{"status": EventDispatched, "created_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,441 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Typed patch payloads. Every Update* function takes one of these instead of
+// a raw map so the same field whitelist, coercion and validation applies to
+// Mongo and in-memory storage. Fields are pointers: nil means "leave as is".
+// The json tag is also the stored field name. IfVersion is not a field: it
+// makes the update conditional on the record's version (see version.go).
+
+// FieldError describes one invalid field in a request payload
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects every field problem found in a payload
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (e *ValidationError) orNil() error {
+	if e == nil || len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// FlexInt accepts JSON numbers (including 2025.0 sent by JS clients) and
+// numeric strings, rejecting fractional values.
+type FlexInt int
+
+func (n *FlexInt) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var f float64
+	switch t := v.(type) {
+	case float64:
+		f = t
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		f = parsed
+	default:
+		return fmt.Errorf("must be an integer")
+	}
+	if f != float64(int64(f)) {
+		return fmt.Errorf("must be an integer")
+	}
+	*n = FlexInt(f)
+	return nil
+}
+
+// FlexFloat accepts JSON numbers and numeric strings (HTML form values)
+type FlexFloat float64
+
+func (n *FlexFloat) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case float64:
+		*n = FlexFloat(t)
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		*n = FlexFloat(parsed)
+	default:
+		return fmt.Errorf("must be a number")
+	}
+	return nil
+}
+
+// alwaysIgnored are identity and bookkeeping keys clients commonly echo back;
+// they are dropped from every patch rather than rejected.
+var alwaysIgnored = map[string]bool{"id": true, "_id": true, "created_at": true, "updated_at": true}
+
+// IgnoredPatchKeys lists the keys DecodePatch drops from every patch
+func IgnoredPatchKeys() []string {
+	out := make([]string, 0, len(alwaysIgnored))
+	for k := range alwaysIgnored {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// DecodePatch decodes a JSON object into a patch struct. Keys in
+// alwaysIgnored or ignore, and fields tagged `patch:"server"`, are dropped;
+// keys that match no field are reported as unknown. Every problem is
+// collected into a single *ValidationError.
+func DecodePatch(body []byte, dst interface{}, ignore ...string) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return &ValidationError{Fields: []FieldError{{Field: "body", Message: "must be a JSON object"}}}
+	}
+	skip := map[string]bool{}
+	for _, k := range ignore {
+		skip[k] = true
+	}
+	rv := reflect.ValueOf(dst).Elem()
+	rt := rv.Type()
+	fields := map[string]int{}
+	for i := 0; i < rt.NumField(); i++ {
+		name := jsonName(rt.Field(i))
+		if name == "" || rt.Field(i).Tag.Get("patch") == "server" {
+			continue
+		}
+		fields[name] = i
+	}
+
+	verr := &ValidationError{}
+	for key, val := range raw {
+		if alwaysIgnored[key] || skip[key] {
+			continue
+		}
+		idx, ok := fields[key]
+		if !ok {
+			verr.add(key, "unknown or read-only field")
+			continue
+		}
+		if string(val) == "null" {
+			continue
+		}
+		f := rv.Field(idx)
+		ptr := reflect.New(f.Type().Elem())
+		if err := json.Unmarshal(val, ptr.Interface()); err != nil {
+			verr.add(key, "%s", typeMessage(err, f.Type().Elem()))
+			continue
+		}
+		f.Set(ptr)
+	}
+	sort.Slice(verr.Fields, func(i, j int) bool { return verr.Fields[i].Field < verr.Fields[j].Field })
+	return verr.orNil()
+}
+
+func jsonName(f reflect.StructField) string {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+func typeMessage(err error, t reflect.Type) string {
+	if ute, ok := err.(*json.UnmarshalTypeError); ok {
+		return "must be of type " + friendlyType(ute.Type)
+	}
+	if t == reflect.TypeOf(FlexInt(0)) || t == reflect.TypeOf(FlexFloat(0)) {
+		return err.Error()
+	}
+	return "invalid value"
+}
+
+func friendlyType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		return "array"
+	case reflect.Map:
+		return "object"
+	}
+	return t.String()
+}
+
+// patchFields flattens the non-nil fields of a patch into a field -> value map
+// with Flex types unwrapped. It is the $set document in Mongo mode and the
+// input to applyFields in memory, so both modes write exactly the same keys.
+func patchFields(p interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	rv := reflect.ValueOf(p)
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rv.Field(i)
+		name := jsonName(rt.Field(i))
+		if name == "" || f.Kind() != reflect.Ptr || f.IsNil() {
+			continue
+		}
+		v := f.Elem().Interface()
+		switch t := v.(type) {
+		case FlexInt:
+			v = int(t)
+		case FlexFloat:
+			v = float64(t)
+		}
+		out[name] = v
+	}
+	return out
+}
+
+// applyFields copies patchFields output onto an entity struct by json tag
+func applyFields(dst interface{}, fields map[string]interface{}) {
+	rv := reflect.ValueOf(dst).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		v, ok := fields[jsonName(rt.Field(i))]
+		if !ok {
+			continue
+		}
+		f := rv.Field(i)
+		val := reflect.ValueOf(v)
+		if f.Kind() == reflect.Interface {
+			f.Set(val)
+		} else if val.Type().ConvertibleTo(f.Type()) {
+			f.Set(val.Convert(f.Type()))
+		}
+	}
+}
+
+// ParseTimestamp accepts RFC3339 as well as the date and datetime-local
+// formats HTML inputs send (interpreted in server local time).
+func ParseTimestamp(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			if layout == "2006-01-02" {
+				// a bare date deadline covers the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func validEmail(s string) bool {
+	_, err := mail.ParseAddress(s)
+	return err == nil
+}
+
+// CompanyPatch lists the company fields clients may change. Verified and
+// Approved are admin-only; the handler enforces that.
+type CompanyPatch struct {
+	Name        *string `json:"name,omitempty"`
+	Email       *string `json:"email,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Website     *string `json:"website,omitempty"`
+	Industry    *string `json:"industry,omitempty"`
+	Verified    *bool   `json:"verified,omitempty"`
+	Approved    *bool   `json:"approved,omitempty"`
+	UpdatedAt   *string `json:"updated_at,omitempty" patch:"server"`
+	IfVersion   *int64  `json:"-"`
+}
+
+func (p *CompanyPatch) Validate() error {
+	verr := &ValidationError{}
+	if p.Name != nil && strings.TrimSpace(*p.Name) == "" {
+		verr.add("name", "must not be empty")
+	}
+	if p.Email != nil && *p.Email != "" && !validEmail(*p.Email) {
+		verr.add("email", "must be a valid email address")
+	}
+	// verified and approved are two names for the same flag; keep them in sync
+	if p.Verified != nil && p.Approved == nil {
+		p.Approved = p.Verified
+	} else if p.Approved != nil && p.Verified == nil {
+		p.Verified = p.Approved
+	} else if p.Verified != nil && *p.Verified != *p.Approved {
+		verr.add("approved", "must match verified")
+	}
+	return verr.orNil()
+}
+
+// ProfilePatch lists the profile fields clients may change
+type ProfilePatch struct {
+	FullName  *string `json:"full_name,omitempty"`
+	Email     *string `json:"email,omitempty"`
+	Role      *string `json:"role,omitempty"`
+	UpdatedAt *string `json:"updated_at,omitempty" patch:"server"`
+	IfVersion *int64  `json:"-"`
+}
+
+func (p *ProfilePatch) Validate() error {
+	verr := &ValidationError{}
+	if p.FullName != nil && strings.TrimSpace(*p.FullName) == "" {
+		verr.add("full_name", "must not be empty")
+	}
+	if p.Email != nil && !validEmail(*p.Email) {
+		verr.add("email", "must be a valid email address")
+	}
+	if p.Role != nil && *p.Role != "student" && *p.Role != "recruiter" && *p.Role != "admin" {
+		verr.add("role", "must be one of student, recruiter, admin")
+	}
+	return verr.orNil()
+}
+
+// StudentProfilePatch lists the student profile fields clients may change
+type StudentProfilePatch struct {
+	RollNumber     *string                   `json:"roll_number,omitempty"`
+	CGPA           *FlexFloat                `json:"cgpa,omitempty"`
+	Branch         *string                   `json:"branch,omitempty"`
+	GraduationYear *FlexInt                  `json:"graduation_year,omitempty"`
+	Backlogs       *FlexInt                  `json:"backlogs,omitempty"`
+	GapMonths      *FlexInt                  `json:"gap_months,omitempty"`
+	Skills         *[]string                 `json:"skills,omitempty"`
+	Projects       *[]map[string]interface{} `json:"projects,omitempty"`
+	Internships    *[]map[string]interface{} `json:"internships,omitempty"`
+	UpdatedAt      *string                   `json:"updated_at,omitempty" patch:"server"`
+	IfVersion      *int64                    `json:"-"`
+}
+
+func (p *StudentProfilePatch) Validate() error {
+	verr := &ValidationError{}
+	if p.RollNumber != nil && strings.TrimSpace(*p.RollNumber) == "" {
+		verr.add("roll_number", "must not be empty")
+	}
+	if p.CGPA != nil && (*p.CGPA < 0 || *p.CGPA > 10) {
+		verr.add("cgpa", "must be between 0 and 10")
+	}
+	if p.GraduationYear != nil && (*p.GraduationYear < 1950 || *p.GraduationYear > 2100) {
+		verr.add("graduation_year", "must be between 1950 and 2100")
+	}
+	if p.Backlogs != nil && *p.Backlogs < 0 {
+		verr.add("backlogs", "must not be negative")
+	}
+	if p.GapMonths != nil && *p.GapMonths < 0 {
+		verr.add("gap_months", "must not be negative")
+	}
+	return verr.orNil()
+}
+
+// JobPostingPatch lists the job fields clients may change. Lifecycle
+// timestamps are server-managed and only set by the publish/close flows.
+type JobPostingPatch struct {
+	Title               *string                 `json:"title,omitempty"`
+	Description         *string                 `json:"description,omitempty"`
+	Role                *string                 `json:"role,omitempty"`
+	Openings            *FlexInt                `json:"openings,omitempty"`
+	SalaryMin           *FlexFloat              `json:"salary_min,omitempty"`
+	SalaryMax           *FlexFloat              `json:"salary_max,omitempty"`
+	JobLocation         *string                 `json:"job_location,omitempty"`
+	BondTerms           *string                 `json:"bond_terms,omitempty"`
+	EligibilityCriteria *map[string]interface{} `json:"eligibility_criteria,omitempty"`
+	ApplicationDeadline *string                 `json:"application_deadline,omitempty"`
+	Status              *string                 `json:"status,omitempty"`
+	PublishAt           *string                 `json:"publish_at,omitempty" patch:"server"`
+	PublishedAt         *string                 `json:"published_at,omitempty" patch:"server"`
+	ClosedAt            *string                 `json:"closed_at,omitempty" patch:"server"`
+	UpdatedAt           *string                 `json:"updated_at,omitempty" patch:"server"`
+	IfVersion           *int64                  `json:"-"`
+}
+
+func (p *JobPostingPatch) Validate() error {
+	verr := &ValidationError{}
+	if p.Title != nil && strings.TrimSpace(*p.Title) == "" {
+		verr.add("title", "must not be empty")
+	}
+	if p.Openings != nil && *p.Openings < 1 {
+		verr.add("openings", "must be at least 1")
+	}
+	if p.SalaryMin != nil && *p.SalaryMin < 0 {
+		verr.add("salary_min", "must not be negative")
+	}
+	if p.SalaryMax != nil && *p.SalaryMax < 0 {
+		verr.add("salary_max", "must not be negative")
+	}
+	if p.SalaryMin != nil && p.SalaryMax != nil && *p.SalaryMax < *p.SalaryMin {
+		verr.add("salary_max", "must not be less than salary_min")
+	}
+	if p.ApplicationDeadline != nil && *p.ApplicationDeadline != "" {
+		if _, ok := ParseTimestamp(*p.ApplicationDeadline); !ok {
+			verr.add("application_deadline", "must be an RFC3339 timestamp or YYYY-MM-DD date")
+		}
+	}
+	if p.Status != nil && *p.Status != "draft" && *p.Status != "active" && *p.Status != "closed" {
+		verr.add("status", "must be one of draft, active, closed")
+	}
+	return verr.orNil()
+}
+
+// Application and eligibility statuses from the applications table, plus
+// "closed" for applications whose job was withdrawn.
+var (
+	applicationStatuses = map[string]bool{
+		"applied": true, "shortlisted": true, "interview_scheduled": true, "selected": true,
+		"rejected": true, "offer_accepted": true, "offer_rejected": true, "closed": true,
+	}
+	eligibilityStatuses = map[string]bool{"eligible": true, "not_eligible": true, "conditional": true}
+)
+
+// ApplicationPatch lists the application fields clients may change
+type ApplicationPatch struct {
+	Status            *string `json:"status,omitempty"`
+	EligibilityStatus *string `json:"eligibility_status,omitempty"`
+	EligibilityNotes  *string `json:"eligibility_notes,omitempty"`
+	UpdatedAt         *string `json:"updated_at,omitempty" patch:"server"`
+	IfVersion         *int64  `json:"-"`
+}
+
+func (p *ApplicationPatch) Validate() error {
+	verr := &ValidationError{}
+	if p.Status != nil && !applicationStatuses[*p.Status] {
+		verr.add("status", "unknown application status %q", *p.Status)
+	}
+	if p.EligibilityStatus != nil && !eligibilityStatuses[*p.EligibilityStatus] {
+		verr.add("eligibility_status", "must be one of eligible, not_eligible, conditional")
+	}
+	return verr.orNil()
+}
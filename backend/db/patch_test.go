@@ -0,0 +1,91 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodePatchAndValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		patch  interface{ Validate() error }
+		body   string
+		ignore []string
+		// want lists the fields reported invalid, in order; none for a valid patch
+		want []string
+	}{
+		{name: "profile", patch: &ProfilePatch{}, body: `{"full_name":"Ana","email":"ana@campus.test"}`},
+		{name: "echoed bookkeeping keys are dropped", patch: &ProfilePatch{}, body: `{"id":"p1","_id":"p1","created_at":"x","updated_at":"x","full_name":"Ana"}`},
+		{name: "null leaves a field as is", patch: &ProfilePatch{}, body: `{"full_name":null}`},
+		{name: "caller ignored keys are dropped", patch: &CompanyPatch{}, body: `{"recruiter_id":"r1","name":"Acme"}`, ignore: []string{"recruiter_id"}},
+		{name: "not an object", patch: &ProfilePatch{}, body: `["full_name"]`, want: []string{"body"}},
+		{name: "unknown field", patch: &ProfilePatch{}, body: `{"version":3}`, want: []string{"version"}},
+		{name: "server field is read-only", patch: &JobPostingPatch{}, body: `{"published_at":"2026-01-01T00:00:00Z"}`, want: []string{"published_at"}},
+		{name: "wrong type", patch: &ProfilePatch{}, body: `{"full_name":5}`, want: []string{"full_name"}},
+		{name: "blank name", patch: &ProfilePatch{}, body: `{"full_name":"  "}`, want: []string{"full_name"}},
+		{name: "invalid email", patch: &ProfilePatch{}, body: `{"email":"not-an-email"}`, want: []string{"email"}},
+		{name: "unknown role", patch: &ProfilePatch{}, body: `{"role":"superuser"}`, want: []string{"role"}},
+		{name: "errors are sorted by field", patch: &ProfilePatch{}, body: `{"zeta":1,"alpha":2}`, want: []string{"alpha", "zeta"}},
+		{name: "numeric strings and whole floats", patch: &StudentProfilePatch{}, body: `{"cgpa":"8.5","graduation_year":2025.0,"backlogs":"0"}`},
+		{name: "fractional integer", patch: &StudentProfilePatch{}, body: `{"graduation_year":2025.5}`, want: []string{"graduation_year"}},
+		{name: "cgpa out of range", patch: &StudentProfilePatch{}, body: `{"cgpa":11}`, want: []string{"cgpa"}},
+		{name: "negative backlogs", patch: &StudentProfilePatch{}, body: `{"backlogs":-1,"gap_months":-2}`, want: []string{"backlogs", "gap_months"}},
+		{name: "salary range", patch: &JobPostingPatch{}, body: `{"salary_min":10,"salary_max":5}`, want: []string{"salary_max"}},
+		{name: "deadline as a date", patch: &JobPostingPatch{}, body: `{"application_deadline":"2026-11-30"}`},
+		{name: "malformed deadline", patch: &JobPostingPatch{}, body: `{"application_deadline":"30/11/2026"}`, want: []string{"application_deadline"}},
+		{name: "job status", patch: &JobPostingPatch{}, body: `{"status":"open"}`, want: []string{"status"}},
+		{name: "no openings", patch: &JobPostingPatch{}, body: `{"openings":0}`, want: []string{"openings"}},
+		{name: "verified alone", patch: &CompanyPatch{}, body: `{"verified":true}`},
+		{name: "verified and approved disagree", patch: &CompanyPatch{}, body: `{"verified":true,"approved":false}`, want: []string{"approved"}},
+		{name: "application status", patch: &ApplicationPatch{}, body: `{"status":"hired"}`, want: []string{"status"}},
+		{name: "eligibility status", patch: &ApplicationPatch{}, body: `{"eligibility_status":"maybe"}`, want: []string{"eligibility_status"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DecodePatch([]byte(tt.body), tt.patch, tt.ignore...)
+			if err == nil {
+				err = tt.patch.Validate()
+			}
+			var got []string
+			if err != nil {
+				var verr *ValidationError
+				if !errors.As(err, &verr) {
+					t.Fatalf("error = %v, want a *ValidationError", err)
+				}
+				for _, f := range verr.Fields {
+					got = append(got, f.Field)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("invalid fields = %v, want %v (%v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestDecodePatchValues(t *testing.T) {
+	var sp StudentProfilePatch
+	if err := DecodePatch([]byte(`{"cgpa":"8.5","graduation_year":2025.0,"skills":["go"]}`), &sp); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"cgpa": 8.5, "graduation_year": 2025, "skills": []string{"go"}}
+	if got := patchFields(sp); !reflect.DeepEqual(got, want) {
+		t.Fatalf("patchFields = %#v, want %#v", got, want)
+	}
+
+	var co CompanyPatch
+	if err := DecodePatch([]byte(`{"approved":true}`), &co); err != nil {
+		t.Fatal(err)
+	}
+	if err := co.Validate(); err != nil || co.Verified == nil || !*co.Verified {
+		t.Fatalf("approved did not set verified: %+v, %v", co, err)
+	}
+
+	var p ProfilePatch
+	err := DecodePatch([]byte(`{"full_name":5}`), &p)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Fields[0].Message != "must be of type string" {
+		t.Fatalf("type error = %v", err)
+	}
+}
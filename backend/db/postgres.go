@@ -0,0 +1,1084 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The postgres mode stores everything in the relational schema from
+// supabase/migrations. Rows map onto the same structs as Mongo documents:
+// a field's bson name is its column ("_id" is "id"), empty strings are
+// NULL, interface{} fields are jsonb and typed slices or structs are jsonb
+// encoded by their bson tags.
+
+// PG is the connection pool when Mode is ModePostgres
+var PG *pgxpool.Pool
+
+// pgQuerier is satisfied by both the pool and a transaction
+type pgQuerier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// switchToPostgres connects to the database at url. Callers fall back to the
+// memory mode if it fails.
+func switchToPostgres(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return err
+	}
+	PG = pool
+	setMode(ModePostgres)
+	cfg := pool.Config().ConnConfig
+	slog.Info("connected to postgres", "host", cfg.Host, "db", cfg.Database)
+	return nil
+}
+
+// pgTx runs fn in a transaction, committing only if it returns nil
+func pgTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, PG, fn)
+}
+
+// pgValidID reports whether id can be a primary key of table. Profile ids
+// are Firebase UIDs; every other table uses UUIDs.
+func pgValidID(table, id string) bool {
+	if table == "profiles" {
+		return id != ""
+	}
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
+// pgErr maps constraint violations onto the domain errors
+func pgErr(table string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	var pe *pgconn.PgError
+	if !errors.As(err, &pe) {
+		return err
+	}
+	// constraint names follow <table>_<column>_fkey / _check
+	column := func(suffix string) string {
+		return strings.TrimSuffix(strings.TrimPrefix(pe.ConstraintName, pe.TableName+"_"), suffix)
+	}
+	switch pe.Code {
+	case "23505": // unique_violation
+		for _, idx := range uniqueIndexes {
+			if idx.Name == pe.ConstraintName {
+				return idx.conflict()
+			}
+		}
+		return &ConflictError{Field: "id", Message: "duplicate " + table + " record"}
+	case "23503": // foreign_key_violation
+		return &ValidationError{Fields: []FieldError{{Field: column("_fkey"), Message: "refers to a record that does not exist"}}}
+	case "23502": // not_null_violation
+		return &ValidationError{Fields: []FieldError{{Field: pe.ColumnName, Message: "is required"}}}
+	case "23514": // check_violation
+		return &ValidationError{Fields: []FieldError{{Field: column("_check"), Message: "is not an allowed value"}}}
+	case "22P02": // invalid_text_representation, e.g. a malformed UUID
+		return &ValidationError{Fields: []FieldError{{Field: "id", Message: "is not a valid identifier"}}}
+	case "22007", "22008": // invalid or out of range datetime
+		return &ValidationError{Fields: []FieldError{{Field: "timestamp", Message: pe.Message}}}
+	}
+	return err
+}
+
+func pgCode(err error, code string) bool {
+	var pe *pgconn.PgError
+	return errors.As(err, &pe) && pe.Code == code
+}
+
+// Column mapping
+
+type pgKind int
+
+const (
+	pgText pgKind = iota
+	pgTime
+	pgInt
+	pgFloat
+	pgBool
+	pgJSON // interface{} fields
+	pgDoc  // typed slices, maps and structs
+)
+
+type pgColumn struct {
+	key   string // bson name, also the patch field name
+	name  string
+	field int
+	kind  pgKind
+}
+
+// pgRenamed lists columns whose name differs from the struct's bson name
+var pgRenamed = map[string]map[string]string{
+	"resumes": {"created_at": "uploaded_at"},
+}
+
+var pgColumnCache sync.Map
+
+// pgColumns returns the columns of table that back the fields of t
+func pgColumns(table string, t reflect.Type) []pgColumn {
+	cacheKey := table + "/" + t.String()
+	if cols, ok := pgColumnCache.Load(cacheKey); ok {
+		return cols.([]pgColumn)
+	}
+	cols := make([]pgColumn, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := strings.Split(f.Tag.Get("bson"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		name := key
+		if key == "_id" {
+			name = "id"
+		}
+		if r, ok := pgRenamed[table][key]; ok {
+			name = r
+		}
+		cols = append(cols, pgColumn{key: key, name: name, field: i, kind: pgKindOf(name, f.Type)})
+	}
+	pgColumnCache.Store(cacheKey, cols)
+	return cols
+}
+
+func pgKindOf(name string, t reflect.Type) pgKind {
+	switch t.Kind() {
+	case reflect.String:
+		if strings.HasSuffix(name, "_at") || name == "application_deadline" {
+			return pgTime
+		}
+		return pgText
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return pgInt
+	case reflect.Float32, reflect.Float64:
+		return pgFloat
+	case reflect.Bool:
+		return pgBool
+	case reflect.Interface:
+		return pgJSON
+	}
+	return pgDoc
+}
+
+// expr is the select expression for the column under alias. Timestamps come
+// back in RFC3339 UTC and NULLs as zero values, as the structs expect.
+func (c pgColumn) expr(alias string) string {
+	col := alias + "." + pgx.Identifier{c.name}.Sanitize()
+	switch c.kind {
+	case pgTime:
+		return fmt.Sprintf(`coalesce(to_char(%s AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), '')`, col)
+	case pgInt:
+		return fmt.Sprintf("coalesce(%s, 0)::bigint", col)
+	case pgFloat:
+		return fmt.Sprintf("coalesce(%s, 0)::float8", col)
+	case pgBool:
+		return fmt.Sprintf("coalesce(%s, false)", col)
+	case pgJSON:
+		return col
+	}
+	return fmt.Sprintf("coalesce(%s::text, '')", col)
+}
+
+// value converts a field for writing. A nil result stores NULL.
+func (c pgColumn) value(v reflect.Value) (interface{}, error) {
+	switch c.kind {
+	case pgText:
+		if v.String() == "" {
+			return nil, nil
+		}
+		return v.String(), nil
+	case pgTime:
+		s := v.String()
+		if s == "" {
+			return nil, nil
+		}
+		// same parsing as the lifecycle code, so bare dates mean end of day
+		if t, ok := ParseTimestamp(s); ok {
+			return t, nil
+		}
+		return s, nil
+	case pgJSON:
+		if v.IsNil() {
+			return nil, nil
+		}
+		data, err := json.Marshal(v.Interface())
+		return string(data), err
+	case pgDoc:
+		if (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.IsNil() {
+			return nil, nil
+		}
+		return pgDocJSON(v.Interface())
+	}
+	return v.Interface(), nil
+}
+
+// pgDocJSON encodes v as JSON following its bson tags, so fields hidden from
+// API responses (json:"-") are still stored
+func pgDocJSON(v interface{}) (string, error) {
+	data, err := bson.MarshalExtJSON(bson.M{"v": v}, false, false)
+	if err != nil {
+		return "", err
+	}
+	var wrapped struct {
+		V json.RawMessage `json:"v"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return "", err
+	}
+	return string(wrapped.V), nil
+}
+
+func pgDocDecode(s string, dst interface{}) error {
+	var wrapped struct {
+		V bson.RawValue `bson:"v"`
+	}
+	if err := bson.UnmarshalExtJSON([]byte(`{"v":`+s+`}`), false, &wrapped); err != nil {
+		return err
+	}
+	return wrapped.V.Unmarshal(dst)
+}
+
+// pgRecord holds the scan targets for one table's columns within a row
+type pgRecord struct {
+	cols    []pgColumn
+	targets []interface{}
+}
+
+func newPGRecord(table string, t reflect.Type) *pgRecord {
+	r := &pgRecord{cols: pgColumns(table, t)}
+	for _, c := range r.cols {
+		switch c.kind {
+		case pgInt:
+			r.targets = append(r.targets, new(int64))
+		case pgFloat:
+			r.targets = append(r.targets, new(float64))
+		case pgBool:
+			r.targets = append(r.targets, new(bool))
+		case pgJSON:
+			r.targets = append(r.targets, new(interface{}))
+		default:
+			r.targets = append(r.targets, new(string))
+		}
+	}
+	return r
+}
+
+func (r *pgRecord) selectList(alias string) string {
+	exprs := make([]string, 0, len(r.cols))
+	for _, c := range r.cols {
+		exprs = append(exprs, c.expr(alias))
+	}
+	return strings.Join(exprs, ", ")
+}
+
+// decode copies the last scanned row into dst, a pointer to a struct
+func (r *pgRecord) decode(dst interface{}) error {
+	rv := reflect.ValueOf(dst).Elem()
+	for i, c := range r.cols {
+		f := rv.Field(c.field)
+		switch t := r.targets[i].(type) {
+		case *int64:
+			f.SetInt(*t)
+		case *float64:
+			f.SetFloat(*t)
+		case *bool:
+			f.SetBool(*t)
+		case *interface{}:
+			if *t != nil {
+				f.Set(reflect.ValueOf(*t))
+			}
+		case *string:
+			if c.kind != pgDoc {
+				f.SetString(*t)
+			} else if *t != "" {
+				if err := pgDocDecode(*t, f.Addr().Interface()); err != nil {
+					return fmt.Errorf("%s: %w", c.name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// pgScanRows scans each row into recs and calls fn after every row
+func pgScanRows(rows pgx.Rows, fn func() error, recs ...*pgRecord) error {
+	defer rows.Close()
+	targets := make([]interface{}, 0)
+	for _, r := range recs {
+		targets = append(targets, r.targets...)
+	}
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return err
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Generic statements
+
+// pgSelect runs query, whose %s verb is replaced by table's select list
+// under alias t, and scans every row into a T
+func pgSelect[T any](ctx context.Context, q pgQuerier, table, query string, args ...interface{}) ([]T, error) {
+	rec := newPGRecord(table, reflect.TypeOf((*T)(nil)).Elem())
+	rows, err := q.Query(ctx, fmt.Sprintf(query, rec.selectList("t")), args...)
+	if err != nil {
+		if pgCode(err, "22P02") {
+			// a malformed id in the filter matches nothing
+			return []T{}, nil
+		}
+		return nil, pgErr(table, err)
+	}
+	out := make([]T, 0)
+	err = pgScanRows(rows, func() error {
+		var v T
+		if err := rec.decode(&v); err != nil {
+			return err
+		}
+		out = append(out, v)
+		return nil
+	}, rec)
+	if err != nil {
+		return nil, pgErr(table, err)
+	}
+	return out, nil
+}
+
+// pgWhere turns an equality filter into conditions on alias t, appending
+// the values to args. Keys listed in skip are left for the caller.
+func pgWhere(filter map[string]interface{}, args *[]interface{}, skip ...string) []string {
+	keys := make([]string, 0, len(filter))
+outer:
+	for k := range filter {
+		for _, s := range skip {
+			if k == s {
+				continue outer
+			}
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	conds := make([]string, 0, len(keys))
+	for _, k := range keys {
+		col := k
+		if k == "_id" {
+			col = "id"
+		}
+		*args = append(*args, filter[k])
+		conds = append(conds, fmt.Sprintf("t.%s = $%d", pgx.Identifier{col}.Sanitize(), len(*args)))
+	}
+	return conds
+}
+
+// pgList selects the rows of table matching an equality filter
+func pgList[T any](ctx context.Context, table string, filter map[string]interface{}, order string) ([]T, error) {
+	args := []interface{}{}
+	query := "SELECT %s FROM " + table + " t"
+	if conds := pgWhere(filter, &args); len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	if order != "" {
+		query += " ORDER BY " + order
+	}
+	return pgSelect[T](ctx, PG, table, query, args...)
+}
+
+// pgGet returns the row of table with the given id
+func pgGet[T any](ctx context.Context, table, id string) (T, error) {
+	var zero T
+	if !pgValidID(table, id) {
+		return zero, ErrNotFound
+	}
+	rows, err := pgSelect[T](ctx, PG, table, "SELECT %s FROM "+table+" t WHERE t.id = $1", id)
+	if err != nil {
+		return zero, err
+	}
+	if len(rows) == 0 {
+		return zero, ErrNotFound
+	}
+	return rows[0], nil
+}
+
+// pgInsert inserts v, a struct mapped onto table. NULL fields are omitted so
+// the column defaults apply.
+func pgInsert(ctx context.Context, q pgQuerier, table string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	names := make([]string, 0)
+	params := make([]string, 0)
+	args := make([]interface{}, 0)
+	for _, c := range pgColumns(table, rv.Type()) {
+		val, err := c.value(rv.Field(c.field))
+		if err != nil {
+			return err
+		}
+		if val == nil {
+			continue
+		}
+		args = append(args, val)
+		names = append(names, pgx.Identifier{c.name}.Sanitize())
+		params = append(params, fmt.Sprintf("$%d", len(args)))
+	}
+	_, err := q.Exec(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table, strings.Join(names, ", "), strings.Join(params, ", ")), args...)
+	return pgErr(table, err)
+}
+
+// pgUpdate sets fields (keyed like patchFields output) on the row of table
+// with the given id and bumps its version, only while that is want when set.
+// t is the struct type stored in table.
+func pgUpdate(ctx context.Context, q pgQuerier, table string, t reflect.Type, id string, fields map[string]interface{}, want *int64) error {
+	if !pgValidID(table, id) {
+		return ErrNotFound
+	}
+	sets := make([]string, 0, len(fields))
+	args := []interface{}{id}
+	for _, c := range pgColumns(table, t) {
+		v, ok := fields[c.key]
+		if !ok || c.key == "_id" {
+			continue
+		}
+		var val interface{}
+		if v != nil {
+			// convert through a field of the column's type, as applyFields does
+			fv := reflect.New(t.Field(c.field).Type).Elem()
+			rv := reflect.ValueOf(v)
+			if fv.Kind() == reflect.Interface {
+				fv.Set(rv)
+			} else if rv.Type().ConvertibleTo(fv.Type()) {
+				fv.Set(rv.Convert(fv.Type()))
+			} else {
+				return &ValidationError{Fields: []FieldError{{Field: c.key, Message: "has the wrong type"}}}
+			}
+			var err error
+			if val, err = c.value(fv); err != nil {
+				return err
+			}
+		}
+		args = append(args, val)
+		sets = append(sets, fmt.Sprintf("%s = $%d", pgx.Identifier{c.name}.Sanitize(), len(args)))
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	sets = append(sets, "version = version + 1")
+	where := "id = $1"
+	if want != nil {
+		args = append(args, *want)
+		where += fmt.Sprintf(" AND version = $%d", len(args))
+	}
+	tag, err := q.Exec(ctx, fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(sets, ", "), where), args...)
+	if err != nil {
+		return pgErr(table, err)
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+	// a miss is only a conflict if the row still exists
+	if want != nil {
+		var exists bool
+		if err := q.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM "+table+" WHERE id = $1)", id).Scan(&exists); err == nil && exists {
+			return staleVersion()
+		}
+	}
+	return ErrNotFound
+}
+
+// pgPublish stores events in the transaction
+func pgPublish(ctx context.Context, tx pgx.Tx, events []Event) error {
+	for _, e := range events {
+		if err := pgInsert(ctx, tx, "outbox", e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pgUpdatePublishing is pgUpdate storing events in the same transaction
+func pgUpdatePublishing(ctx context.Context, table string, t reflect.Type, id string, fields map[string]interface{}, want *int64, events []Event) error {
+	return pgTx(ctx, func(tx pgx.Tx) error {
+		if err := pgUpdate(ctx, tx, table, t, id, fields, want); err != nil {
+			return err
+		}
+		return pgPublish(ctx, tx, events)
+	})
+}
+
+// pgReviewVerificationRequest stores a review and its company change in one
+// transaction
+func pgReviewVerificationRequest(ctx context.Context, id string, patch map[string]interface{}, ifVersion *int64,
+	companyID string, coFields map[string]interface{}, company *CompanyPatch, events []Event) error {
+	return pgTx(ctx, func(tx pgx.Tx) error {
+		if err := pgUpdate(ctx, tx, "verification_requests", reflect.TypeOf(VerificationRequest{}), id, patch, ifVersion); err != nil {
+			return err
+		}
+		if company == nil {
+			return nil
+		}
+		if err := pgUpdate(ctx, tx, "companies", reflect.TypeOf(Company{}), companyID, coFields, company.IfVersion); err != nil {
+			return err
+		}
+		return pgPublish(ctx, tx, events)
+	})
+}
+
+// Queries that need more than the generic statements
+
+func pgGetCompanies(ctx context.Context, filter map[string]interface{}) ([]Company, error) {
+	args := []interface{}{}
+	conds := pgWhere(filter, &args, "member_id")
+	if uid, ok := filter["member_id"].(string); ok {
+		args = append(args, uid)
+		n := len(args)
+		conds = append(conds, fmt.Sprintf("(t.recruiter_id = $%d OR t.id IN (SELECT company_id FROM company_members WHERE user_id = $%d))", n, n))
+	}
+	query := "SELECT %s FROM companies t"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	return pgSelect[Company](ctx, PG, "companies", query, args...)
+}
+
+func pgGetJobPostings(ctx context.Context, filter map[string]interface{}) ([]JobPosting, error) {
+	args := []interface{}{}
+	query := "SELECT %s FROM job_postings t" + pgJobWhere(filter, &args)
+	return pgSelect[JobPosting](ctx, PG, "job_postings", query, args...)
+}
+
+// pgJobWhere is the WHERE clause for job posting filters, which hide
+// soft-deleted postings unless include_deleted is set
+func pgJobWhere(filter map[string]interface{}, args *[]interface{}) string {
+	conds := pgWhere(filter, args, "include_deleted")
+	if includeDeleted, _ := filter["include_deleted"].(bool); !includeDeleted {
+		conds = append(conds, "t.deleted_at IS NULL")
+	}
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// pgDeleteJobPosting soft-deletes a posting and closes its open applications
+// in one transaction
+func pgDeleteJobPosting(ctx context.Context, id, now string) ([]Application, error) {
+	if !pgValidID("job_postings", id) {
+		return nil, ErrNotFound
+	}
+	var closed []Application
+	err := pgTx(ctx, func(tx pgx.Tx) error {
+		var companyID string
+		err := tx.QueryRow(ctx,
+			"UPDATE job_postings SET deleted_at = $2, status = 'closed', version = version + 1 WHERE id = $1 AND deleted_at IS NULL RETURNING company_id::text",
+			id, now).Scan(&companyID)
+		if err != nil {
+			return pgErr("job_postings", err)
+		}
+		open, err := pgSelect[Application](ctx, tx, "applications",
+			"SELECT %s FROM applications t WHERE t.job_id = $1 AND t.status = ANY($2) FOR UPDATE",
+			id, openApplicationStatuses)
+		if err != nil {
+			return err
+		}
+		closed, err = pgSelect[Application](ctx, tx, "applications",
+			"UPDATE applications t SET status = 'closed', updated_at = $2, version = version + 1 WHERE t.job_id = $1 AND t.status = ANY($3) RETURNING %s",
+			id, now, openApplicationStatuses)
+		if err != nil {
+			return err
+		}
+		from := make(map[string]string, len(open))
+		for _, a := range open {
+			from[a.ID] = a.Status
+		}
+		events := make([]Event, 0, len(closed))
+		for _, a := range closed {
+			events = append(events, ApplicationStatusEvent(a, companyID, from[a.ID]))
+		}
+		return pgPublish(ctx, tx, events)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return closed, nil
+}
+
+func pgRestoreJobPosting(ctx context.Context, id string) error {
+	if !pgValidID("job_postings", id) {
+		return ErrNotFound
+	}
+	tag, err := PG.Exec(ctx,
+		"UPDATE job_postings SET deleted_at = NULL, version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return pgErr("job_postings", err)
+	}
+	if tag.RowsAffected() == 0 {
+		var exists bool
+		if err := PG.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM job_postings WHERE id = $1)", id).Scan(&exists); err == nil && exists {
+			return notDeleted()
+		}
+		return ErrNotFound
+	}
+	return nil
+}
+
+func pgCountApplications(ctx context.Context, jobID string, statuses []string) (int64, error) {
+	if !pgValidID("job_postings", jobID) {
+		return 0, nil
+	}
+	var n int64
+	err := PG.QueryRow(ctx,
+		"SELECT count(*) FROM applications WHERE job_id = $1 AND status = ANY($2)", jobID, statuses).Scan(&n)
+	return n, pgErr("applications", err)
+}
+
+// pgCreateInterview inserts the interview, moves its application to
+// interview_scheduled and stores events in one transaction
+func pgCreateInterview(ctx context.Context, rec map[string]interface{}, events []Event) error {
+	str := func(k string) interface{} {
+		s, _ := rec[k].(string)
+		if s == "" {
+			return nil
+		}
+		if strings.HasSuffix(k, "_at") {
+			if t, ok := ParseTimestamp(s); ok {
+				return t
+			}
+		}
+		return s
+	}
+	return pgTx(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO interviews (id, application_id, scheduled_at, location, mode, created_at)
+			VALUES ($1, $2, $3, $4, $5, coalesce($6, now()))`,
+			str("id"), str("application_id"), str("scheduled_at"), str("location"), str("mode"), str("created_at"))
+		if err != nil {
+			return pgErr("interviews", err)
+		}
+		if aid := str("application_id"); aid != nil {
+			tag, err := tx.Exec(ctx,
+				"UPDATE applications SET status = 'interview_scheduled', updated_at = now(), version = version + 1 WHERE id = $1", aid)
+			if err != nil {
+				return pgErr("applications", err)
+			}
+			if tag.RowsAffected() == 0 {
+				return ErrNotFound
+			}
+		}
+		return pgPublish(ctx, tx, events)
+	})
+}
+
+func pgRemoveCompanyMember(ctx context.Context, companyID, userID string) error {
+	if !pgValidID("companies", companyID) {
+		return ErrNotFound
+	}
+	tag, err := PG.Exec(ctx,
+		"DELETE FROM company_members WHERE company_id = $1 AND user_id = $2", companyID, userID)
+	if err != nil {
+		return pgErr("company_members", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func pgAddVerificationDocument(ctx context.Context, id string, doc VerificationDocument, updatedAt string) error {
+	if !pgValidID("verification_requests", id) {
+		return ErrNotFound
+	}
+	docs, err := pgDocJSON([]VerificationDocument{doc})
+	if err != nil {
+		return err
+	}
+	tag, err := PG.Exec(ctx,
+		`UPDATE verification_requests SET documents = coalesce(documents, '[]'::jsonb) || $2::jsonb, updated_at = $3,
+		version = version + 1 WHERE id = $1`, id, docs, updatedAt)
+	if err != nil {
+		return pgErr("verification_requests", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func pgListAuditEntries(ctx context.Context, f AuditFilter) ([]AuditEntry, error) {
+	args := []interface{}{}
+	conds := []string{}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.ActorID != "" {
+		add("t.actor_id = $%d", f.ActorID)
+	}
+	if f.Entity != "" {
+		add("t.entity = $%d", f.Entity)
+	}
+	if f.EntityID != "" {
+		add("t.entity_id = $%d", f.EntityID)
+	}
+	if f.Action != "" {
+		add("t.action = $%d", f.Action)
+	}
+	if f.Since != "" {
+		add("t.created_at >= $%d", f.Since)
+	}
+	if f.Until != "" {
+		add("t.created_at <= $%d", f.Until)
+	}
+	query := "SELECT %s FROM audit_log t"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY t.created_at DESC"
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+	return pgSelect[AuditEntry](ctx, PG, "audit_log", query, args...)
+}
+
+func pgPurgeAuditEntries(ctx context.Context, before string) (int64, error) {
+	tag, err := PG.Exec(ctx, "DELETE FROM audit_log WHERE created_at < $1", before)
+	if err != nil {
+		return 0, pgErr("audit_log", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// pgSaveEventDelivery writes the delivery columns of e. The outbox is not
+// versioned: only the dispatcher updates events.
+func pgSaveEventDelivery(ctx context.Context, e Event) error {
+	if !pgValidID("outbox", e.ID) {
+		return ErrNotFound
+	}
+	delivered, err := pgDocJSON(e.Delivered)
+	if err != nil {
+		return err
+	}
+	tag, err := PG.Exec(ctx,
+		`UPDATE outbox SET status = $2, delivered = $3::jsonb, attempts = $4,
+		next_attempt_at = nullif($5, '')::timestamptz, last_error = nullif($6, ''),
+		dispatched_at = nullif($7, '')::timestamptz WHERE id = $1`,
+		e.ID, e.Status, delivered, e.Attempts, e.NextAttemptAt, e.LastError, e.DispatchedAt)
+	if err != nil {
+		return pgErr("outbox", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func pgPurgeEvents(ctx context.Context, before string) (int64, error) {
+	tag, err := PG.Exec(ctx, "DELETE FROM outbox WHERE status = $1 AND created_at < $2", EventDispatched, before)
+	if err != nil {
+		return 0, pgErr("outbox", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func pgDeleteWebhook(ctx context.Context, companyID, id string) error {
+	if !pgValidID("webhooks", id) {
+		return ErrNotFound
+	}
+	// deliveries go with it: webhook_deliveries cascades
+	tag, err := PG.Exec(ctx, "DELETE FROM webhooks WHERE id = $1 AND company_id = $2", id, companyID)
+	if err != nil {
+		return pgErr("webhooks", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func pgListWebhookDeliveries(ctx context.Context, filter map[string]interface{}, limit int) ([]WebhookDelivery, error) {
+	args := []interface{}{}
+	query := "SELECT %s FROM webhook_deliveries t"
+	if conds := pgWhere(filter, &args); len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY t.created_at DESC"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	return pgSelect[WebhookDelivery](ctx, PG, "webhook_deliveries", query, args...)
+}
+
+// pgSaveWebhookDeliveryAttempt writes the outcome columns of d
+func pgSaveWebhookDeliveryAttempt(ctx context.Context, d WebhookDelivery) error {
+	if !pgValidID("webhook_deliveries", d.ID) {
+		return ErrNotFound
+	}
+	tag, err := PG.Exec(ctx,
+		`UPDATE webhook_deliveries SET status = $2, attempts = $3,
+		next_attempt_at = nullif($4, '')::timestamptz, response_status = nullif($5, 0),
+		last_error = nullif($6, ''), delivered_at = nullif($7, '')::timestamptz WHERE id = $1`,
+		d.ID, d.Status, d.Attempts, d.NextAttemptAt, d.ResponseStatus, d.LastError, d.DeliveredAt)
+	if err != nil {
+		return pgErr("webhook_deliveries", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Joins
+
+func pgJobPostingsWithCompany(ctx context.Context, filter map[string]interface{}) ([]bson.M, error) {
+	job := newPGRecord("job_postings", reflect.TypeOf(JobPosting{}))
+	co := newPGRecord("companies", reflect.TypeOf(Company{}))
+	args := []interface{}{}
+	query := "SELECT " + job.selectList("t") + ", " + co.selectList("c") +
+		" FROM job_postings t LEFT JOIN companies c ON c.id = t.company_id" +
+		pgJobWhere(filter, &args) + " ORDER BY t.created_at DESC"
+	rows, err := PG.Query(ctx, query, args...)
+	if err != nil {
+		if pgCode(err, "22P02") {
+			return []bson.M{}, nil
+		}
+		return nil, pgErr("job_postings", err)
+	}
+	out := make([]bson.M, 0)
+	ids := make([]string, 0)
+	err = pgScanRows(rows, func() error {
+		var j JobPosting
+		var c Company
+		if err := job.decode(&j); err != nil {
+			return err
+		}
+		if err := co.decode(&c); err != nil {
+			return err
+		}
+		doc := toDoc(j)
+		if c.ID != "" {
+			doc["companies"] = toDoc(c)
+		}
+		out = append(out, doc)
+		ids = append(ids, j.ID)
+		return nil
+	}, job, co)
+	if err != nil {
+		return nil, pgErr("job_postings", err)
+	}
+	apps, err := pgSelect[Application](ctx, PG, "applications",
+		"SELECT %s FROM applications t WHERE t.job_id = ANY($1::text[]::uuid[]) ORDER BY t.created_at DESC", ids)
+	if err != nil {
+		return nil, err
+	}
+	byJob := map[string]bson.A{}
+	for _, a := range apps {
+		byJob[a.JobID] = append(byJob[a.JobID], toDoc(a))
+	}
+	for i, doc := range out {
+		list := byJob[ids[i]]
+		if list == nil {
+			list = bson.A{}
+		}
+		doc["applications"] = list
+		doc["applications_count"] = len(list)
+	}
+	return out, nil
+}
+
+func pgCompanyApplications(ctx context.Context, companyID string) ([]bson.M, error) {
+	if !pgValidID("companies", companyID) {
+		return []bson.M{}, nil
+	}
+	app := newPGRecord("applications", reflect.TypeOf(Application{}))
+	job := newPGRecord("job_postings", reflect.TypeOf(JobPosting{}))
+	sp := newPGRecord("student_profiles", reflect.TypeOf(StudentProfile{}))
+	prof := newPGRecord("profiles", reflect.TypeOf(Profile{}))
+	query := "SELECT " + app.selectList("t") + ", " + job.selectList("j") + ", " + sp.selectList("s") + ", " + prof.selectList("p") +
+		` FROM applications t
+		JOIN job_postings j ON j.id = t.job_id
+		LEFT JOIN student_profiles s ON s.id = t.student_id
+		LEFT JOIN profiles p ON p.id = s.user_id
+		WHERE j.company_id = $1
+		ORDER BY t.created_at DESC`
+	rows, err := PG.Query(ctx, query, companyID)
+	if err != nil {
+		return nil, pgErr("applications", err)
+	}
+	out := make([]bson.M, 0)
+	err = pgScanRows(rows, func() error {
+		var a Application
+		var j JobPosting
+		var s StudentProfile
+		var p Profile
+		for _, d := range []struct {
+			rec *pgRecord
+			dst interface{}
+		}{{app, &a}, {job, &j}, {sp, &s}, {prof, &p}} {
+			if err := d.rec.decode(d.dst); err != nil {
+				return err
+			}
+		}
+		doc := toDoc(a)
+		doc["job_postings"] = toDoc(j)
+		if s.ID != "" {
+			spDoc := toDoc(s)
+			if p.ID != "" {
+				spDoc["profiles"] = toDoc(p)
+			}
+			doc["student_profiles"] = spDoc
+		}
+		out = append(out, doc)
+		return nil
+	}, app, job, sp, prof)
+	if err != nil {
+		return nil, pgErr("applications", err)
+	}
+	return out, nil
+}
+
+// pgInterview maps the interviews table; the other backends keep
+// interviews as maps, which convert to and from it by their keys
+type pgInterview struct {
+	ID            string `bson:"id"`
+	ApplicationID string `bson:"application_id"`
+	ScheduledAt   string `bson:"scheduled_at"`
+	Location      string `bson:"location"`
+	Mode          string `bson:"mode"`
+	CreatedAt     string `bson:"created_at"`
+}
+
+// pgAll selects every row of table, soft deleted job postings included
+func pgAll[T any](ctx context.Context, table string) ([]interface{}, error) {
+	rows, err := pgSelect[T](ctx, PG, table, "SELECT %s FROM "+table+" t")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]interface{}, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func pgExport(ctx context.Context) (Dataset, error) {
+	load := map[string]func(ctx context.Context, table string) ([]interface{}, error){
+		"profiles":              pgAll[Profile],
+		"student_profiles":      pgAll[StudentProfile],
+		"companies":             pgAll[Company],
+		"company_members":       pgAll[CompanyMember],
+		"job_postings":          pgAll[JobPosting],
+		"resumes":               pgAll[Resume],
+		"applications":          pgAll[Application],
+		"notifications":         pgAll[Notification],
+		"verification_requests": pgAll[VerificationRequest],
+		"outbox":                pgAll[Event],
+		"webhooks":              pgAll[Webhook],
+		"webhook_deliveries":    pgAll[WebhookDelivery],
+		"interviews": func(ctx context.Context, table string) ([]interface{}, error) {
+			rows, err := pgAll[pgInterview](ctx, table)
+			if err != nil {
+				return nil, err
+			}
+			for i, r := range rows {
+				if rows[i], err = pgInterviewMap(r.(pgInterview)); err != nil {
+					return nil, err
+				}
+			}
+			return rows, nil
+		},
+	}
+	out := Dataset{}
+	for _, name := range Collections() {
+		var rows []interface{}
+		var err error
+		if name == "audit_log" {
+			rows, err = pgAuditLog(ctx)
+		} else {
+			rows, err = load[name](ctx, name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		out[name] = rows
+	}
+	return out, nil
+}
+
+func pgAuditLog(ctx context.Context) ([]interface{}, error) {
+	rows, err := pgSelect[AuditEntry](ctx, PG, "audit_log", "SELECT %s FROM audit_log t ORDER BY t.created_at, t.id")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]interface{}, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func pgInterviewMap(r pgInterview) (map[string]interface{}, error) {
+	data, err := bson.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	return m, bson.Unmarshal(data, &m)
+}
+
+// pgImport replaces every table the backend manages in one transaction.
+// TRUNCATE cascades to placement_stats, whose rows reference the replaced
+// students, companies and jobs.
+func pgImport(ctx context.Context, d Dataset) error {
+	names := Collections()
+	return pgTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "TRUNCATE "+strings.Join(names, ", ")+" CASCADE"); err != nil {
+			return err
+		}
+		for _, name := range names {
+			for _, rec := range d[name] {
+				id := recordField(rec, "id")
+				if m, ok := rec.(map[string]interface{}); ok {
+					var row pgInterview
+					data, err := bson.Marshal(m)
+					if err == nil {
+						err = bson.Unmarshal(data, &row)
+					}
+					if err != nil {
+						return fmt.Errorf("%s: %w", name, err)
+					}
+					rec = row
+				}
+				if err := pgInsert(ctx, tx, name, rec); err != nil {
+					return fmt.Errorf("%s/%s: %w", name, id, err)
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// uniqueIndex mirrors a UNIQUE constraint from the SQL schema. Documents with
+// an empty value in any of the fields are not indexed, like NULLs in SQL.
+type uniqueIndex struct {
+	Name       string
+	Collection string
+	Fields     []string
+	// Field is the name reported in the 409 response
+	Field   string
+	Message string
+	// CaseInsensitive compares values ignoring case (emails)
+	CaseInsensitive bool
+}
+
+var uniqueIndexes = []uniqueIndex{
+	{Name: "uniq_applications_job_student", Collection: "applications", Fields: []string{"job_id", "student_id"},
+		Field: "student_id", Message: "student has already applied to this job"},
+	{Name: "uniq_student_profiles_roll_number", Collection: "student_profiles", Fields: []string{"roll_number"},
+		Field: "roll_number", Message: "roll number is already registered"},
+	{Name: "uniq_student_profiles_user", Collection: "student_profiles", Fields: []string{"user_id"},
+		Field: "user_id", Message: "user already has a student profile"},
+	{Name: "uniq_profiles_email", Collection: "profiles", Fields: []string{"email"},
+		Field: "email", Message: "email is already in use", CaseInsensitive: true},
+	{Name: "uniq_company_members_company_user", Collection: "company_members", Fields: []string{"company_id", "user_id"},
+		Field: "user_id", Message: "user is already a member of this company"},
+}
+
+// memUnique is the in-memory secondary index: index name -> key -> document id
+var memUnique map[string]map[string]string
+
+func (idx uniqueIndex) conflict() error {
+	return &ConflictError{Field: idx.Field, Message: idx.Message}
+}
+
+// EnsureIndexes creates the unique indexes in Mongo. Existing duplicate data
+// makes creation fail; that is logged so the server still starts and the
+// migration runner can clean up.
+func EnsureIndexes(ctx context.Context) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		return
+	}
+	for _, idx := range uniqueIndexes {
+		keys := bson.D{}
+		partial := bson.M{}
+		for _, f := range idx.Fields {
+			keys = append(keys, bson.E{Key: f, Value: 1})
+			partial[f] = bson.M{"$gt": ""}
+		}
+		opts := options.Index().SetName(idx.Name).SetUnique(true).SetPartialFilterExpression(partial)
+		if idx.CaseInsensitive {
+			opts.SetCollation(&options.Collation{Locale: "en", Strength: 2})
+		}
+		_, err := DB.Collection(idx.Collection).Indexes().CreateOne(ctx, mongo.IndexModel{Keys: keys, Options: opts})
+		if err != nil {
+			slog.WarnContext(ctx, "failed to create unique index", "index", idx.Name, "error", err)
+		}
+	}
+}
+
+// uniqueErr converts a Mongo duplicate key error on one of our unique indexes
+// into a *ConflictError naming the field
+func uniqueErr(collection string, err error) error {
+	if err == nil || !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	for _, idx := range uniqueIndexes {
+		if idx.Collection == collection && strings.Contains(err.Error(), idx.Name) {
+			return idx.conflict()
+		}
+	}
+	return &ConflictError{Field: "id", Message: "duplicate " + collection + " record"}
+}
+
+// uniqueKey builds the composite index key for a document, or "" when one of
+// the fields is empty
+func uniqueKey(idx uniqueIndex, doc interface{}) string {
+	values := stringFields(doc)
+	parts := make([]string, 0, len(idx.Fields))
+	for _, f := range idx.Fields {
+		v := values[f]
+		if v == "" {
+			return ""
+		}
+		if idx.CaseInsensitive {
+			v = strings.ToLower(v)
+		}
+		parts = append(parts, v)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// stringFields returns a struct's string fields keyed by json name
+func stringFields(doc interface{}) map[string]string {
+	out := map[string]string{}
+	rv := reflect.ValueOf(doc)
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		if rv.Field(i).Kind() == reflect.String {
+			out[jsonName(rt.Field(i))] = rv.Field(i).String()
+		}
+	}
+	return out
+}
+
+// checkUniqueLocked reports a conflict if doc would collide with another
+// document in collection. Callers hold mu.
+func checkUniqueLocked(collection, id string, doc interface{}) error {
+	for _, idx := range uniqueIndexes {
+		if idx.Collection != collection {
+			continue
+		}
+		key := uniqueKey(idx, doc)
+		if key == "" {
+			continue
+		}
+		if owner, ok := memUnique[idx.Name][key]; ok && owner != id {
+			return idx.conflict()
+		}
+	}
+	return nil
+}
+
+// reindexLocked moves a document's index entries from old to doc; either may
+// be nil for inserts and deletes. Callers hold mu.
+func reindexLocked(collection, id string, old, doc interface{}) {
+	for _, idx := range uniqueIndexes {
+		if idx.Collection != collection {
+			continue
+		}
+		if memUnique[idx.Name] == nil {
+			memUnique[idx.Name] = map[string]string{}
+		}
+		if old != nil {
+			if key := uniqueKey(idx, old); key != "" && memUnique[idx.Name][key] == id {
+				delete(memUnique[idx.Name], key)
+			}
+		}
+		if doc != nil {
+			if key := uniqueKey(idx, doc); key != "" {
+				memUnique[idx.Name][key] = id
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// refuseWrites puts the store in the readonly fallback, so every persist
+// fails with ErrUnavailable, until the returned func is called
+func refuseWrites() (allow func()) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	prevActive, prevFallback := health.FallbackActive, fallback
+	health.FallbackActive, fallback = true, FallbackReadOnly
+	return func() {
+		healthMu.Lock()
+		defer healthMu.Unlock()
+		health.FallbackActive, fallback = prevActive, prevFallback
+	}
+}
+
+func TestUniqueConflicts(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		first func() error
+		dup   func() error
+		field string
+	}{
+		{
+			name:  "profile email ignores case",
+			first: func() error { return CreateProfile(ctx, Profile{ID: "p1", Email: "Ana@campus.test", Role: "student"}) },
+			dup:   func() error { return CreateProfile(ctx, Profile{ID: "p2", Email: "ana@CAMPUS.test", Role: "student"}) },
+			field: "email",
+		},
+		{
+			name:  "profile email on update",
+			first: func() error { return CreateProfile(ctx, Profile{ID: "p1", Email: "ana@campus.test", Role: "student"}) },
+			dup: func() error {
+				if err := CreateProfile(ctx, Profile{ID: "p2", Email: "ben@campus.test", Role: "student"}); err != nil {
+					return err
+				}
+				email := "ana@campus.test"
+				return UpdateProfile(ctx, "p2", ProfilePatch{Email: &email})
+			},
+			field: "email",
+		},
+		{
+			name: "roll number",
+			first: func() error {
+				return CreateStudentProfile(ctx, StudentProfile{ID: "s1", UserID: "u1", RollNumber: "CS-001"})
+			},
+			dup: func() error {
+				return CreateStudentProfile(ctx, StudentProfile{ID: "s2", UserID: "u2", RollNumber: "CS-001"})
+			},
+			field: "roll_number",
+		},
+		{
+			name:  "one student profile per user",
+			first: func() error { return CreateStudentProfile(ctx, StudentProfile{ID: "s1", UserID: "u1"}) },
+			dup:   func() error { return CreateStudentProfile(ctx, StudentProfile{ID: "s2", UserID: "u1"}) },
+			field: "user_id",
+		},
+		{
+			name:  "one application per job and student",
+			first: func() error { return CreateApplication(ctx, Application{ID: "a1", JobID: "j1", StudentID: "s1"}) },
+			dup:   func() error { return CreateApplication(ctx, Application{ID: "a2", JobID: "j1", StudentID: "s1"}) },
+			field: "student_id",
+		},
+		{
+			name: "one membership per company and user",
+			first: func() error {
+				return AddCompanyMember(ctx, CompanyMember{ID: "m1", CompanyID: "c1", UserID: "u1", Role: MemberRecruiter})
+			},
+			dup: func() error {
+				return AddCompanyMember(ctx, CompanyMember{ID: "m2", CompanyID: "c1", UserID: "u1", Role: MemberOwner})
+			},
+			field: "user_id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withMemory(t)
+			if err := tt.first(); err != nil {
+				t.Fatal(err)
+			}
+			err := tt.dup()
+			var cerr *ConflictError
+			if !errors.As(err, &cerr) || cerr.Field != tt.field {
+				t.Fatalf("error = %v, want a conflict on %s", err, tt.field)
+			}
+		})
+	}
+}
+
+// A write that is refused must not claim its unique keys
+func TestRefusedWriteKeepsKeysFree(t *testing.T) {
+	withMemory(t)
+	ctx := context.Background()
+
+	allow := refuseWrites()
+	err := CreateProfile(ctx, Profile{ID: "p1", Email: "ana@campus.test", Role: "student"})
+	allow()
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("refused create error = %v, want ErrUnavailable", err)
+	}
+
+	if err := CreateProfile(ctx, Profile{ID: "p2", Email: "ana@campus.test", Role: "student"}); err != nil {
+		t.Fatalf("email still taken by the refused write: %v", err)
+	}
+}
+
+// An insert naming an id that is already stored must not replace the record
+func TestDuplicateIDConflicts(t *testing.T) {
+	withMemory(t)
+	ctx := context.Background()
+	inserts := map[string]func(name string) error{
+		"companies": func(name string) error { return CreateCompany(ctx, Company{ID: "x1", Name: name}) },
+		"job_postings": func(name string) error {
+			return CreateJobPosting(ctx, JobPosting{ID: "x1", CompanyID: "c1", Title: name})
+		},
+		"resumes":  func(name string) error { return CreateResume(ctx, Resume{ID: "x1", StudentID: "s1", FileName: name}) },
+		"profiles": func(name string) error { return CreateProfile(ctx, Profile{ID: "x1", FullName: name}) },
+		"verification_requests": func(name string) error {
+			return CreateVerificationRequest(ctx, VerificationRequest{ID: "x1", CompanyID: "c1", Address: name})
+		},
+		"webhooks": func(name string) error { return CreateWebhook(ctx, Webhook{ID: "x1", CompanyID: "c1", URL: name}) },
+	}
+	for collection, insert := range inserts {
+		if err := insert("first"); err != nil {
+			t.Fatalf("%s: %v", collection, err)
+		}
+		err := insert("second")
+		var cerr *ConflictError
+		if !errors.As(err, &cerr) || cerr.Field != "id" {
+			t.Errorf("%s: second insert error = %v, want a conflict on id", collection, err)
+		}
+	}
+	if co, _ := GetCompany(ctx, "x1"); co.Name != "first" {
+		t.Errorf("company replaced by a duplicate insert: %+v", co)
+	}
+	if j, _ := GetJobPosting(ctx, "x1"); j.Title != "first" {
+		t.Errorf("job posting replaced by a duplicate insert: %+v", j)
+	}
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"sync/atomic"
+
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Units of work. An operation that writes several records runs them as one
+// unit so they are stored together or not at all:
+//
+//   - memory and bolt: the caller holds mu for the whole unit and stages its
+//     writes in a memBatch, which persists them in a single bolt transaction
+//     before touching the maps
+//   - postgres: pgTx
+//   - mongo: mongoTx, a session transaction when the deployment supports
+//     them (a replica set or sharded cluster). A standalone server has no
+//     transactions; the writes then run in order and a failure after the
+//     first is reported as a *PartialWriteError naming what was stored.
+
+// memBatch stages the writes of a unit of work in the memory and bolt modes
+type memBatch struct {
+	writes []memWrite
+}
+
+// memWrite stores v as collection/id, or removes the record when v is nil
+type memWrite struct {
+	collection, id string
+	v              interface{}
+	apply          func()
+}
+
+// put stages v as the record collection/id; apply stores it in the map once
+// the batch is persisted
+func (b *memBatch) put(collection, id string, v interface{}, apply func()) {
+	b.writes = append(b.writes, memWrite{collection: collection, id: id, v: v, apply: apply})
+}
+
+// remove stages the removal of collection/id; apply removes it from the map
+func (b *memBatch) remove(collection, id string, apply func()) {
+	b.writes = append(b.writes, memWrite{collection: collection, id: id, apply: apply})
+}
+
+// commitLocked persists the batch in one bolt transaction, then applies it.
+// A failed commit leaves both the file and the maps unchanged.
+func (b *memBatch) commitLocked() error {
+	if err := Writable(); err != nil {
+		return err
+	}
+	if boltDB != nil {
+		err := boltDB.Update(func(tx *bolt.Tx) error {
+			for _, w := range b.writes {
+				bucket, err := tx.CreateBucketIfNotExists([]byte(w.collection))
+				if err != nil {
+					return err
+				}
+				if w.v == nil {
+					if err := bucket.Delete([]byte(w.id)); err != nil {
+						return err
+					}
+					continue
+				}
+				data, err := bson.Marshal(w.v)
+				if err != nil {
+					return err
+				}
+				if err := bucket.Put([]byte(w.id), data); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	fallbackWrites.Add(int64(len(b.writes)))
+	for _, w := range b.writes {
+		w.apply()
+	}
+	return nil
+}
+
+// mongoTransactions is set when the connected deployment supports
+// multi-document transactions
+var mongoTransactions atomic.Bool
+
+// detectMongoTransactions asks the server whether it is part of a replica
+// set or sharded cluster, the deployments that support transactions
+func detectMongoTransactions(ctx context.Context, client *mongo.Client) bool {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid"
+}
+
+// mongoUnit is one run of a Mongo unit of work. Writes go through write so a
+// failure part way can be reported when there is no transaction to undo them.
+type mongoUnit struct {
+	ctx     context.Context
+	atomic  bool
+	written []string
+}
+
+// write runs one write of the unit; records name what it stores as
+// collection/id
+func (u *mongoUnit) write(fn func(ctx context.Context) error, records ...string) error {
+	if err := fn(u.ctx); err != nil {
+		if !u.atomic && len(u.written) > 0 {
+			return &PartialWriteError{Written: append([]string(nil), u.written...), Err: err}
+		}
+		return err
+	}
+	u.written = append(u.written, records...)
+	return nil
+}
+
+// mongoTx runs fn as a unit of work. In a transaction the driver may run fn
+// again after a transient error, so fn must start from its reads.
+func mongoTx(ctx context.Context, fn func(u *mongoUnit) error) error {
+	if !mongoTransactions.Load() {
+		return fn(&mongoUnit{ctx: ctx})
+	}
+	sess, err := DB.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer sess.EndSession(ctx)
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(&mongoUnit{ctx: sc, atomic: true})
+	})
+	return err
+}
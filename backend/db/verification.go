@@ -0,0 +1,294 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var inMemoryVerificationRequests map[string]VerificationRequest
+
+// Verification request statuses
+const (
+	VerificationPending          = "pending"
+	VerificationApproved         = "approved"
+	VerificationRejected         = "rejected"
+	VerificationChangesRequested = "changes_requested"
+)
+
+// VerificationDocument is a file attached to a verification request. The
+// bytes live in the blob store under BlobKey.
+type VerificationDocument struct {
+	ID          string `bson:"id" json:"id"`
+	Name        string `bson:"name" json:"name"`
+	ContentType string `bson:"content_type,omitempty" json:"content_type"`
+	Size        int64  `bson:"size" json:"size"`
+	BlobKey     string `bson:"blob_key" json:"-"`
+	UploadedAt  string `bson:"uploaded_at" json:"uploaded_at"`
+}
+
+// VerificationRequest is a recruiter's request to have a company verified,
+// together with the admin's decision and notes.
+type VerificationRequest struct {
+	ID                 string                 `bson:"_id,omitempty" json:"id"`
+	CompanyID          string                 `bson:"company_id" json:"company_id"`
+	SubmittedBy        string                 `bson:"submitted_by,omitempty" json:"submitted_by"`
+	RegistrationNumber string                 `bson:"registration_number,omitempty" json:"registration_number"`
+	TaxID              string                 `bson:"tax_id,omitempty" json:"tax_id"`
+	Address            string                 `bson:"address,omitempty" json:"address"`
+	ContactName        string                 `bson:"contact_name,omitempty" json:"contact_name"`
+	ContactPhone       string                 `bson:"contact_phone,omitempty" json:"contact_phone"`
+	Documents          []VerificationDocument `bson:"documents" json:"documents"`
+	Status             string                 `bson:"status" json:"status"`
+	ReviewNotes        string                 `bson:"review_notes,omitempty" json:"review_notes"`
+	ReviewedBy         string                 `bson:"reviewed_by,omitempty" json:"reviewed_by"`
+	ReviewedAt         string                 `bson:"reviewed_at,omitempty" json:"reviewed_at"`
+	CreatedAt          string                 `bson:"created_at,omitempty" json:"created_at"`
+	UpdatedAt          string                 `bson:"updated_at,omitempty" json:"updated_at"`
+	Version            int64                  `bson:"version" json:"version"`
+}
+
+func CreateVerificationRequest(ctx context.Context, v VerificationRequest) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	v.Version = FirstVersion
+	if v.Documents == nil {
+		v.Documents = []VerificationDocument{}
+	}
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := inMemoryVerificationRequests[v.ID]; ok {
+			return &ConflictError{Field: "id", Message: "duplicate verification_requests record"}
+		}
+		if err := persistLocked("verification_requests", v.ID, v); err != nil {
+			return err
+		}
+		inMemoryVerificationRequests[v.ID] = v
+		return nil
+	}
+	if Mode() == ModePostgres {
+		return pgInsert(ctx, PG, "verification_requests", v)
+	}
+	_, err := DB.Collection("verification_requests").InsertOne(ctx, v)
+	return uniqueErr("verification_requests", err)
+}
+
+func GetVerificationRequest(ctx context.Context, id string) (VerificationRequest, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		if v, ok := inMemoryVerificationRequests[id]; ok {
+			return v, nil
+		}
+		return VerificationRequest{}, ErrNotFound
+	}
+	if Mode() == ModePostgres {
+		return pgGet[VerificationRequest](ctx, "verification_requests", id)
+	}
+	var v VerificationRequest
+	if err := DB.Collection("verification_requests").FindOne(ctx, bson.M{"_id": id}).Decode(&v); err != nil {
+		return VerificationRequest{}, notFound(err)
+	}
+	return v, nil
+}
+
+// GetVerificationRequests lists requests newest first. Supports company_id and status filters.
+func GetVerificationRequests(ctx context.Context, filter map[string]interface{}) ([]VerificationRequest, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		out := make([]VerificationRequest, 0)
+		cid, _ := filter["company_id"].(string)
+		status, _ := filter["status"].(string)
+		for _, v := range inMemoryVerificationRequests {
+			if cid != "" && v.CompanyID != cid {
+				continue
+			}
+			if status != "" && v.Status != status {
+				continue
+			}
+			out = append(out, v)
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt > out[j].CreatedAt })
+		return out, nil
+	}
+	if Mode() == ModePostgres {
+		return pgList[VerificationRequest](ctx, "verification_requests", filter, "t.created_at DESC")
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cur, err := DB.Collection("verification_requests").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]VerificationRequest, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateVerificationRequest sets patch's fields on a request. With ifVersion
+// set, it only applies while the request is still at that version.
+func UpdateVerificationRequest(ctx context.Context, id string, patch map[string]interface{}, ifVersion *int64) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		v, ok := inMemoryVerificationRequests[id]
+		if !ok {
+			return ErrNotFound
+		}
+		if err := checkVersion(v.Version, ifVersion); err != nil {
+			return err
+		}
+		applyVerificationPatch(&v, patch)
+		v.Version++
+		if err := persistLocked("verification_requests", id, v); err != nil {
+			return err
+		}
+		inMemoryVerificationRequests[id] = v
+		return nil
+	}
+	if Mode() == ModePostgres {
+		return pgUpdate(ctx, PG, "verification_requests", reflect.TypeOf(VerificationRequest{}), id, patch, ifVersion)
+	}
+	return mongoUpdateVersioned(ctx, "verification_requests", id, bson.M{"$set": patch}, ifVersion)
+}
+
+// applyVerificationPatch sets patch's fields on v in the memory modes
+func applyVerificationPatch(v *VerificationRequest, patch map[string]interface{}) {
+	if s, ok := patch["registration_number"].(string); ok {
+		v.RegistrationNumber = s
+	}
+	if s, ok := patch["tax_id"].(string); ok {
+		v.TaxID = s
+	}
+	if s, ok := patch["address"].(string); ok {
+		v.Address = s
+	}
+	if s, ok := patch["contact_name"].(string); ok {
+		v.ContactName = s
+	}
+	if s, ok := patch["contact_phone"].(string); ok {
+		v.ContactPhone = s
+	}
+	if s, ok := patch["status"].(string); ok {
+		v.Status = s
+	}
+	if s, ok := patch["review_notes"].(string); ok {
+		v.ReviewNotes = s
+	}
+	if s, ok := patch["reviewed_by"].(string); ok {
+		v.ReviewedBy = s
+	}
+	if s, ok := patch["reviewed_at"].(string); ok {
+		v.ReviewedAt = s
+	}
+	if s, ok := patch["updated_at"].(string); ok {
+		v.UpdatedAt = s
+	}
+}
+
+// ReviewVerificationRequest stores an admin's decision on a request, while
+// it is still at version ifVersion, together with the change it makes to
+// the company and that change's events, as one unit of work. company is
+// nil when the decision leaves the company as it is.
+func ReviewVerificationRequest(ctx context.Context, id string, patch map[string]interface{}, ifVersion *int64,
+	companyID string, company *CompanyPatch, events ...Event) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	var coFields map[string]interface{}
+	if company != nil {
+		coFields = patchFields(*company)
+	}
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		v, ok := inMemoryVerificationRequests[id]
+		if !ok {
+			return ErrNotFound
+		}
+		if err := checkVersion(v.Version, ifVersion); err != nil {
+			return err
+		}
+		applyVerificationPatch(&v, patch)
+		v.Version++
+		var batch memBatch
+		batch.put("verification_requests", id, v, func() { inMemoryVerificationRequests[id] = v })
+		if company != nil {
+			co, ok := inMemoryCompanies[companyID]
+			if !ok {
+				return ErrNotFound
+			}
+			if err := checkVersion(co.Version, company.IfVersion); err != nil {
+				return err
+			}
+			applyFields(&co, coFields)
+			co.Version++
+			batch.put("companies", companyID, co, func() { inMemoryCompanies[companyID] = co })
+		}
+		batch.publish(events)
+		return batch.commitLocked()
+	}
+	if Mode() == ModePostgres {
+		return pgReviewVerificationRequest(ctx, id, patch, ifVersion, companyID, coFields, company, events)
+	}
+	return mongoTx(ctx, func(u *mongoUnit) error {
+		err := u.write(func(ctx context.Context) error {
+			return mongoUpdateVersioned(ctx, "verification_requests", id, bson.M{"$set": patch}, ifVersion)
+		}, "verification_requests/"+id)
+		if err != nil || company == nil {
+			return err
+		}
+		err = u.write(func(ctx context.Context) error {
+			return mongoUpdateVersioned(ctx, "companies", companyID, bson.M{"$set": coFields}, company.IfVersion)
+		}, "companies/"+companyID)
+		if err != nil {
+			return err
+		}
+		return u.publish(events)
+	})
+}
+
+// AddVerificationDocument appends a document to a request's document list
+func AddVerificationDocument(ctx context.Context, id string, doc VerificationDocument, updatedAt string) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		v, ok := inMemoryVerificationRequests[id]
+		if !ok {
+			return ErrNotFound
+		}
+		v.Documents = append(v.Documents, doc)
+		v.UpdatedAt = updatedAt
+		v.Version++
+		if err := persistLocked("verification_requests", id, v); err != nil {
+			return err
+		}
+		inMemoryVerificationRequests[id] = v
+		return nil
+	}
+	if Mode() == ModePostgres {
+		return pgAddVerificationDocument(ctx, id, doc, updatedAt)
+	}
+	res, err := DB.Collection("verification_requests").UpdateOne(ctx, bson.M{"_id": id},
+		bson.M{"$push": bson.M{"documents": doc}, "$set": bson.M{"updated_at": updatedAt}, "$inc": bson.M{"version": 1}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
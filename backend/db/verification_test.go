@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReviewVerificationRequestIsOneUnit(t *testing.T) {
+	withMemory(t)
+	ctx := context.Background()
+	co := Company{ID: "c1", Name: "Unit Labs"}
+	if err := CreateCompany(ctx, co); err != nil {
+		t.Fatal(err)
+	}
+	v := VerificationRequest{ID: "v1", CompanyID: co.ID, Status: VerificationPending, Version: FirstVersion}
+	if err := CreateVerificationRequest(ctx, v); err != nil {
+		t.Fatal(err)
+	}
+	approve := map[string]interface{}{"status": VerificationApproved}
+	verified := true
+
+	// a stale company version fails the whole review
+	stale := FirstVersion + 5
+	err := ReviewVerificationRequest(ctx, v.ID, approve, &v.Version, co.ID,
+		&CompanyPatch{Verified: &verified, IfVersion: &stale}, CompanyVerifiedEvent(co))
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("review against a stale company error = %v, want ErrConflict", err)
+	}
+	if got, _ := GetVerificationRequest(ctx, v.ID); got.Status != VerificationPending {
+		t.Fatalf("request status after the failed review = %q, want pending", got.Status)
+	}
+	if due, _ := DueEvents(ctx, "9999", 0); len(due) != 0 {
+		t.Fatalf("%d events published by the failed review", len(due))
+	}
+
+	current := FirstVersion
+	err = ReviewVerificationRequest(ctx, v.ID, approve, &v.Version, co.ID,
+		&CompanyPatch{Verified: &verified, IfVersion: &current}, CompanyVerifiedEvent(co))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotV, _ := GetVerificationRequest(ctx, v.ID)
+	gotCo, _ := GetCompany(ctx, co.ID)
+	if gotV.Status != VerificationApproved || !gotCo.Verified {
+		t.Fatalf("after the review: request %q, company verified %v", gotV.Status, gotCo.Verified)
+	}
+	if due, _ := DueEvents(ctx, "9999", 0); len(due) != 1 || due[0].Type != EventCompanyVerified {
+		t.Fatalf("events after the review = %+v", due)
+	}
+}
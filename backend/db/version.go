@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Records that can change carry a version: 1 when created, bumped by every
+// write to the record. An update may name the version its caller read (the
+// patch's IfVersion); if another write got there first the update fails
+// with a *ConflictError on "version" and nothing is stored. Records written
+// before versions existed read as version 0 until their first update.
+
+// FirstVersion is the version of a newly created record
+const FirstVersion int64 = 1
+
+// staleVersion reports a conditional update that lost to a concurrent write
+func staleVersion() error {
+	return &ConflictError{Field: "version", Message: "the record was changed by another update; reload it and try again"}
+}
+
+// checkVersion tests an update's condition against the stored version
+func checkVersion(current int64, want *int64) error {
+	if want != nil && *want != current {
+		return staleVersion()
+	}
+	return nil
+}
+
+// mongoVersionFilter matches want in a filter; documents stored before
+// versions existed have no field, which only version 0 matches
+func mongoVersionFilter(want int64) interface{} {
+	if want == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return want
+}
+
+// mongoUpdateVersioned applies update to the document of coll with the given
+// id, bumping its version, only while the version is still want (when set)
+func mongoUpdateVersioned(ctx context.Context, coll, id string, update bson.M, want *int64) error {
+	filter := bson.M{"_id": id}
+	if want != nil {
+		filter["version"] = mongoVersionFilter(*want)
+	}
+	update["$inc"] = bson.M{"version": 1}
+	res, err := DB.Collection(coll).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	// a miss is only a conflict if the document still exists
+	if want != nil {
+		if n, err := DB.Collection(coll).CountDocuments(ctx, bson.M{"_id": id}); err == nil && n > 0 {
+			return staleVersion()
+		}
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,375 @@
+package db
+
+import (
+	"context"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	inMemoryWebhooks          map[string]Webhook
+	inMemoryWebhookDeliveries map[string]WebhookDelivery
+)
+
+// WebhookEventTypes are the domain events companies can receive by webhook
+var WebhookEventTypes = []string{EventApplicationStatusChanged, EventInterviewScheduled, EventJobPublished}
+
+// Webhook is a company's subscription to domain events, sent by POST to URL
+// and signed with Secret. EventTypes lists the events it receives.
+type Webhook struct {
+	ID         string   `bson:"_id,omitempty" json:"id"`
+	CompanyID  string   `bson:"company_id" json:"company_id"`
+	URL        string   `bson:"url" json:"url"`
+	EventTypes []string `bson:"event_types" json:"event_types"`
+	Secret     string   `bson:"secret" json:"-"`
+	CreatedBy  string   `bson:"created_by,omitempty" json:"created_by"`
+	CreatedAt  string   `bson:"created_at,omitempty" json:"created_at"`
+}
+
+// Wants reports whether the webhook receives events of eventType
+func (w Webhook) Wants(eventType string) bool {
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent to one webhook: the body as signed and
+// sent, and the outcome of the attempts so far. A replay is a new delivery
+// of the same body, naming the one it repeats in ReplayOf.
+type WebhookDelivery struct {
+	ID             string `bson:"_id,omitempty" json:"id"`
+	WebhookID      string `bson:"webhook_id" json:"webhook_id"`
+	CompanyID      string `bson:"company_id" json:"company_id"`
+	EventID        string `bson:"event_id" json:"event_id"`
+	EventType      string `bson:"event_type" json:"event_type"`
+	Body           string `bson:"body" json:"body"`
+	Status         string `bson:"status" json:"status"`
+	Attempts       int    `bson:"attempts" json:"attempts"`
+	NextAttemptAt  string `bson:"next_attempt_at,omitempty" json:"next_attempt_at"`
+	ResponseStatus int    `bson:"response_status,omitempty" json:"response_status"`
+	LastError      string `bson:"last_error,omitempty" json:"last_error"`
+	ReplayOf       string `bson:"replay_of,omitempty" json:"replay_of"`
+	CreatedAt      string `bson:"created_at" json:"created_at"`
+	DeliveredAt    string `bson:"delivered_at,omitempty" json:"delivered_at"`
+}
+
+// DeliveryFilter narrows GetWebhookDeliveries; empty fields are ignored
+type DeliveryFilter struct {
+	CompanyID string
+	WebhookID string
+	Status    string
+	Limit     int
+}
+
+func (f DeliveryFilter) matches(d WebhookDelivery) bool {
+	return (f.CompanyID == "" || d.CompanyID == f.CompanyID) &&
+		(f.WebhookID == "" || d.WebhookID == f.WebhookID) &&
+		(f.Status == "" || d.Status == f.Status)
+}
+
+// CreateWebhook stores a new webhook
+func CreateWebhook(ctx context.Context, w Webhook) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := inMemoryWebhooks[w.ID]; ok {
+			return &ConflictError{Field: "id", Message: "duplicate webhooks record"}
+		}
+		if err := persistLocked("webhooks", w.ID, w); err != nil {
+			return err
+		}
+		inMemoryWebhooks[w.ID] = w
+		return nil
+	}
+	if Mode() == ModePostgres {
+		return pgInsert(ctx, PG, "webhooks", w)
+	}
+	_, err := DB.Collection("webhooks").InsertOne(ctx, w)
+	return uniqueErr("webhooks", err)
+}
+
+// GetWebhook returns a webhook by id
+func GetWebhook(ctx context.Context, id string) (Webhook, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		if w, ok := inMemoryWebhooks[id]; ok {
+			return w, nil
+		}
+		return Webhook{}, ErrNotFound
+	}
+	if Mode() == ModePostgres {
+		return pgGet[Webhook](ctx, "webhooks", id)
+	}
+	var w Webhook
+	err := DB.Collection("webhooks").FindOne(ctx, bson.M{"_id": id}).Decode(&w)
+	return w, notFound(err)
+}
+
+// GetWebhooks lists a company's webhooks, oldest first
+func GetWebhooks(ctx context.Context, companyID string) ([]Webhook, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		out := make([]Webhook, 0)
+		for _, w := range inMemoryWebhooks {
+			if w.CompanyID == companyID {
+				out = append(out, w)
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt < out[j].CreatedAt })
+		return out, nil
+	}
+	if Mode() == ModePostgres {
+		return pgList[Webhook](ctx, "webhooks", map[string]interface{}{"company_id": companyID}, "t.created_at")
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cur, err := DB.Collection("webhooks").Find(ctx, bson.M{"company_id": companyID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Webhook, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteWebhook removes a company's webhook together with its delivery log
+func DeleteWebhook(ctx context.Context, companyID, id string) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		if w, ok := inMemoryWebhooks[id]; !ok || w.CompanyID != companyID {
+			return ErrNotFound
+		}
+		var batch memBatch
+		for did, d := range inMemoryWebhookDeliveries {
+			if d.WebhookID == id {
+				batch.remove("webhook_deliveries", did, func() { delete(inMemoryWebhookDeliveries, did) })
+			}
+		}
+		batch.remove("webhooks", id, func() { delete(inMemoryWebhooks, id) })
+		return batch.commitLocked()
+	}
+	if Mode() == ModePostgres {
+		return pgDeleteWebhook(ctx, companyID, id)
+	}
+	return mongoTx(ctx, func(u *mongoUnit) error {
+		w, err := GetWebhook(u.ctx, id)
+		if err != nil {
+			return err
+		}
+		if w.CompanyID != companyID {
+			return ErrNotFound
+		}
+		// the log goes first, so a failure part way never leaves deliveries
+		// of a webhook that no longer exists
+		err = u.write(func(ctx context.Context) error {
+			_, err := DB.Collection("webhook_deliveries").DeleteMany(ctx, bson.M{"webhook_id": id})
+			return err
+		}, "webhook_deliveries/webhook_id="+id)
+		if err != nil {
+			return err
+		}
+		return u.write(func(ctx context.Context) error {
+			res, err := DB.Collection("webhooks").DeleteOne(ctx, bson.M{"_id": id, "company_id": companyID})
+			if err != nil {
+				return err
+			}
+			if res.DeletedCount == 0 {
+				return ErrNotFound
+			}
+			return nil
+		}, "webhooks/"+id)
+	})
+}
+
+// CreateWebhookDelivery stores a delivery. Its id is unique, so a delivery
+// created twice for the same event fails with a *ConflictError.
+func CreateWebhookDelivery(ctx context.Context, d WebhookDelivery) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := inMemoryWebhookDeliveries[d.ID]; ok {
+			return &ConflictError{Field: "id", Message: "duplicate webhook_deliveries record"}
+		}
+		if err := persistLocked("webhook_deliveries", d.ID, d); err != nil {
+			return err
+		}
+		inMemoryWebhookDeliveries[d.ID] = d
+		return nil
+	}
+	if Mode() == ModePostgres {
+		return pgInsert(ctx, PG, "webhook_deliveries", d)
+	}
+	_, err := DB.Collection("webhook_deliveries").InsertOne(ctx, d)
+	return uniqueErr("webhook_deliveries", err)
+}
+
+// GetWebhookDelivery returns a delivery by id
+func GetWebhookDelivery(ctx context.Context, id string) (WebhookDelivery, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		if d, ok := inMemoryWebhookDeliveries[id]; ok {
+			return d, nil
+		}
+		return WebhookDelivery{}, ErrNotFound
+	}
+	if Mode() == ModePostgres {
+		return pgGet[WebhookDelivery](ctx, "webhook_deliveries", id)
+	}
+	var d WebhookDelivery
+	err := DB.Collection("webhook_deliveries").FindOne(ctx, bson.M{"_id": id}).Decode(&d)
+	return d, notFound(err)
+}
+
+// GetWebhookDeliveries returns matching deliveries, newest first
+func GetWebhookDeliveries(ctx context.Context, f DeliveryFilter) ([]WebhookDelivery, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		out := make([]WebhookDelivery, 0)
+		for _, d := range inMemoryWebhookDeliveries {
+			if f.matches(d) {
+				out = append(out, d)
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt > out[j].CreatedAt })
+		if f.Limit > 0 && len(out) > f.Limit {
+			out = out[:f.Limit]
+		}
+		return out, nil
+	}
+	filter := map[string]interface{}{}
+	if f.CompanyID != "" {
+		filter["company_id"] = f.CompanyID
+	}
+	if f.WebhookID != "" {
+		filter["webhook_id"] = f.WebhookID
+	}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+	if Mode() == ModePostgres {
+		return pgListWebhookDeliveries(ctx, filter, f.Limit)
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if f.Limit > 0 {
+		opts.SetLimit(int64(f.Limit))
+	}
+	cur, err := DB.Collection("webhook_deliveries").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]WebhookDelivery, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DueWebhookDeliveries lists up to limit pending deliveries whose next
+// attempt is due at the RFC3339 timestamp now, oldest first
+func DueWebhookDeliveries(ctx context.Context, now string, limit int) ([]WebhookDelivery, error) {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.RLock()
+		defer mu.RUnlock()
+		out := make([]WebhookDelivery, 0)
+		for _, d := range inMemoryWebhookDeliveries {
+			if d.Status == DeliveryPending && d.NextAttemptAt <= now {
+				out = append(out, d)
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt < out[j].CreatedAt })
+		if limit > 0 && len(out) > limit {
+			out = out[:limit]
+		}
+		return out, nil
+	}
+	if Mode() == ModePostgres {
+		return pgSelect[WebhookDelivery](ctx, PG, "webhook_deliveries",
+			"SELECT %s FROM webhook_deliveries t WHERE t.status = $1 AND t.next_attempt_at <= $2 ORDER BY t.created_at LIMIT $3",
+			DeliveryPending, now, limit)
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	cur, err := DB.Collection("webhook_deliveries").Find(ctx,
+		bson.M{"status": DeliveryPending, "next_attempt_at": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]WebhookDelivery, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SaveWebhookDeliveryAttempt stores the outcome of an attempt on d
+func SaveWebhookDeliveryAttempt(ctx context.Context, d WebhookDelivery) error {
+	ctx, cancel := opCtx(ctx)
+	defer cancel()
+	if UseInMemory() {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := inMemoryWebhookDeliveries[d.ID]; !ok {
+			// the webhook was deleted meanwhile
+			return ErrNotFound
+		}
+		if err := persistLocked("webhook_deliveries", d.ID, d); err != nil {
+			return err
+		}
+		inMemoryWebhookDeliveries[d.ID] = d
+		return nil
+	}
+	if Mode() == ModePostgres {
+		return pgSaveWebhookDeliveryAttempt(ctx, d)
+	}
+	res, err := DB.Collection("webhook_deliveries").UpdateOne(ctx, bson.M{"_id": d.ID}, bson.M{"$set": bson.M{
+		"status":          d.Status,
+		"attempts":        d.Attempts,
+		"next_attempt_at": d.NextAttemptAt,
+		"response_status": d.ResponseStatus,
+		"last_error":      d.LastError,
+		"delivered_at":    d.DeliveredAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Package events delivers the domain events stored in the outbox to the
+// subscribers registered for them. Delivery is at least once: an event stays
+// pending until every subscriber that wants it has accepted it, and a
+// subscriber that fails is retried with exponential backoff, so subscribers
+// must tolerate seeing an event again.
+package events
+
+import (
+	"backend/db"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// batchSize bounds the events one dispatch run delivers
+	batchSize = 100
+	// maxAttempts is how often an event is tried before it is marked failed
+	maxAttempts = 8
+	// firstRetry is the delay before the first retry; each later one doubles
+	// it, up to maxRetry
+	firstRetry = 30 * time.Second
+	maxRetry   = time.Hour
+)
+
+// Subscriber receives the events of the listed types, or all events when
+// Types is empty. Name is recorded on each event it accepts, so it must stay
+// stable across releases.
+type Subscriber struct {
+	Name   string
+	Types  []string
+	Handle func(ctx context.Context, e db.Event) error
+}
+
+func (s Subscriber) wants(e db.Event) bool {
+	return len(s.Types) == 0 || slices.Contains(s.Types, e.Type)
+}
+
+// Dispatcher delivers due events to its subscribers
+type Dispatcher struct {
+	subscribers []Subscriber
+}
+
+// NewDispatcher returns a dispatcher for subscribers
+func NewDispatcher(subscribers ...Subscriber) *Dispatcher {
+	return &Dispatcher{subscribers: subscribers}
+}
+
+// Run is the scheduler task that delivers the events due at now
+func (d *Dispatcher) Run(ctx context.Context, now time.Time) error {
+	due, err := db.DueEvents(ctx, now.UTC().Format(time.RFC3339), batchSize)
+	if err != nil {
+		return err
+	}
+	for _, e := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		d.deliver(ctx, &e, now)
+		if err := db.SaveEventDelivery(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver offers e to every subscriber that wants it and has not accepted
+// it yet, then moves it to dispatched, schedules a retry or gives up
+func (d *Dispatcher) deliver(ctx context.Context, e *db.Event, now time.Time) {
+	var failures []string
+	for _, s := range d.subscribers {
+		if !s.wants(*e) || slices.Contains(e.Delivered, s.Name) {
+			continue
+		}
+		if err := handle(ctx, s, *e); err != nil {
+			slog.WarnContext(ctx, "event delivery failed", "event", e.ID, "type", e.Type, "subscriber", s.Name, "error", err)
+			failures = append(failures, s.Name+": "+err.Error())
+			continue
+		}
+		e.Delivered = append(e.Delivered, s.Name)
+	}
+	e.Attempts++
+	ts := now.UTC().Format(time.RFC3339)
+	if len(failures) == 0 {
+		e.Status, e.DispatchedAt, e.LastError = db.EventDispatched, ts, ""
+		return
+	}
+	e.LastError = strings.Join(failures, "; ")
+	if e.Attempts >= maxAttempts {
+		e.Status = db.EventFailed
+		slog.ErrorContext(ctx, "event delivery abandoned", "event", e.ID, "type", e.Type, "attempts", e.Attempts, "error", e.LastError)
+		return
+	}
+	e.NextAttemptAt = now.Add(Backoff(e.Attempts)).UTC().Format(time.RFC3339)
+}
+
+// handle runs one subscriber, turning a panic into a failed delivery
+func handle(ctx context.Context, s Subscriber, e db.Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return s.Handle(ctx, e)
+}
+
+// Backoff is the delay before retrying after the given number of failed
+// attempts
+func Backoff(attempts int) time.Duration {
+	d := firstRetry
+	for i := 1; i < attempts && d < maxRetry; i++ {
+		d *= 2
+	}
+	return min(d, maxRetry)
+}
+
+// RetentionTask returns a scheduler task body that purges dispatched events
+// older than retention
+func RetentionTask(retention time.Duration) func(ctx context.Context, now time.Time) error {
+	return func(ctx context.Context, now time.Time) error {
+		cutoff := now.UTC().Add(-retention).Format(time.RFC3339)
+		n, err := db.PurgeEvents(ctx, cutoff)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			slog.InfoContext(ctx, "outbox retention purged events", "count", n, "cutoff", cutoff)
+		}
+		return nil
+	}
+}
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"backend/db"
-	"context"
 	"net/http"
 	"time"
 
@@ -14,66 +13,56 @@ func GetApplications(c *gin.Context) {
 	// Support listing applications with optional filters: student_id or company_id
 	student := c.Query("student_id")
 	company := c.Query("company_id")
-	// If in-memory, use db helper for simple listing. Company-aggregations are only supported against Mongo.
-	if db.UseInMemory {
-		filter := map[string]interface{}{}
-		if student != "" {
-			filter["student_id"] = student
-		}
-		out, err := db.GetApplications(filter)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "list failed"})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"data": out})
+	// a company's applicant list is visible to its team members only
+	if company != "" && !requireCompanyRole(c, company, companyAnyRole...) {
 		return
 	}
-
-	col := db.DB.Collection("applications")
-
-	// If company filter is provided, use aggregation to join job_postings and student_profiles
+	// a company's view joins each application with its job and student
 	if company != "" {
-		pipeline := mongoPipelineForCompanyApplications(company)
-		cur, err := col.Aggregate(context.Background(), pipeline)
+		out, err := db.CompanyApplications(c, company)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "aggregate failed"})
-			return
-		}
-		var out []map[string]interface{}
-		if err := cur.All(context.Background(), &out); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "decode failed"})
+			failErr(c, err, "list failed")
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"data": out})
 		return
 	}
 
-	// Default simple listing (optionally filtered by student_id)
 	filter := map[string]interface{}{}
 	if student != "" {
 		filter["student_id"] = student
 	}
-	cur, err := col.Find(context.Background(), filter)
+	out, err := db.GetApplications(c, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "list failed"})
-		return
-	}
-	var out []db.Application
-	if err := cur.All(context.Background(), &out); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "decode failed"})
+		failErr(c, err, "list failed")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": out})
 }
 
+// CreateApplication applies the caller's student profile to a job; admins
+// may apply for the student named in student_id
 func CreateApplication(c *gin.Context) {
 	var a db.Application
 	if err := c.BindJSON(&a); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid"})
+		fail(c, http.StatusBadRequest, "invalid")
 		return
 	}
-	if a.ID == "" {
-		a.ID = uuid.New().String()
+	a.ID = uuid.New().String()
+	studentID, ok := studentIDFor(c, a.StudentID)
+	if !ok {
+		return
+	}
+	a.StudentID = studentID
+	// only published jobs accept applications
+	job, err := db.GetJobPosting(c, a.JobID)
+	if err != nil || job.DeletedAt != "" {
+		fail(c, http.StatusNotFound, "job posting not found")
+		return
+	}
+	if job.Status != jobActive {
+		fail(c, http.StatusConflict, "job posting is not accepting applications")
+		return
 	}
 	// set timestamps and sensible defaults
 	if a.AppliedAt == "" {
@@ -82,69 +71,114 @@ func CreateApplication(c *gin.Context) {
 	if a.CreatedAt == "" {
 		a.CreatedAt = a.AppliedAt
 	}
-	if a.Status == "" {
-		a.Status = "applied"
+	// every application starts as applied; later statuses are the
+	// recruiter's to set through updates, which publish their events
+	a.Status = "applied"
+	a.Version = db.FirstVersion
+	if err := db.CreateApplication(c, a); err != nil {
+		failErr(c, err, "insert failed")
+		return
 	}
-	if err := db.CreateApplication(a); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "insert failed"})
+	applicationsCreated.Inc()
+	respondCreated(c, a, a.Version)
+}
+
+// GetApplication returns one application
+func GetApplication(c *gin.Context) {
+	a, err := db.GetApplication(c, c.Param("id"))
+	if err != nil {
+		failErr(c, err, "lookup failed")
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"data": a})
+	respondResource(c, a, a.Version)
 }
 
+// applicationPatchIgnored are keys an update may echo but never changes
+var applicationPatchIgnored = []string{"job_id", "student_id"}
+
 func UpdateApplication(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id required"})
+		fail(c, http.StatusBadRequest, "id required")
 		return
 	}
-	var patch map[string]interface{}
-	if err := c.BindJSON(&patch); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid"})
+	body, err := c.GetRawData()
+	if err != nil {
+		fail(c, http.StatusBadRequest, "invalid")
+		return
+	}
+	var patch db.ApplicationPatch
+	if !decodePatch(c, body, &patch, applicationPatchIgnored...) {
 		return
 	}
-	// set updated_at if not provided
-	if _, ok := patch["updated_at"]; !ok {
-		patch["updated_at"] = time.Now().Format(time.RFC3339)
+	current, err := db.GetApplication(c, id)
+	if err != nil {
+		fail(c, http.StatusNotFound, "not found")
+		return
 	}
-	if err := db.UpdateApplication(id, patch); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+	if !canUpdateApplication(c, current, patch) {
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": patch})
+	now := time.Now().Format(time.RFC3339)
+	patch.UpdatedAt = &now
+	before := current
+	if !expectVersion(c, before.Version, &patch.IfVersion) {
+		return
+	}
+	var events []db.Event
+	if patch.Status != nil && *patch.Status != before.Status {
+		job, err := db.GetJobPosting(c, before.JobID)
+		if err != nil {
+			failErr(c, err, "lookup failed")
+			return
+		}
+		next := before
+		next.Status = *patch.Status
+		events = append(events, db.ApplicationStatusEvent(next, job.CompanyID, before.Status))
+	}
+	if err := db.UpdateApplication(c, id, patch, events...); err != nil {
+		failErr(c, err, "update failed")
+		return
+	}
+	after, _ := db.GetApplication(c, id)
+	if before.Status != after.Status {
+		if after.Status == "offer_accepted" {
+			offersAccepted.Inc()
+		}
+		recordAudit(c, "application.status_change", "application", id, before, after)
+	} else {
+		recordAudit(c, "application.update", "application", id, before, after)
+	}
+	respondUpdated(c, after, patch, after.Version)
+}
+
+// offerResponses are the only status changes a student may make to their own application
+var offerResponses = map[string]bool{"offer_accepted": true, "offer_rejected": true}
+
+// isOfferResponse reports whether a patch only answers an offer
+func isOfferResponse(patch db.ApplicationPatch) bool {
+	return patch.Status != nil && offerResponses[*patch.Status] &&
+		patch.EligibilityStatus == nil && patch.EligibilityNotes == nil
 }
 
-// Helper: build aggregation pipeline to join applications -> job_postings -> student_profiles -> profiles
-func mongoPipelineForCompanyApplications(company string) []interface{} {
-	return []interface{}{
-		// lookup job_postings
-		map[string]interface{}{"$lookup": map[string]interface{}{
-			"from":         "job_postings",
-			"localField":   "job_id",
-			"foreignField": "_id",
-			"as":           "job_postings",
-		}},
-		// unwind job_postings
-		map[string]interface{}{"$unwind": map[string]interface{}{"path": "$job_postings", "preserveNullAndEmptyArrays": true}},
-		// match company
-		map[string]interface{}{"$match": map[string]interface{}{"job_postings.company_id": company}},
-		// lookup student_profiles
-		map[string]interface{}{"$lookup": map[string]interface{}{
-			"from":         "student_profiles",
-			"localField":   "student_id",
-			"foreignField": "_id",
-			"as":           "student_profiles",
-		}},
-		map[string]interface{}{"$unwind": map[string]interface{}{"path": "$student_profiles", "preserveNullAndEmptyArrays": true}},
-		// lookup profiles for student_profiles.user_id
-		map[string]interface{}{"$lookup": map[string]interface{}{
-			"from":         "profiles",
-			"localField":   "student_profiles.user_id",
-			"foreignField": "_id",
-			"as":           "student_profiles.profiles",
-		}},
-		map[string]interface{}{"$unwind": map[string]interface{}{"path": "$student_profiles.profiles", "preserveNullAndEmptyArrays": true}},
-		// sort by created_at desc
-		map[string]interface{}{"$sort": map[string]interface{}{"created_at": -1}},
+// canUpdateApplication allows the applying student to answer an offer and
+// otherwise requires an owner or recruiter of the job's company. It writes
+// the error response itself.
+func canUpdateApplication(c *gin.Context, a db.Application, patch db.ApplicationPatch) bool {
+	uid := c.GetString("userID")
+	if uid == "" {
+		fail(c, http.StatusUnauthorized, "Authorization required")
+		return false
+	}
+	if isOfferResponse(patch) {
+		if sp, err := db.GetStudentProfiles(c, map[string]interface{}{"_id": a.StudentID}); err == nil && len(sp) > 0 && sp[0].UserID == uid {
+			return true
+		}
+	}
+	job, err := db.GetJobPosting(c, a.JobID)
+	if err != nil {
+		fail(c, http.StatusForbidden, "not allowed for this application")
+		return false
 	}
+	return requireCompanyRole(c, job.CompanyID, companyManagers...)
 }
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/middleware"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetAuditLog lists audit entries for admins. Supported filters: actor_id,
+// entity, entity_id, action, from, to (RFC3339) and limit (default 100).
+func GetAuditLog(c *gin.Context) {
+	f := db.AuditFilter{
+		ActorID:  c.Query("actor_id"),
+		Entity:   c.Query("entity"),
+		EntityID: c.Query("entity_id"),
+		Action:   c.Query("action"),
+		Since:    c.Query("from"),
+		Until:    c.Query("to"),
+		Limit:    100,
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			fail(c, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		f.Limit = n
+	}
+	out, err := db.ListAuditEntries(c, f)
+	if err != nil {
+		failErr(c, err, "list failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out})
+}
+
+// recordAudit appends an audit entry for the current request. Failures are
+// logged rather than returned so a broken audit store never blocks the action
+// that has already been applied; for the same reason the entry is still
+// written if the client has gone away.
+func recordAudit(c *gin.Context, action, entity, entityID string, before, after interface{}) {
+	actorID, _ := c.Get("userID")
+	actorRole, _ := c.Get("role")
+	e := db.AuditEntry{
+		ID:        uuid.New().String(),
+		Action:    action,
+		Entity:    entity,
+		EntityID:  entityID,
+		Diff:      diffFields(before, after),
+		IP:        c.ClientIP(),
+		RequestID: c.GetString(middleware.RequestIDKey),
+	}
+	e.ActorID, _ = actorID.(string)
+	e.ActorRole, _ = actorRole.(string)
+	if err := db.CreateAuditEntry(context.WithoutCancel(c), e); err != nil {
+		slog.ErrorContext(c, "failed to record audit entry", "action", action, "entity", entity, "entity_id", entityID, "error", err)
+	}
+}
+
+// recordSystemAudit records an action taken by a background task rather than
+// an HTTP caller.
+func recordSystemAudit(ctx context.Context, action, entity, entityID string, before, after interface{}) {
+	e := db.AuditEntry{
+		ID:        uuid.New().String(),
+		ActorID:   "system",
+		ActorRole: "system",
+		Action:    action,
+		Entity:    entity,
+		EntityID:  entityID,
+		Diff:      diffFields(before, after),
+	}
+	if err := db.CreateAuditEntry(ctx, e); err != nil {
+		slog.ErrorContext(ctx, "failed to record audit entry", "action", action, "entity", entity, "entity_id", entityID, "error", err)
+	}
+}
+
+// AuditRetentionTask returns a scheduler task body that purges audit entries
+// older than retention. A zero retention keeps the trail forever.
+func AuditRetentionTask(retention time.Duration) func(ctx context.Context, now time.Time) error {
+	return func(ctx context.Context, now time.Time) error {
+		if retention <= 0 {
+			return nil
+		}
+		cutoff := now.UTC().Add(-retention).Format(time.RFC3339)
+		n, err := db.PurgeAuditEntries(ctx, cutoff)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			slog.InfoContext(ctx, "audit retention purged entries", "count", n, "cutoff", cutoff)
+		}
+		return nil
+	}
+}
+
+// bookkeeping fields change on every write and only add noise to the trail
+var bookkeeping = map[string]bool{"updated_at": true, "version": true}
+
+// diffFields compares the JSON representation of two documents and returns
+// the fields whose values differ, leaving out the bookkeeping ones.
+func diffFields(before, after interface{}) map[string]db.AuditChange {
+	b := toFieldMap(before)
+	a := toFieldMap(after)
+	out := map[string]db.AuditChange{}
+	for k, av := range a {
+		if bookkeeping[k] {
+			continue
+		}
+		if bv, ok := b[k]; !ok || !reflect.DeepEqual(bv, av) {
+			out[k] = db.AuditChange{Before: b[k], After: av}
+		}
+	}
+	for k, bv := range b {
+		if _, ok := a[k]; !ok && !bookkeeping[k] {
+			out[k] = db.AuditChange{Before: bv, After: nil}
+		}
+	}
+	return out
+}
+
+func toFieldMap(v interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	if v == nil {
+		return out
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return out
+	}
+	_ = json.Unmarshal(raw, &out)
+	return out
+}
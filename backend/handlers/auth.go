@@ -3,10 +3,7 @@ package handlers
 import (
 	"backend/db"
 	"backend/middleware"
-	"context"
-	"log"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,22 +14,27 @@ type googleAuthRequest struct {
 	IDToken string `json:"idToken"`
 }
 
+// tokenResponse carries the API's own JWT
+type tokenResponse struct {
+	Token string `json:"token"`
+}
+
 func GoogleAuth(c *gin.Context) {
 	var req googleAuthRequest
 	if err := c.BindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		fail(c, http.StatusBadRequest, "invalid request")
 		return
 	}
 
 	// verify firebase id token
-	tok, err := middleware.FirebaseAuth.VerifyIDToken(context.Background(), req.IDToken)
+	tok, err := middleware.FirebaseAuth.VerifyIDToken(c, req.IDToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Firebase token"})
+		fail(c, http.StatusUnauthorized, "Invalid Firebase token")
 		return
 	}
 
 	// ensure profile exists
-	_, err = db.GetProfile(tok.UID)
+	_, err = db.GetProfile(c, tok.UID)
 	if err != nil {
 		// create profile
 		newProfile := db.Profile{
@@ -53,29 +55,27 @@ func GoogleAuth(c *gin.Context) {
 			CreatedAt: time.Now().Format(time.RFC3339),
 			UpdatedAt: time.Now().Format(time.RFC3339),
 		}
-		if err := db.CreateProfile(newProfile); err != nil {
-			log.Println("failed to create profile:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create profile"})
+		if err := db.CreateProfile(c, newProfile); err != nil {
+			failErr(c, err, "Failed to create profile")
 			return
 		}
 	}
 
 	// create custom JWT
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your-secret-key"
-	}
-	claims := jwt.MapClaims{
+	signed, err := middleware.SignToken(jwt.MapClaims{
 		"user_id": tok.UID,
 		"email":   tok.Claims["email"],
 		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString([]byte(secret))
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign token"})
+		failErr(c, err, "Failed to sign token")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": signed})
+	// /api sends the token bare; v1 wraps it like every other payload
+	if isV1(c) {
+		c.JSON(http.StatusOK, gin.H{"data": tokenResponse{Token: signed}})
+		return
+	}
+	c.JSON(http.StatusOK, tokenResponse{Token: signed})
 }
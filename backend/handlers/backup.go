@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"backend/backup"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBackupSize caps an uploaded backup archive
+const maxBackupSize = 2 << 30
+
+// DownloadBackup streams a backup archive of every collection and blob
+func DownloadBackup(c *gin.Context) {
+	noDeadlines(c)
+	name := fmt.Sprintf("placement-backup-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Header("Content-Type", "application/gzip")
+	c.Status(http.StatusOK)
+	m, err := backup.Write(c, c.Writer)
+	if err != nil {
+		// headers are already sent; the client sees a truncated download
+		c.Error(err)
+		return
+	}
+	recordAudit(c, "store.backup", "store", m.Source, nil, m)
+}
+
+// RestoreBackup replaces all data with an uploaded backup archive
+// (multipart "file"). Nothing changes unless the archive passes validation.
+func RestoreBackup(c *gin.Context) {
+	noDeadlines(c)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBackupSize)
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		fail(c, http.StatusBadRequest, "file required")
+		return
+	}
+	defer file.Close()
+	m, err := backup.Read(c, file)
+	if err != nil {
+		failErr(c, err, "restore failed")
+		return
+	}
+	recordAudit(c, "store.backup_restore", "store", m.Source, nil, m)
+	c.JSON(http.StatusOK, gin.H{"data": m})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"backend/db"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -9,52 +10,121 @@ import (
 	"github.com/google/uuid"
 )
 
+// GetCompanies lists companies. ?recruiter_id= (or ?member_id=) returns every
+// company the user belongs to, not just those they created.
 func GetCompanies(c *gin.Context) {
-	recruiter := c.Query("recruiter_id")
+	member := c.Query("member_id")
+	if member == "" {
+		member = c.Query("recruiter_id")
+	}
 	filter := map[string]interface{}{}
-	if recruiter != "" {
-		filter["recruiter_id"] = recruiter
+	if member != "" {
+		filter["member_id"] = member
 	}
-	out, err := db.GetCompanies(filter)
+	out, err := db.GetCompanies(c, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "list failed"})
+		failErr(c, err, "list failed")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": out})
 }
 
+// CreateCompany registers a company owned by the caller; only admins may
+// name another recruiter as its owner
 func CreateCompany(c *gin.Context) {
 	var co db.Company
 	if err := c.BindJSON(&co); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid"})
+		fail(c, http.StatusBadRequest, "invalid")
 		return
 	}
-	if co.ID == "" {
-		co.ID = uuid.New().String()
+	co.ID = uuid.New().String()
+	if c.GetString("role") != "admin" || co.RecruiterID == "" {
+		co.RecruiterID = c.GetString("userID")
+	}
+	// new companies start unverified; admins verify through the review workflow
+	if c.GetString("role") != "admin" {
+		co.Verified = false
+		co.Approved = false
 	}
 	co.CreatedAt = time.Now().Format(time.RFC3339)
-	if err := db.CreateCompany(co); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "insert failed"})
+	co.Version = db.FirstVersion
+	if err := db.CreateCompany(c, co); err != nil {
+		failErr(c, err, "insert failed")
+		return
+	}
+	// the creating recruiter becomes the company's first owner
+	if co.RecruiterID != "" {
+		owner := db.CompanyMember{
+			ID:        uuid.New().String(),
+			CompanyID: co.ID,
+			UserID:    co.RecruiterID,
+			Role:      db.MemberOwner,
+			CreatedAt: co.CreatedAt,
+		}
+		if err := db.AddCompanyMember(c, owner); err != nil {
+			slog.ErrorContext(c, "failed to add company owner", "company_id", co.ID, "error", err)
+		}
+	}
+	respondCreated(c, co, co.Version)
+}
+
+// GetCompany returns one company
+func GetCompany(c *gin.Context) {
+	co, err := db.GetCompany(c, c.Param("id"))
+	if err != nil {
+		failErr(c, err, "lookup failed")
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"data": co})
+	respondResource(c, co, co.Version)
 }
 
+// companyPatchIgnored are keys an update may echo but never changes
+var companyPatchIgnored = []string{"recruiter_id"}
+
 func UpdateCompany(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+		fail(c, http.StatusBadRequest, "missing id")
+		return
+	}
+	if !requireCompanyRole(c, id, companyManagers...) {
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		fail(c, http.StatusBadRequest, "invalid")
+		return
+	}
+	var patch db.CompanyPatch
+	// the UI echoes recruiter_id back; ownership changes go through team membership
+	if !decodePatch(c, body, &patch, companyPatchIgnored...) {
 		return
 	}
-	var patch map[string]interface{}
-	if err := c.BindJSON(&patch); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid"})
+	verifying := patch.Verified != nil
+	// verification normally goes through the review workflow; only admins may flip it directly
+	if verifying && c.GetString("role") != "admin" {
+		fail(c, http.StatusForbidden, "only admins can change verification status")
 		return
 	}
-	patch["updated_at"] = time.Now().Format(time.RFC3339)
-	if err := db.UpdateCompany(id, patch); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+	now := time.Now().Format(time.RFC3339)
+	patch.UpdatedAt = &now
+	before, _ := db.GetCompany(c, id)
+	if !expectVersion(c, before.Version, &patch.IfVersion) {
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": patch})
+	var events []db.Event
+	if verifying && *patch.Verified && !before.Verified {
+		events = append(events, db.CompanyVerifiedEvent(before))
+	}
+	if err := db.UpdateCompany(c, id, patch, events...); err != nil {
+		failErr(c, err, "update failed")
+		return
+	}
+	action := "company.update"
+	if verifying {
+		action = "company.verify"
+	}
+	after, _ := db.GetCompany(c, id)
+	recordAudit(c, action, "company", id, before, after)
+	respondUpdated(c, after, patch, after.Version)
 }
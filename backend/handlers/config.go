@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"backend/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// configResponse is the data of GET /api/admin/config
+type configResponse struct {
+	Env      string         `json:"env"`
+	File     string         `json:"file"`
+	Settings []config.Entry `json:"settings"`
+}
+
+// GetConfig returns the effective configuration with the source of every
+// value. Secrets and connection string passwords are redacted.
+func GetConfig(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": configResponse{Env: cfg.Env, File: cfg.File, Settings: cfg.Effective()}})
+	}
+}
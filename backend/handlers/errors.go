@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/middleware"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fail writes a standard error response with the default code for status
+func fail(c *gin.Context, status int, message string) {
+	middleware.AbortWithError(c, status, "", message, nil)
+}
+
+// failErr maps a storage error to its HTTP status. Unrecognised errors are
+// logged with the request id and reported as a 500 carrying fallback, so
+// driver messages never reach clients.
+func failErr(c *gin.Context, err error, fallback string) {
+	var verr *db.ValidationError
+	var cerr *db.ConflictError
+	var perr *db.PartialWriteError
+	switch {
+	case errors.As(err, &perr):
+		// checked first: the cause may look like a client error, but part of
+		// the write is stored and the client has to know what
+		slog.ErrorContext(c, fallback, "error", perr.Err, "written", perr.Written)
+		middleware.AbortWithError(c, http.StatusInternalServerError, middleware.CodeInternal,
+			fallback+": the change was only partly stored", gin.H{"written": perr.Written})
+	case errors.As(err, &verr):
+		middleware.AbortWithError(c, http.StatusUnprocessableEntity, middleware.CodeValidation, "validation failed", verr.Fields)
+	case errors.As(err, &cerr):
+		middleware.AbortWithError(c, http.StatusConflict, middleware.CodeConflict, cerr.Message, gin.H{"field": cerr.Field})
+	case errors.Is(err, db.ErrNotFound):
+		middleware.AbortWithError(c, http.StatusNotFound, middleware.CodeNotFound, "not found", nil)
+	case errors.Is(err, db.ErrConflict):
+		middleware.AbortWithError(c, http.StatusConflict, middleware.CodeConflict, err.Error(), nil)
+	case errors.Is(err, db.ErrForbidden):
+		middleware.AbortWithError(c, http.StatusForbidden, middleware.CodeForbidden, "forbidden", nil)
+	case errors.Is(err, db.ErrUnavailable):
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, middleware.CodeUnavailable, "database unavailable, try again later", nil)
+	default:
+		slog.ErrorContext(c, fallback, "error", err)
+		middleware.AbortWithError(c, http.StatusInternalServerError, middleware.CodeInternal, fallback, nil)
+	}
+}
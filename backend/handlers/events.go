@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/events"
+	"backend/webhooks"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventSubscribers are the subscribers of the domain events published by
+// the handlers and the scheduler
+func EventSubscribers() []events.Subscriber {
+	return []events.Subscriber{
+		{Name: "notifications", Types: []string{db.EventApplicationStatusChanged}, Handle: notifyApplicationStatus},
+		{Name: "audit", Handle: auditEvent},
+		{Name: "webhooks", Types: db.WebhookEventTypes, Handle: queueWebhookDeliveries},
+	}
+}
+
+// eventRecordID derives the id of the record a subscriber stores for an
+// event, so storing it again on a redelivery is a conflict, not a duplicate
+func eventRecordID(e db.Event, subscriber string) string {
+	ns, err := uuid.Parse(e.ID)
+	if err != nil {
+		ns = uuid.NameSpaceOID
+	}
+	return uuid.NewSHA1(ns, []byte(e.ID+"/"+subscriber)).String()
+}
+
+// statusNotice is the notification a student gets when their application
+// moves to a status; message is a format taking the job title
+type statusNotice struct {
+	title, message, kind string
+}
+
+// applicationStatusNotices lists the statuses students are told about.
+// Offer responses are the student's own doing and send nothing.
+var applicationStatusNotices = map[string]statusNotice{
+	"shortlisted":         {"Application shortlisted", "You have been shortlisted for \"%s\".", "shortlist"},
+	"interview_scheduled": {"Interview scheduled", "An interview has been scheduled for your application to \"%s\".", "interview"},
+	"selected":            {"You have been selected", "You have been selected for \"%s\".", "offer"},
+	"rejected":            {"Application update", "Your application for \"%s\" was not taken forward.", "general"},
+	"closed":              {"Job posting withdrawn", "The job \"%s\" you applied to has been withdrawn and your application was closed.", "general"},
+}
+
+// notifyApplicationStatus tells the student about their application's new
+// status
+func notifyApplicationStatus(ctx context.Context, e db.Event) error {
+	to, _ := e.Data["to"].(string)
+	notice, ok := applicationStatusNotices[to]
+	if !ok {
+		return nil
+	}
+	studentID, _ := e.Data["student_id"].(string)
+	jobID, _ := e.Data["job_id"].(string)
+	students, err := db.GetStudentProfiles(ctx, map[string]interface{}{"_id": studentID})
+	if err != nil {
+		return err
+	}
+	job, err := db.GetJobPosting(ctx, jobID)
+	if errors.Is(err, db.ErrNotFound) || len(students) == 0 {
+		// the student or job has been removed since
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	n := db.Notification{
+		ID:        eventRecordID(e, "notifications"),
+		UserID:    students[0].UserID,
+		Title:     notice.title,
+		Message:   fmt.Sprintf(notice.message, job.Title),
+		Type:      notice.kind,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	if err := db.CreateNotification(ctx, n); err != nil && !errors.Is(err, db.ErrConflict) {
+		return err
+	}
+	return nil
+}
+
+// auditEvent records each domain event in the audit trail next to the
+// entries of the requests that caused it
+func auditEvent(ctx context.Context, e db.Event) error {
+	entry := db.AuditEntry{
+		ID:        eventRecordID(e, "audit"),
+		ActorID:   "system",
+		ActorRole: "system",
+		Action:    "event." + e.Type,
+		Entity:    e.Entity,
+		EntityID:  e.EntityID,
+		CreatedAt: e.CreatedAt,
+	}
+	if err := db.CreateAuditEntry(ctx, entry); err != nil && !errors.Is(err, db.ErrConflict) {
+		return err
+	}
+	return nil
+}
+
+// queueWebhookDeliveries stores a delivery of the event for each webhook of
+// its company that wants it; the webhooks sender task posts them
+func queueWebhookDeliveries(ctx context.Context, e db.Event) error {
+	if e.CompanyID == "" {
+		return nil
+	}
+	hooks, err := db.GetWebhooks(ctx, e.CompanyID)
+	if err != nil {
+		return err
+	}
+	for _, w := range hooks {
+		if !w.Wants(e.Type) {
+			continue
+		}
+		d, err := webhooks.NewDelivery(eventRecordID(e, "webhooks/"+w.ID), w, e, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := db.CreateWebhookDelivery(ctx, d); err != nil && !errors.Is(err, db.ErrConflict) {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,33 +2,60 @@ package handlers
 
 import (
 	"backend/db"
+	"backend/health"
 	"net/http"
-	"os"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Health returns whether the server is connected to MongoDB or using the in-memory fallback.
+// healthResponse is the body of GET /api/health
+type healthResponse struct {
+	Status      string    `json:"status"`
+	DB          db.Status `json:"db"`
+	DBConnected bool      `json:"db_connected"`
+	DBMode      string    `json:"db_mode"`
+	DBName      string    `json:"db_name"`
+}
+
+// Health returns whether the server is connected to MongoDB or Postgres or
+// using the in-memory or embedded bolt fallback. While the primary is down
+// status is "degraded" and db carries the monitor's view; the response is
+// still a 200 so load balancers keep routing reads.
 func Health(c *gin.Context) {
-	mode := "mongo"
-	connected := true
-	if db.UseInMemory {
+	st := db.CurrentStatus()
+	mode := db.Mode()
+	connected := !db.UseInMemory() && st.State == db.StateOK
+	if mode == db.ModeMemory {
+		// kept for existing clients that check for this value
 		mode = "in-memory"
-		connected = false
 	}
 
-	dbName := ""
-	if db.DB != nil {
-		dbName = db.DB.Name()
-	} else if v := os.Getenv("MONGO_DB"); v != "" {
-		dbName = v
-	} else {
-		dbName = "placement_portal"
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"db_connected": connected,
-		"db_mode":      mode,
-		"db_name":      dbName,
+	c.JSON(http.StatusOK, healthResponse{
+		Status:      st.State,
+		DB:          st,
+		DBConnected: connected,
+		DBMode:      mode,
+		DBName:      db.Name(),
 	})
 }
+
+// LiveHealth is the liveness probe: it answers as long as the process can
+// serve requests and checks no dependencies, so a database outage never gets
+// the process restarted
+func LiveHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Live())
+}
+
+// ReadyHealth is the readiness probe. It runs checks and answers 503 when a
+// critical one fails, so load balancers stop routing to this instance;
+// non-critical failures are reported as "degraded" with a 200.
+func ReadyHealth(checks []health.Check) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rep := health.Run(c, checks)
+		status := http.StatusOK
+		if !rep.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, rep)
+	}
+}
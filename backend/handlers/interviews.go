@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"backend/db"
-	"context"
 	"net/http"
 	"time"
 
@@ -23,12 +22,28 @@ type Interview struct {
 func CreateInterview(c *gin.Context) {
 	var in Interview
 	if err := c.BindJSON(&in); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid"})
+		fail(c, http.StatusBadRequest, "invalid")
 		return
 	}
-	if in.ID == "" {
-		in.ID = uuid.New().String()
+	if in.ApplicationID == "" {
+		fail(c, http.StatusBadRequest, "application_id required")
+		return
+	}
+	app, err := db.GetApplication(c, in.ApplicationID)
+	if err != nil {
+		fail(c, http.StatusNotFound, "application not found")
+		return
+	}
+	job, err := db.GetJobPosting(c, app.JobID)
+	if err != nil {
+		fail(c, http.StatusNotFound, "application not found")
+		return
 	}
+	// any team member, including interviewers, may schedule interviews
+	if !requireCompanyRole(c, job.CompanyID, companyAnyRole...) {
+		return
+	}
+	in.ID = uuid.New().String()
 	in.CreatedAt = time.Now().Format(time.RFC3339)
 
 	rec := map[string]interface{}{
@@ -40,22 +55,19 @@ func CreateInterview(c *gin.Context) {
 		"created_at":     in.CreatedAt,
 	}
 
-	if db.UseInMemory {
-		if err := db.CreateInterviewRecord(rec); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "insert failed"})
-			return
-		}
-	} else {
-		_, err := db.DB.Collection("interviews").InsertOne(context.Background(), in)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "insert failed"})
-			return
-		}
-		// update application status in Mongo
-		if in.ApplicationID != "" {
-			_, _ = db.DB.Collection("applications").UpdateOne(context.Background(), map[string]interface{}{"_id": in.ApplicationID}, map[string]interface{}{"$set": map[string]interface{}{"status": "interview_scheduled", "updated_at": time.Now().Format(time.RFC3339)}})
-		}
+	events := []db.Event{db.InterviewScheduledEvent(rec, job.CompanyID)}
+	if app.Status != "interview_scheduled" {
+		next := app
+		next.Status = "interview_scheduled"
+		events = append(events, db.ApplicationStatusEvent(next, job.CompanyID, app.Status))
+	}
+	// the interview, the application's new status and the events are stored together
+	if err := db.CreateInterviewRecord(c, rec, events...); err != nil {
+		failErr(c, err, "insert failed")
+		return
 	}
 
+	interviewsScheduled.Inc()
+	recordAudit(c, "interview.create", "interview", in.ID, nil, in)
 	c.JSON(http.StatusCreated, gin.H{"data": in})
 }
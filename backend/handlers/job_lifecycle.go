@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"backend/db"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Job posting statuses, matching the job_postings.status check constraint
+const (
+	jobDraft  = "draft"
+	jobActive = "active"
+	jobClosed = "closed"
+)
+
+// filledStatuses are application states that consume one of a job's openings
+var filledStatuses = []string{"selected", "offer_accepted"}
+
+func validJobStatus(s string) bool {
+	return s == jobDraft || s == jobActive || s == jobClosed
+}
+
+type publishRequest struct {
+	PublishAt string `json:"publish_at"`
+}
+
+// PublishJobPosting makes a draft or closed job active. With a future
+// publish_at in the body the job stays a draft and the scheduler publishes it.
+func PublishJobPosting(c *gin.Context) {
+	id := c.Param("id")
+	var req publishRequest
+	// body is optional
+	_ = c.ShouldBindJSON(&req)
+
+	before, err := db.GetJobPosting(c, id)
+	if err != nil || before.DeletedAt != "" {
+		fail(c, http.StatusNotFound, "not found")
+		return
+	}
+	if !requireCompanyRole(c, before.CompanyID, companyManagers...) {
+		return
+	}
+	if before.Status == jobActive {
+		fail(c, http.StatusConflict, "job posting already published")
+		return
+	}
+
+	now := time.Now()
+	ts := now.Format(time.RFC3339)
+	patch := db.JobPostingPatch{UpdatedAt: &ts}
+	action := "job.publish"
+	if req.PublishAt != "" {
+		at, ok := db.ParseTimestamp(req.PublishAt)
+		if !ok {
+			fail(c, http.StatusBadRequest, "invalid publish_at")
+			return
+		}
+		if at.After(now) {
+			status, publishAt := jobDraft, at.Format(time.RFC3339)
+			patch.Status = &status
+			patch.PublishAt = &publishAt
+			action = "job.schedule_publish"
+		}
+	}
+	if action == "job.publish" {
+		if !companyVerified(c, before.CompanyID) {
+			fail(c, http.StatusForbidden, "company must be verified before publishing jobs")
+			return
+		}
+		status, cleared := jobActive, ""
+		patch.Status = &status
+		patch.PublishedAt = &ts
+		patch.PublishAt = &cleared
+	}
+	patch.IfVersion = &before.Version
+	var events []db.Event
+	if action == "job.publish" {
+		events = append(events, db.JobPublishedEvent(before, ts))
+	}
+	if err := db.UpdateJobPosting(c, id, patch, events...); err != nil {
+		failErr(c, err, "update failed")
+		return
+	}
+	after, _ := db.GetJobPosting(c, id)
+	recordAudit(c, action, "job_posting", id, before, after)
+	c.JSON(http.StatusOK, gin.H{"data": after})
+}
+
+// CloseJobPosting stops a job from accepting applications
+func CloseJobPosting(c *gin.Context) {
+	id := c.Param("id")
+	before, err := db.GetJobPosting(c, id)
+	if err != nil || before.DeletedAt != "" {
+		fail(c, http.StatusNotFound, "not found")
+		return
+	}
+	if !requireCompanyRole(c, before.CompanyID, companyManagers...) {
+		return
+	}
+	if before.Status == jobClosed {
+		fail(c, http.StatusConflict, "job posting already closed")
+		return
+	}
+	now := time.Now().Format(time.RFC3339)
+	status, cleared := jobClosed, ""
+	patch := db.JobPostingPatch{Status: &status, ClosedAt: &now, PublishAt: &cleared, UpdatedAt: &now, IfVersion: &before.Version}
+	if err := db.UpdateJobPosting(c, id, patch); err != nil {
+		failErr(c, err, "update failed")
+		return
+	}
+	after, _ := db.GetJobPosting(c, id)
+	recordAudit(c, "job.close", "job_posting", id, before, after)
+	c.JSON(http.StatusOK, gin.H{"data": after})
+}
+
+// RunJobLifecycle is the scheduler task that publishes drafts whose
+// publish_at has passed and closes active jobs once their application
+// deadline passes or all openings are filled.
+func RunJobLifecycle(ctx context.Context, now time.Time) error {
+	drafts, err := db.GetJobPostings(ctx, map[string]interface{}{"status": jobDraft})
+	if err != nil {
+		return err
+	}
+	for _, j := range drafts {
+		at, ok := db.ParseTimestamp(j.PublishAt)
+		if !ok || at.After(now) {
+			continue
+		}
+		// scheduled jobs of unverified companies wait until verification
+		if !companyVerified(ctx, j.CompanyID) {
+			continue
+		}
+		ts := now.Format(time.RFC3339)
+		status, cleared := jobActive, ""
+		patch := db.JobPostingPatch{Status: &status, PublishedAt: &ts, PublishAt: &cleared, UpdatedAt: &ts, IfVersion: &j.Version}
+		if err := db.UpdateJobPosting(ctx, j.ID, patch, db.JobPublishedEvent(j, ts)); errors.Is(err, db.ErrConflict) {
+			// changed since it was listed; the next run looks again
+			continue
+		} else if err != nil {
+			return err
+		}
+		after, _ := db.GetJobPosting(ctx, j.ID)
+		recordSystemAudit(ctx, "job.publish", "job_posting", j.ID, j, after)
+	}
+
+	active, err := db.GetJobPostings(ctx, map[string]interface{}{"status": jobActive})
+	if err != nil {
+		return err
+	}
+	for _, j := range active {
+		action := ""
+		if deadline, ok := db.ParseTimestamp(j.ApplicationDeadline); ok && now.After(deadline) {
+			action = "job.close_deadline"
+		} else if j.Openings > 0 {
+			filled, err := db.CountApplications(ctx, j.ID, filledStatuses...)
+			if err != nil {
+				return err
+			}
+			if filled >= int64(j.Openings) {
+				action = "job.close_filled"
+			}
+		}
+		if action == "" {
+			continue
+		}
+		ts := now.Format(time.RFC3339)
+		status := jobClosed
+		patch := db.JobPostingPatch{Status: &status, ClosedAt: &ts, UpdatedAt: &ts, IfVersion: &j.Version}
+		if err := db.UpdateJobPosting(ctx, j.ID, patch); errors.Is(err, db.ErrConflict) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		after, _ := db.GetJobPosting(ctx, j.ID)
+		recordSystemAudit(ctx, action, "job_posting", j.ID, j, after)
+	}
+	return nil
+}
@@ -2,61 +2,41 @@ package handlers
 
 import (
 	"backend/db"
-	"context"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// GetJobPostings lists jobs. Students and anonymous callers only see active
+// jobs; recruiters see every status for their own company and admins for any
+// company, optionally narrowed with ?status=.
 func GetJobPostings(c *gin.Context) {
 	company := c.Query("company_id")
+	role := c.GetString("role")
+	// soft-deleted postings are only visible to admins who ask for them
+	includeDeleted := c.Query("include_deleted") == "true" && role == "admin"
 
-	// If using in-memory, return simple list from db helpers
-	if db.UseInMemory {
-		filter := map[string]interface{}{}
-		if company != "" {
-			filter["company_id"] = company
-		}
-		out, err := db.GetJobPostings(filter)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "list failed"})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"data": out})
-		return
-	}
-
-	// Build aggregation pipeline to lookup company details
-	col := db.DB.Collection("job_postings")
-	pipeline := mongo.Pipeline{
-		{{"$match", bson.D{{"status", "active"}}}},
-		{{"$lookup", bson.D{{"from", "companies"}, {"localField", "company_id"}, {"foreignField", "_id"}, {"as", "companies"}}}},
-		{{"$unwind", bson.D{{"path", "$companies"}, {"preserveNullAndEmptyArrays", true}}}},
-		// lookup applications to compute count
-		{{"$lookup", bson.D{{"from", "applications"}, {"localField", "_id"}, {"foreignField", "job_id"}, {"as", "applications"}}}},
-		{{"$addFields", bson.D{{"applications_count", bson.D{{"$size", "$applications"}}}}}},
+	status := jobActive
+	if role == "admin" || (company != "" && companyRoleFor(c, company) != "") {
+		status = c.Query("status")
 	}
 
+	filter := map[string]interface{}{}
 	if company != "" {
-		// add additional match by company id
-		oid := company
-		pipeline = append(pipeline, bson.D{{"$match", bson.D{{"company_id", oid}}}})
+		filter["company_id"] = company
 	}
-	// sort by created_at desc
-	pipeline = append(pipeline, bson.D{{"$sort", bson.D{{"created_at", -1}}}})
-
-	cur, err := col.Aggregate(context.Background(), pipeline)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "aggregate failed"})
-		return
+	if status != "" {
+		filter["status"] = status
+	}
+	if includeDeleted {
+		filter["include_deleted"] = true
 	}
-	var out []map[string]interface{}
-	if err := cur.All(context.Background(), &out); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "decode failed"})
+	// each job carries its company and applications_count
+	out, err := db.JobPostingsWithCompany(c, filter)
+	if err != nil {
+		failErr(c, err, "list failed")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": out})
@@ -65,50 +45,180 @@ func GetJobPostings(c *gin.Context) {
 func CreateJobPosting(c *gin.Context) {
 	var j db.JobPosting
 	if err := c.BindJSON(&j); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid"})
+		fail(c, http.StatusBadRequest, "invalid")
 		return
 	}
-	if j.ID == "" {
-		j.ID = uuid.New().String()
+	if !requireCompanyRole(c, j.CompanyID, companyManagers...) {
+		return
 	}
+	// ids are always the server's: a client-chosen one could name another
+	// company's record
+	j.ID = uuid.New().String()
+	// new jobs start as drafts unless the client publishes them straight away
 	if j.Status == "" {
-		j.Status = "active"
+		j.Status = jobDraft
+	}
+	if !validJobStatus(j.Status) {
+		fail(c, http.StatusBadRequest, "invalid status")
+		return
+	}
+	if j.Status == jobActive && !companyVerified(c, j.CompanyID) {
+		fail(c, http.StatusForbidden, "company must be verified before publishing jobs")
+		return
+	}
+	now := time.Now().Format(time.RFC3339)
+	j.CreatedAt = now
+	j.UpdatedAt = now
+	j.PublishedAt = ""
+	j.ClosedAt = ""
+	j.DeletedAt = ""
+	switch j.Status {
+	case jobActive:
+		j.PublishedAt = now
+		j.PublishAt = ""
+	case jobClosed:
+		j.ClosedAt = now
 	}
-	j.CreatedAt = time.Now().Format(time.RFC3339)
-	if err := db.CreateJobPosting(j); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "insert failed"})
+	j.Version = db.FirstVersion
+	if err := db.CreateJobPosting(c, j); err != nil {
+		failErr(c, err, "insert failed")
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"data": j})
+	recordAudit(c, "job.create", "job_posting", j.ID, nil, j)
+	respondCreated(c, j, j.Version)
 }
 
+// jobPostingPatchIgnored are keys an update may echo but never changes
+var jobPostingPatchIgnored = []string{"company_id"}
+
 func UpdateJobPosting(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id required"})
+		fail(c, http.StatusBadRequest, "id required")
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		fail(c, http.StatusBadRequest, "invalid")
+		return
+	}
+	var patch db.JobPostingPatch
+	if !decodePatch(c, body, &patch, jobPostingPatchIgnored...) {
 		return
 	}
-	var patch map[string]interface{}
-	if err := c.BindJSON(&patch); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid"})
+	before, err := db.GetJobPosting(c, id)
+	if err != nil || before.DeletedAt != "" {
+		fail(c, http.StatusNotFound, "not found")
 		return
 	}
-	if err := db.UpdateJobPosting(id, patch); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+	if !requireCompanyRole(c, before.CompanyID, companyManagers...) {
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": patch})
+	if !expectVersion(c, before.Version, &patch.IfVersion) {
+		return
+	}
+	now := time.Now().Format(time.RFC3339)
+	patch.UpdatedAt = &now
+	// keep lifecycle timestamps consistent when status is changed directly
+	if patch.Status != nil && *patch.Status != before.Status {
+		if *patch.Status == jobActive && !companyVerified(c, before.CompanyID) {
+			fail(c, http.StatusForbidden, "company must be verified before publishing jobs")
+			return
+		}
+		switch *patch.Status {
+		case jobActive:
+			cleared := ""
+			patch.PublishedAt = &now
+			patch.PublishAt = &cleared
+		case jobClosed:
+			patch.ClosedAt = &now
+		}
+	}
+	var events []db.Event
+	if patch.Status != nil && *patch.Status == jobActive && before.Status != jobActive {
+		events = append(events, db.JobPublishedEvent(before, now))
+	}
+	if err := db.UpdateJobPosting(c, id, patch, events...); err != nil {
+		failErr(c, err, "update failed")
+		return
+	}
+	after, _ := db.GetJobPosting(c, id)
+	recordAudit(c, jobAuditAction(before.Status, after.Status), "job_posting", id, before, after)
+	respondUpdated(c, after, patch, after.Version)
 }
 
 func DeleteJobPosting(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id required"})
+		fail(c, http.StatusBadRequest, "id required")
+		return
+	}
+	before, err := db.GetJobPosting(c, id)
+	if err != nil {
+		fail(c, http.StatusNotFound, "not found")
+		return
+	}
+	if !requireCompanyRole(c, before.CompanyID, companyManagers...) {
 		return
 	}
-	if err := db.DeleteJobPosting(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+	// closing the open applications publishes their status events, which
+	// notify the students
+	if _, err := db.DeleteJobPosting(c, id); err != nil {
+		failErr(c, err, "delete failed")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": "deleted"})
+	after, _ := db.GetJobPosting(c, id)
+	recordAudit(c, "job.delete", "job_posting", id, before, after)
+	respondNoContent(c, "deleted")
+}
+
+// GetJobPosting returns one job posting. Like the listing, only published
+// jobs are visible outside the company, and withdrawn ones only to admins.
+func GetJobPosting(c *gin.Context) {
+	j, err := db.GetJobPosting(c, c.Param("id"))
+	if err == nil && c.GetString("role") != "admin" {
+		if j.DeletedAt != "" || j.Status != jobActive && companyRoleFor(c, j.CompanyID) == "" {
+			err = db.ErrNotFound
+		}
+	}
+	if err != nil {
+		failErr(c, err, "lookup failed")
+		return
+	}
+	respondResource(c, j, j.Version)
+}
+
+// RestoreJobPosting undoes a soft delete (admin only)
+func RestoreJobPosting(c *gin.Context) {
+	id := c.Param("id")
+	before, err := db.GetJobPosting(c, id)
+	if err != nil {
+		failErr(c, err, "lookup failed")
+		return
+	}
+	if err := db.RestoreJobPosting(c, id); err != nil {
+		failErr(c, err, "restore failed")
+		return
+	}
+	after, err := db.GetJobPosting(c, id)
+	if err != nil {
+		failErr(c, err, "lookup failed")
+		return
+	}
+	recordAudit(c, "job.restore", "job_posting", id, before, after)
+	respondResource(c, after, after.Version)
+}
+
+// jobAuditAction names a job update after the status transition it performed
+func jobAuditAction(from, to string) string {
+	if from == to {
+		return "job.update"
+	}
+	switch to {
+	case "active":
+		return "job.publish"
+	case "closed":
+		return "job.close"
+	}
+	return "job.status_change"
 }
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"backend/db"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Role sets used by authorization checks on company-owned resources
+var (
+	companyManagers = []string{db.MemberOwner, db.MemberRecruiter}
+	companyAnyRole  = []string{db.MemberOwner, db.MemberRecruiter, db.MemberInterviewer}
+)
+
+type inviteMemberRequest struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+}
+
+// companyRoleFor returns the caller's team role in a company, or "admin" for
+// platform admins. Empty means the caller has no access.
+func companyRoleFor(c *gin.Context, companyID string) string {
+	if c.GetString("role") == "admin" {
+		return "admin"
+	}
+	role, err := db.GetCompanyMemberRole(c, companyID, c.GetString("userID"))
+	if err != nil {
+		slog.ErrorContext(c, "membership lookup failed", "company_id", companyID, "error", err)
+		return ""
+	}
+	return role
+}
+
+// requireCompanyRole checks that the caller is an admin or holds one of roles
+// in the company, writing a 401/403 response when they do not.
+func requireCompanyRole(c *gin.Context, companyID string, roles ...string) bool {
+	if c.GetString("userID") == "" {
+		fail(c, http.StatusUnauthorized, "Authorization required")
+		return false
+	}
+	role := companyRoleFor(c, companyID)
+	if role == "admin" {
+		return true
+	}
+	for _, r := range roles {
+		if role == r {
+			return true
+		}
+	}
+	fail(c, http.StatusForbidden, "not allowed for this company")
+	return false
+}
+
+// GetCompanyMembers lists a company's team; any member may view it
+func GetCompanyMembers(c *gin.Context) {
+	companyID := c.Param("id")
+	if !requireCompanyRole(c, companyID, companyAnyRole...) {
+		return
+	}
+	out, err := db.GetCompanyMembers(c, map[string]interface{}{"company_id": companyID})
+	if err != nil {
+		failErr(c, err, "list failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out})
+}
+
+// InviteCompanyMember adds an existing profile (by user_id or email) to a
+// company's team. Only owners and admins may invite.
+func InviteCompanyMember(c *gin.Context) {
+	companyID := c.Param("id")
+	co, err := db.GetCompany(c, companyID)
+	if err != nil {
+		fail(c, http.StatusNotFound, "company not found")
+		return
+	}
+	if !requireCompanyRole(c, companyID, db.MemberOwner) {
+		return
+	}
+	var req inviteMemberRequest
+	if err := c.BindJSON(&req); err != nil {
+		fail(c, http.StatusBadRequest, "invalid")
+		return
+	}
+	if !db.ValidMemberRole(req.Role) {
+		fail(c, http.StatusBadRequest, "role must be owner, recruiter or interviewer")
+		return
+	}
+	var invitee db.Profile
+	switch {
+	case req.UserID != "":
+		invitee, err = db.GetProfile(c, req.UserID)
+	case req.Email != "":
+		var rows []db.Profile
+		rows, err = db.ListProfiles(c, map[string]interface{}{"email": req.Email})
+		if err == nil && len(rows) == 0 {
+			err = fmt.Errorf("no profile with email %s", req.Email)
+		}
+		if err == nil {
+			invitee = rows[0]
+		}
+	default:
+		fail(c, http.StatusBadRequest, "user_id or email required")
+		return
+	}
+	if err != nil {
+		fail(c, http.StatusNotFound, "user not found")
+		return
+	}
+
+	m := db.CompanyMember{
+		ID:        uuid.New().String(),
+		CompanyID: companyID,
+		UserID:    invitee.ID,
+		Role:      req.Role,
+		InvitedBy: c.GetString("userID"),
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	if err := db.AddCompanyMember(c, m); err != nil {
+		failErr(c, err, "insert failed")
+		return
+	}
+	recordAudit(c, "company.member_add", "company", companyID, nil, m)
+	notifyUser(c, invitee.ID, "Added to company team",
+		fmt.Sprintf("You were added to %s as %s.", co.Name, req.Role), "general")
+	c.JSON(http.StatusCreated, gin.H{"data": m})
+}
+
+// RemoveCompanyMember removes a user from a company's team. Owners may remove
+// anyone and members may remove themselves, but the last owner must stay.
+func RemoveCompanyMember(c *gin.Context) {
+	companyID := c.Param("id")
+	userID := c.Param("user_id")
+	if c.GetString("userID") != userID && !requireCompanyRole(c, companyID, db.MemberOwner) {
+		return
+	}
+	members, err := db.GetCompanyMembers(c, map[string]interface{}{"company_id": companyID})
+	if err != nil {
+		failErr(c, err, "list failed")
+		return
+	}
+	co, err := db.GetCompany(c, companyID)
+	if err != nil {
+		fail(c, http.StatusNotFound, "company not found")
+		return
+	}
+	var target *db.CompanyMember
+	owners := 0
+	// a company's recruiter_id without a team row still owns it, see
+	// db.GetCompanyMemberRole
+	legacyOwner := co.RecruiterID != ""
+	for i, m := range members {
+		if m.Role == db.MemberOwner {
+			owners++
+		}
+		if m.UserID == co.RecruiterID {
+			legacyOwner = false
+		}
+		if m.UserID == userID {
+			target = &members[i]
+		}
+	}
+	if legacyOwner {
+		owners++
+	}
+	if target == nil {
+		fail(c, http.StatusNotFound, "not a member")
+		return
+	}
+	if target.Role == db.MemberOwner && owners <= 1 {
+		fail(c, http.StatusConflict, "cannot remove the last owner")
+		return
+	}
+	if err := db.RemoveCompanyMember(c, companyID, userID); err != nil {
+		failErr(c, err, "delete failed")
+		return
+	}
+	recordAudit(c, "company.member_remove", "company", companyID, *target, nil)
+	respondNoContent(c, "removed")
+}
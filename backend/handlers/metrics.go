@@ -0,0 +1,10 @@
+package handlers
+
+import "backend/telemetry"
+
+// Business counters exposed on /metrics
+var (
+	applicationsCreated = telemetry.NewCounter("applications_created_total", "Applications submitted by students.")
+	interviewsScheduled = telemetry.NewCounter("interviews_scheduled_total", "Interviews scheduled by company teams.")
+	offersAccepted      = telemetry.NewCounter("offers_accepted_total", "Offers accepted by students.")
+)
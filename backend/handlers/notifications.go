@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"backend/db"
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetNotifications lists the caller's notifications. Admins may name
+// another user with user_id.
+func GetNotifications(c *gin.Context) {
+	userID := c.GetString("userID")
+	if u := c.Query("user_id"); u != "" && u != userID {
+		if c.GetString("role") != "admin" {
+			fail(c, http.StatusForbidden, "not allowed for this user")
+			return
+		}
+		userID = u
+	}
+	filter := map[string]interface{}{"user_id": userID}
+	out, err := db.GetNotifications(c, filter)
+	if err != nil {
+		failErr(c, err, "list failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out})
+}
+
+// notifyUser stores a notification for a profile id. Like audit entries,
+// notification failures are logged and never fail the triggering request,
+// and the notification is stored even if the client has gone away.
+func notifyUser(ctx context.Context, userID, title, message, kind string) {
+	if userID == "" {
+		return
+	}
+	n := db.Notification{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Title:     title,
+		Message:   message,
+		Type:      kind,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	if err := db.CreateNotification(context.WithoutCancel(ctx), n); err != nil {
+		slog.ErrorContext(ctx, "failed to create notification", "recipient", userID, "error", err)
+	}
+}
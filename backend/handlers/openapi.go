@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"backend/backup"
+	"backend/db"
+	"backend/health"
+	"backend/middleware"
+	"backend/openapi"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Shapes of the joined read models, which carry the stored documents'
+// field names (the id under "_id") rather than the JSON ones
+var (
+	jobPostingWithCompany = openapi.Doc{Name: "JobPostingWithCompany", V: db.JobPosting{}, Joins: map[string]interface{}{
+		"companies":          openapi.Doc{Name: "CompanyDocument", V: db.Company{}},
+		"applications":       openapi.ListOf{Item: openapi.Doc{Name: "ApplicationDocument", V: db.Application{}}},
+		"applications_count": 0,
+	}}
+	companyApplication = openapi.Doc{Name: "CompanyApplication", V: db.Application{}, Joins: map[string]interface{}{
+		"job_postings": openapi.Doc{Name: "JobPostingDocument", V: db.JobPosting{}},
+		"student_profiles": openapi.Doc{Name: "ApplicantProfile", V: db.StudentProfile{}, Joins: map[string]interface{}{
+			"profiles": openapi.Doc{Name: "ProfileDocument", V: db.Profile{}},
+		}},
+	}}
+)
+
+// patchIgnored adds the keys every patch drops to a handler's own
+func patchIgnored(keys ...string) []string {
+	return append(db.IgnoredPatchKeys(), keys...)
+}
+
+// apiRoutes documents every route the router registers under /api. Payload
+// types are the ones the handlers bind and write; the contract command
+// checks that the two stay in step.
+func apiRoutes() []openapi.Route {
+	const created = http.StatusCreated
+	return []openapi.Route{
+		{Method: "GET", Path: "/api/openapi.json", Tag: "meta", Summary: "This document", Bare: &openapi.Schema{}, Public: true},
+		{Method: "GET", Path: "/api/health", Tag: "health", Summary: "Storage backend and its health", Bare: healthResponse{}, Public: true},
+		{Method: "GET", Path: "/api/health/live", Tag: "health", Summary: "Liveness probe", Bare: health.Report{}, Public: true},
+		{Method: "GET", Path: "/api/health/ready", Tag: "health", Summary: "Readiness probe; 503 while a critical dependency fails",
+			Bare: health.Report{}, Other: map[int]interface{}{http.StatusServiceUnavailable: health.Report{}}, Public: true},
+		{Method: "POST", Path: "/api/auth/google", Tag: "auth", Summary: "Exchange a Firebase ID token for an API token",
+			Body: googleAuthRequest{}, Bare: tokenResponse{}, Public: true},
+
+		{Method: "GET", Path: "/api/profiles", Tag: "profiles", Summary: "List profiles", Query: []string{"id", "user_id"}, Data: []db.Profile{}},
+		{Method: "POST", Path: "/api/profiles", Tag: "profiles", Summary: "Create a profile", Body: db.Profile{}, Status: created, Data: db.Profile{}},
+		{Method: "PUT", ETag: true, Path: "/api/profiles/:id", Tag: "profiles", Summary: "Update your own profile; only admins may change a role",
+			Body: db.ProfilePatch{}, Ignored: patchIgnored(), Data: db.ProfilePatch{}},
+
+		{Method: "GET", Path: "/api/student_profiles", Tag: "student profiles", Summary: "List student profiles", Query: []string{"user_id"}, Data: []db.StudentProfile{}},
+		{Method: "POST", Path: "/api/student_profiles", Tag: "student profiles", Summary: "Create a student profile",
+			Body: db.StudentProfile{}, Status: created, Data: db.StudentProfile{}},
+		{Method: "PUT", ETag: true, Path: "/api/student_profiles/:id", Tag: "student profiles", Summary: "Update your own student profile",
+			Body: db.StudentProfilePatch{}, Ignored: patchIgnored(studentProfilePatchIgnored...), Data: db.StudentProfilePatch{}},
+
+		{Method: "GET", Path: "/api/companies", Tag: "companies", Summary: "List companies", Query: []string{"member_id", "recruiter_id"}, Data: []db.Company{}},
+		{Method: "POST", Path: "/api/companies", Tag: "companies", Summary: "Create a company owned by the caller", Body: db.Company{}, Status: created, Data: db.Company{}},
+		{Method: "PUT", ETag: true, Path: "/api/companies/:id", Tag: "companies", Summary: "Update a company",
+			Body: db.CompanyPatch{}, Ignored: patchIgnored(companyPatchIgnored...), Data: db.CompanyPatch{}},
+		{Method: "GET", Path: "/api/companies/:id/members", Tag: "companies", Summary: "List a company's team", Data: []db.CompanyMember{}},
+		{Method: "POST", Path: "/api/companies/:id/members", Tag: "companies", Summary: "Invite a profile to a company's team",
+			Body: inviteMemberRequest{}, Status: created, Data: db.CompanyMember{}},
+		{Method: "DELETE", Path: "/api/companies/:id/members/:user_id", Tag: "companies", Summary: "Remove a team member", Data: ""},
+		{Method: "GET", Path: "/api/companies/:id/webhooks", Tag: "companies", Summary: "List a company's webhooks", Data: []db.Webhook{}},
+		{Method: "POST", Path: "/api/companies/:id/webhooks", Tag: "companies", Summary: "Register a webhook; the response carries its signing secret",
+			Body: webhookRequest{}, Status: created, Data: createdWebhook{}},
+		{Method: "DELETE", Path: "/api/companies/:id/webhooks/:webhook_id", Tag: "companies", Summary: "Remove a webhook and its delivery log", Data: ""},
+		{Method: "GET", Path: "/api/companies/:id/webhooks/deliveries", Tag: "companies", Summary: "List a company's webhook deliveries, newest first",
+			Query: []string{"webhook_id", "status", "limit"}, Data: []db.WebhookDelivery{}},
+		{Method: "POST", Path: "/api/companies/:id/webhooks/deliveries/:delivery_id/replay", Tag: "companies", Summary: "Send a delivery's event again",
+			Status: http.StatusAccepted, Data: db.WebhookDelivery{}},
+
+		{Method: "GET", Path: "/api/job_postings", Tag: "jobs", Summary: "List job postings with their company and applications",
+			Query: []string{"company_id", "status", "include_deleted"}, Data: openapi.ListOf{Item: jobPostingWithCompany}},
+		{Method: "POST", Path: "/api/job_postings", Tag: "jobs", Summary: "Create a job posting", Body: db.JobPosting{}, Status: created, Data: db.JobPosting{}},
+		{Method: "PUT", ETag: true, Path: "/api/job_postings/:id", Tag: "jobs", Summary: "Update a job posting",
+			Body: db.JobPostingPatch{}, Ignored: patchIgnored(jobPostingPatchIgnored...), Data: db.JobPostingPatch{}},
+		{Method: "DELETE", Path: "/api/job_postings/:id", Tag: "jobs", Summary: "Withdraw a job posting", Data: ""},
+		{Method: "POST", Path: "/api/job_postings/:id/publish", Tag: "jobs", Summary: "Publish a job now or at publish_at",
+			Body: publishRequest{}, OptionalBody: true, Data: db.JobPosting{}},
+		{Method: "POST", Path: "/api/job_postings/:id/close", Tag: "jobs", Summary: "Close a job to applications", Data: db.JobPosting{}},
+
+		{Method: "POST", Path: "/api/resumes/upload", Tag: "resumes", Summary: "Upload a PDF or DOCX resume for the caller's student profile",
+			Form: []string{"file", "student_id"}, Status: created, Data: db.Resume{}},
+		{Method: "GET", Path: "/api/resumes", Tag: "resumes", Summary: "List the resumes of a student you may see", Query: []string{"student_id"}, Data: []db.Resume{}},
+		{Method: "GET", Path: "/api/resumes/:id/file", Tag: "resumes", Summary: "Download a resume", File: "application/octet-stream"},
+
+		{Method: "GET", Path: "/api/applications", Tag: "applications", Summary: "List applications; a company's come joined with job and student",
+			Query: []string{"student_id", "company_id"}, Data: openapi.AnyOf{[]db.Application{}, openapi.ListOf{Item: companyApplication}}},
+		{Method: "POST", Path: "/api/applications", Tag: "applications", Summary: "Apply to a job as the caller's student profile", Body: db.Application{}, Status: created, Data: db.Application{}},
+		{Method: "PUT", ETag: true, Path: "/api/applications/:id", Tag: "applications", Summary: "Update an application",
+			Body: db.ApplicationPatch{}, Ignored: patchIgnored(applicationPatchIgnored...), Data: db.ApplicationPatch{}},
+
+		{Method: "POST", Path: "/api/interviews", Tag: "interviews", Summary: "Schedule an interview", Body: Interview{}, Status: created, Data: Interview{}},
+		{Method: "GET", Path: "/api/notifications", Tag: "notifications", Summary: "List your notifications; admins may name another user", Query: []string{"user_id"}, Data: []db.Notification{}},
+
+		{Method: "POST", Path: "/api/companies/:id/verification", Tag: "verification", Summary: "Request company verification",
+			Body: verificationDetails{}, Status: created, Data: db.VerificationRequest{}},
+		{Method: "GET", Path: "/api/verification_requests", Tag: "verification", Summary: "List verification requests",
+			Query: []string{"company_id", "status"}, Data: []db.VerificationRequest{}},
+		{Method: "PUT", ETag: true, Path: "/api/verification_requests/:id", Tag: "verification", Summary: "Amend an open verification request",
+			Body: verificationDetails{}, Data: db.VerificationRequest{}},
+		{Method: "POST", Path: "/api/verification_requests/:id/documents", Tag: "verification", Summary: "Attach a PDF or DOCX document",
+			Form: []string{"file"}, Status: created, Data: db.VerificationDocument{}},
+		{Method: "GET", Path: "/api/verification_requests/:id/documents/:doc_id", Tag: "verification", Summary: "Download a document",
+			File: "application/octet-stream"},
+
+		{Method: "GET", Path: "/api/admin/audit", Tag: "admin", Summary: "Search the audit log",
+			Query: []string{"actor_id", "entity", "entity_id", "action", "from", "to", "limit"}, Data: []db.AuditEntry{}},
+		{Method: "POST", Path: "/api/admin/job_postings/:id/restore", Tag: "admin", Summary: "Restore a withdrawn job posting", Data: db.JobPosting{}},
+		{Method: "POST", Path: "/api/admin/verification_requests/:id/review", Tag: "admin", Summary: "Approve, reject or request changes",
+			Body: verificationReview{}, Data: db.VerificationRequest{}},
+		{Method: "GET", Path: "/api/admin/snapshot", Tag: "admin", Summary: "Download a snapshot of the embedded store", File: "application/octet-stream"},
+		{Method: "POST", Path: "/api/admin/restore", Tag: "admin", Summary: "Replace the embedded store with a snapshot", Form: []string{"file"}, Data: ""},
+		{Method: "GET", Path: "/api/admin/config", Tag: "admin", Summary: "Effective configuration, secrets redacted", Data: configResponse{}},
+		{Method: "GET", Path: "/api/admin/backup", Tag: "admin", Summary: "Download a backup archive", File: "application/gzip"},
+		{Method: "POST", Path: "/api/admin/backup/restore", Tag: "admin", Summary: "Restore a backup archive", Form: []string{"file"}, Data: backup.Manifest{}},
+	}
+}
+
+// v1ByID are the resources /api/v1 reads by id, with the type its updates
+// answer with
+var v1ByID = []struct {
+	path, tag, summary string
+	v                  interface{}
+}{
+	{"/profiles/:id", "profiles", "Get a profile", db.Profile{}},
+	{"/student_profiles/:id", "student profiles", "Get a student profile", db.StudentProfile{}},
+	{"/companies/:id", "companies", "Get a company", db.Company{}},
+	{"/job_postings/:id", "jobs", "Get a job posting", db.JobPosting{}},
+	{"/applications/:id", "applications", "Get an application", db.Application{}},
+	{"/verification_requests/:id", "verification", "Get a verification request", db.VerificationRequest{}},
+}
+
+// v1Routes documents /api/v1 from the /api table: the same routes less the
+// unversioned ones, with updates answering with the stored resource, deletes
+// with 204 and every payload in the envelope, plus the reads by id
+func v1Routes(routes []openapi.Route) []openapi.Route {
+	stored := map[string]interface{}{}
+	var out []openapi.Route
+	for _, b := range v1ByID {
+		stored["/api"+b.path] = b.v
+		out = append(out, openapi.Route{Method: "GET", Path: "/api/v1" + b.path, Tag: b.tag, Summary: b.summary, Data: b.v, ETag: true})
+	}
+	for _, r := range routes {
+		if r.Path == "/api/openapi.json" || strings.HasPrefix(r.Path, "/api/health") {
+			continue
+		}
+		switch {
+		case r.Method == "PUT":
+			r.Data = stored[r.Path]
+		case r.Method == "DELETE", r.Path == "/api/admin/restore":
+			r.Status, r.Data = http.StatusNoContent, nil
+		case r.Bare != nil:
+			r.Data, r.Bare = r.Bare, nil
+		}
+		r.Path = "/api/v1" + strings.TrimPrefix(r.Path, "/api")
+		out = append(out, r)
+	}
+	return out
+}
+
+// OpenAPISpec builds the OpenAPI document for the /api and /api/v1 routes
+func OpenAPISpec() *openapi.Spec {
+	g := openapi.NewGenerator()
+	// the flexible numbers also take numeric strings from HTML forms
+	g.Override(db.FlexInt(0), openapi.Type("integer", "string"))
+	g.Override(db.FlexFloat(0), openapi.Type("number", "string"))
+	info := openapi.Info{Title: "Placement portal API", Version: health.BuildVersion()}
+	routes := apiRoutes()
+	return openapi.Build(info, g, middleware.ErrorBody{}, append(routes, v1Routes(routes)...))
+}
+
+// ServeOpenAPI serves the document, encoded once
+func ServeOpenAPI(spec *openapi.Spec) gin.HandlerFunc {
+	body, err := json.Marshal(spec)
+	if err != nil {
+		panic(err)
+	}
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	}
+}
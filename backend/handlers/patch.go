@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"backend/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+type validatable interface {
+	Validate() error
+}
+
+// decodePatch decodes and validates a typed patch from the request body,
+// writing a 422 response listing every invalid field on failure.
+func decodePatch(c *gin.Context, body []byte, dst validatable, ignore ...string) bool {
+	err := db.DecodePatch(body, dst, ignore...)
+	if err == nil {
+		err = dst.Validate()
+	}
+	if err != nil {
+		failErr(c, err, "invalid")
+		return false
+	}
+	return true
+}
@@ -24,9 +24,9 @@ func GetProfiles(c *gin.Context) {
 		filter["_id"] = u
 	}
 
-	out, err := db.ListProfiles(filter)
+	out, err := db.ListProfiles(c, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		failErr(c, err, "list failed")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": out})
@@ -35,7 +35,7 @@ func GetProfiles(c *gin.Context) {
 func CreateProfile(c *gin.Context) {
 	var p db.Profile
 	if err := c.BindJSON(&p); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid"})
+		fail(c, http.StatusBadRequest, "invalid")
 		return
 	}
 	if p.ID == "" {
@@ -44,28 +44,63 @@ func CreateProfile(c *gin.Context) {
 	now := time.Now().Format(time.RFC3339)
 	p.CreatedAt = now
 	p.UpdatedAt = now
-	if err := db.CreateProfile(p); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "insert failed"})
+	p.Version = db.FirstVersion
+	if err := db.CreateProfile(c, p); err != nil {
+		failErr(c, err, "insert failed")
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"data": p})
+	respondCreated(c, p, p.Version)
 }
 
+// GetProfile returns one profile
+func GetProfile(c *gin.Context) {
+	p, err := db.GetProfile(c, c.Param("id"))
+	if err != nil {
+		failErr(c, err, "lookup failed")
+		return
+	}
+	respondResource(c, p, p.Version)
+}
+
+// UpdateProfile changes a profile. Users may only change their own, and
+// only admins may change a role.
 func UpdateProfile(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+		fail(c, http.StatusBadRequest, "missing id")
+		return
+	}
+	admin := c.GetString("role") == "admin"
+	if c.GetString("userID") != id && !admin {
+		fail(c, http.StatusForbidden, "not allowed for this profile")
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		fail(c, http.StatusBadRequest, "invalid body")
+		return
+	}
+	var patch db.ProfilePatch
+	if !decodePatch(c, body, &patch) {
 		return
 	}
-	var patch map[string]interface{}
-	if err := c.BindJSON(&patch); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+	now := time.Now().Format(time.RFC3339)
+	patch.UpdatedAt = &now
+	before, _ := db.GetProfile(c, id)
+	if patch.Role != nil && *patch.Role != before.Role && !admin {
+		fail(c, http.StatusForbidden, "only admins may change a role")
+		return
+	}
+	if !expectVersion(c, before.Version, &patch.IfVersion) {
 		return
 	}
-	patch["updated_at"] = time.Now().Format(time.RFC3339)
-	if err := db.UpdateProfile(id, patch); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+	if err := db.UpdateProfile(c, id, patch); err != nil {
+		failErr(c, err, "update failed")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": patch})
+	after, _ := db.GetProfile(c, id)
+	if before.Role != after.Role {
+		recordAudit(c, "profile.role_change", "profile", id, before, after)
+	}
+	respondUpdated(c, after, patch, after.Version)
 }
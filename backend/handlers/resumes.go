@@ -2,55 +2,157 @@ package handlers
 
 import (
 	"backend/db"
+	"backend/storage"
+	"errors"
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// UploadResume stores a multipart "file" (PDF or DOCX) in the blob store and
+// records it for the caller's student profile; admins name the student in
+// student_id
 func UploadResume(c *gin.Context) {
-	// accept multipart form
-	file, _, err := c.Request.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "file required"})
+	file, header, contentType, ok := formDocument(c)
+	if !ok {
 		return
 	}
 	defer file.Close()
 
-	// For demo, we won't persist the file. We'll return a fake URL and store metadata.
-	id := uuid.New().String()
-	url := fmt.Sprintf("/uploads/%s.pdf", id)
-
-	studentID := c.PostForm("student_id")
-	if studentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "student_id required"})
+	studentID, ok := studentIDFor(c, c.PostForm("student_id"))
+	if !ok {
 		return
 	}
 
+	id := uuid.New().String()
+	name := filepath.Base(header.Filename)
 	r := db.Resume{
-		ID:        id,
-		StudentID: studentID,
-		FileURL:   url,
-		CreatedAt: time.Now().Format(time.RFC3339),
+		ID:          id,
+		StudentID:   studentID,
+		FileName:    name,
+		FileURL:     fmt.Sprintf("/api/resumes/%s/file", id),
+		ContentType: contentType,
+		BlobKey:     fmt.Sprintf("resumes/%s%s", id, documentExt[contentType]),
+		CreatedAt:   time.Now().Format(time.RFC3339),
 	}
-	if err := db.CreateResume(r); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "insert failed"})
+	n, err := storage.Put(r.BlobKey, file)
+	if err != nil {
+		failErr(c, err, "upload failed")
+		return
+	}
+	r.Size = n
+	if err := db.CreateResume(c, r); err != nil {
+		_ = storage.Delete(r.BlobKey)
+		failErr(c, err, "insert failed")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"data": r})
 }
 
+// canViewResumes reports whether the caller may see a student's resumes:
+// admins, the student and the team of any company the student applied to
+func canViewResumes(c *gin.Context, studentID string) (bool, error) {
+	if c.GetString("role") == "admin" {
+		return true, nil
+	}
+	sp, err := db.GetStudentProfile(c, studentID)
+	if err == nil && sp.UserID == c.GetString("userID") {
+		return true, nil
+	}
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return false, err
+	}
+	apps, err := db.GetApplications(c, map[string]interface{}{"student_id": studentID})
+	if err != nil {
+		return false, err
+	}
+	seen := map[string]bool{}
+	for _, a := range apps {
+		job, err := db.GetJobPosting(c, a.JobID)
+		if errors.Is(err, db.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if seen[job.CompanyID] {
+			continue
+		}
+		seen[job.CompanyID] = true
+		if companyRoleFor(c, job.CompanyID) != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DownloadResume streams a resume file from the blob store. Callers who may
+// not see it get the same 404 as for a missing resume.
+func DownloadResume(c *gin.Context) {
+	r, err := db.GetResume(c, c.Param("id"))
+	if err != nil {
+		fail(c, http.StatusNotFound, "resume not found")
+		return
+	}
+	if ok, err := canViewResumes(c, r.StudentID); err != nil {
+		failErr(c, err, "lookup failed")
+		return
+	} else if !ok {
+		fail(c, http.StatusNotFound, "resume not found")
+		return
+	}
+	if r.BlobKey == "" {
+		fail(c, http.StatusNotFound, "no file stored for this resume")
+		return
+	}
+	f, err := storage.Open(r.BlobKey)
+	if err != nil {
+		fail(c, http.StatusNotFound, "resume missing from blob store")
+		return
+	}
+	defer f.Close()
+	ct := r.ContentType
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", r.FileName))
+	c.DataFromReader(http.StatusOK, r.Size, ct, f, nil)
+}
+
+// GetResumes lists a student's resumes. Without student_id admins get all
+// of them and students their own.
 func GetResumes(c *gin.Context) {
 	student := c.Query("student_id")
 	filter := map[string]interface{}{}
-	if student != "" {
+	switch {
+	case student != "":
+		if ok, err := canViewResumes(c, student); err != nil {
+			failErr(c, err, "lookup failed")
+			return
+		} else if !ok {
+			fail(c, http.StatusNotFound, "student not found")
+			return
+		}
 		filter["student_id"] = student
+	case c.GetString("role") != "admin":
+		own, err := ownStudentID(c)
+		if err != nil {
+			failErr(c, err, "list failed")
+			return
+		}
+		if own == "" {
+			c.JSON(http.StatusOK, gin.H{"data": []db.Resume{}})
+			return
+		}
+		filter["student_id"] = own
 	}
-	out, err := db.GetResumes(filter)
+	out, err := db.GetResumes(c, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "list failed"})
+		failErr(c, err, "list failed")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": out})
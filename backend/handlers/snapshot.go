@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"backend/db"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSnapshotSize caps an uploaded snapshot image
+const maxSnapshotSize = 512 << 20
+
+// noDeadlines lifts the server's read and write timeouts for a request that
+// moves a large body
+func noDeadlines(c *gin.Context) {
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+}
+
+// DownloadSnapshot streams a bbolt image of the memory or bolt store
+func DownloadSnapshot(c *gin.Context) {
+	if !db.UseInMemory() {
+		fail(c, http.StatusConflict, db.ErrSnapshotUnsupported.Error())
+		return
+	}
+	noDeadlines(c)
+	name := fmt.Sprintf("placement-%s.db", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Header("Content-Type", "application/octet-stream")
+	c.Status(http.StatusOK)
+	if err := db.Snapshot(c.Writer); err != nil {
+		// headers are already sent; the client sees a truncated download
+		c.Error(err)
+		return
+	}
+	recordAudit(c, "store.snapshot", "store", db.Mode(), nil, nil)
+}
+
+// RestoreSnapshot replaces all data with an uploaded snapshot (multipart "file")
+func RestoreSnapshot(c *gin.Context) {
+	if !db.UseInMemory() {
+		fail(c, http.StatusConflict, db.ErrSnapshotUnsupported.Error())
+		return
+	}
+	noDeadlines(c)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSnapshotSize)
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		fail(c, http.StatusBadRequest, "file required")
+		return
+	}
+	defer file.Close()
+	if err := db.Restore(file); err != nil {
+		failErr(c, err, "restore failed")
+		return
+	}
+	recordAudit(c, "store.restore", "store", db.Mode(), nil, nil)
+	respondNoContent(c, "restored")
+}
@@ -16,9 +16,9 @@ func GetStudentProfiles(c *gin.Context) {
 	if user != "" {
 		filter["user_id"] = user
 	}
-	out, err := db.GetStudentProfiles(filter)
+	out, err := db.GetStudentProfiles(c, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list"})
+		failErr(c, err, "failed to list")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": out})
@@ -27,47 +27,99 @@ func GetStudentProfiles(c *gin.Context) {
 func CreateStudentProfile(c *gin.Context) {
 	var sp db.StudentProfile
 	if err := c.BindJSON(&sp); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid"})
+		fail(c, http.StatusBadRequest, "invalid")
 		return
 	}
-	if sp.ID == "" {
-		sp.ID = uuid.New().String()
-	}
+	sp.ID = uuid.New().String()
 	now := time.Now().Format(time.RFC3339)
 	sp.CreatedAt = now
 	sp.UpdatedAt = now
-	if err := db.CreateStudentProfile(sp); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "insert failed"})
+	sp.Version = db.FirstVersion
+	if err := db.CreateStudentProfile(c, sp); err != nil {
+		failErr(c, err, "insert failed")
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"data": sp})
+	respondCreated(c, sp, sp.Version)
 }
 
-func UpdateStudentProfile(c *gin.Context) {
-	id := c.Param("id")
-	var patch map[string]interface{}
-	if err := c.BindJSON(&patch); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid"})
+// GetStudentProfile returns one student profile
+func GetStudentProfile(c *gin.Context) {
+	sp, err := db.GetStudentProfile(c, c.Param("id"))
+	if err != nil {
+		failErr(c, err, "lookup failed")
 		return
 	}
-	patch["updated_at"] = time.Now().Format(time.RFC3339)
+	respondResource(c, sp, sp.Version)
+}
 
-	if id == "" {
-		if u, ok := patch["user_id"].(string); ok && u != "" {
-			// try to find existing student profile for user
-			rows, err := db.GetStudentProfiles(map[string]interface{}{"user_id": u})
-			if err == nil && len(rows) > 0 {
-				id = rows[0].ID
-			}
+// ownStudentID returns the id of the caller's student profile, or "" when
+// they have none
+func ownStudentID(c *gin.Context) (string, error) {
+	own, err := db.GetStudentProfiles(c, map[string]interface{}{"user_id": c.GetString("userID")})
+	if err != nil || len(own) == 0 {
+		return "", err
+	}
+	return own[0].ID, nil
+}
+
+// studentIDFor picks the student a write acts for: admins name one in
+// requested, everyone else acts for their own student profile whatever the
+// request says. It writes the error response when there is none.
+func studentIDFor(c *gin.Context, requested string) (string, bool) {
+	if c.GetString("role") == "admin" {
+		if requested == "" {
+			fail(c, http.StatusBadRequest, "student_id required")
+			return "", false
 		}
+		return requested, true
+	}
+	id, err := ownStudentID(c)
+	if err != nil {
+		failErr(c, err, "lookup failed")
+		return "", false
 	}
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+		fail(c, http.StatusForbidden, "a student profile is required")
+		return "", false
+	}
+	return id, true
+}
+
+// studentProfilePatchIgnored are keys an update may echo but never changes
+var studentProfilePatchIgnored = []string{"user_id"}
+
+// UpdateStudentProfile changes a student profile; only its student and
+// admins may
+func UpdateStudentProfile(c *gin.Context) {
+	id := c.Param("id")
+	before, err := db.GetStudentProfile(c, id)
+	if err != nil {
+		failErr(c, err, "lookup failed")
+		return
+	}
+	if before.UserID != c.GetString("userID") && c.GetString("role") != "admin" {
+		fail(c, http.StatusForbidden, "not allowed for this profile")
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		fail(c, http.StatusBadRequest, "invalid")
+		return
+	}
+	var patch db.StudentProfilePatch
+	// the profile stays with its user; an echoed user_id is dropped
+	if !decodePatch(c, body, &patch, studentProfilePatchIgnored...) {
+		return
+	}
+	now := time.Now().Format(time.RFC3339)
+	patch.UpdatedAt = &now
+	if !expectVersion(c, before.Version, &patch.IfVersion) {
 		return
 	}
-	if err := db.UpdateStudentProfile(id, patch); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+	if err := db.UpdateStudentProfile(c, id, patch); err != nil {
+		failErr(c, err, "update failed")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": patch})
+	after, _ := db.GetStudentProfile(c, id)
+	respondUpdated(c, after, patch, after.Version)
 }
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"backend/db"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUpdateStudentProfileAccess(t *testing.T) {
+	withMemory(t)
+	if err := db.CreateStudentProfile(context.Background(), db.StudentProfile{ID: "s1", UserID: "u1", RollNumber: "CS-001"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		user, role string
+		id         string
+		want       int
+	}{
+		{name: "another student", user: "u2", role: "student", id: "s1", want: http.StatusForbidden},
+		{name: "a recruiter", user: "r1", role: "recruiter", id: "s1", want: http.StatusForbidden},
+		{name: "the student", user: "u1", role: "student", id: "s1", want: http.StatusOK},
+		{name: "an admin", user: "a1", role: "admin", id: "s1", want: http.StatusOK},
+		{name: "missing profile", user: "a1", role: "admin", id: "s9", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("userID", tt.user)
+				c.Set("role", tt.role)
+			})
+			r.PUT("/api/student_profiles/:id", UpdateStudentProfile)
+			req := httptest.NewRequest(http.MethodPut, "/api/student_profiles/"+tt.id, strings.NewReader(`{"cgpa":9.9}`))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("answered %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxUploadSize caps an uploaded resume or verification document. main sets
+// it from MAX_UPLOAD_BYTES.
+var MaxUploadSize int64 = 10 << 20
+
+// Document types accepted for upload
+const (
+	contentTypePDF  = "application/pdf"
+	contentTypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+)
+
+// documentExt maps an accepted content type to the extension its blob is stored under
+var documentExt = map[string]string{contentTypePDF: ".pdf", contentTypeDOCX: ".docx"}
+
+// errUnsupportedDocument is returned by sniffDocument for anything but PDF or DOCX
+var errUnsupportedDocument = errors.New("only PDF and DOCX files are accepted")
+
+// sniffDocument identifies an upload by its content rather than the name or
+// Content-Type the client sent. A DOCX is a zip archive, so it is opened to
+// check for the Word document part; other Office files and plain zips are
+// refused. The file is rewound for storing afterwards.
+func sniffDocument(file multipart.File, size int64) (string, error) {
+	head := make([]byte, 8)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	head = head[:n]
+	ct := ""
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		ct = contentTypePDF
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		zr, err := zip.NewReader(file, size)
+		if err != nil {
+			return "", errUnsupportedDocument
+		}
+		for _, f := range zr.File {
+			if f.Name == "word/document.xml" {
+				ct = contentTypeDOCX
+				break
+			}
+		}
+	}
+	if ct == "" {
+		return "", errUnsupportedDocument
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return ct, nil
+}
+
+// formDocument reads the multipart "file" field, enforcing MaxUploadSize and
+// the accepted document types. It writes the error response itself and
+// returns ok=false when the upload is refused; otherwise the caller closes
+// the file.
+func formDocument(c *gin.Context) (file multipart.File, header *multipart.FileHeader, contentType string, ok bool) {
+	// room for the other form fields on top of the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxUploadSize+1<<20)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			fail(c, http.StatusRequestEntityTooLarge, "file too large")
+		} else {
+			fail(c, http.StatusBadRequest, "file required")
+		}
+		return nil, nil, "", false
+	}
+	if header.Size > MaxUploadSize {
+		file.Close()
+		fail(c, http.StatusRequestEntityTooLarge, "file too large")
+		return nil, nil, "", false
+	}
+	contentType, err = sniffDocument(file, header.Size)
+	if err != nil {
+		file.Close()
+		fail(c, http.StatusUnsupportedMediaType, errUnsupportedDocument.Error())
+		return nil, nil, "", false
+	}
+	return file, header, contentType, true
+}
@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/middleware"
+	"backend/storage"
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type verificationDetails struct {
+	RegistrationNumber string `json:"registration_number"`
+	TaxID              string `json:"tax_id"`
+	Address            string `json:"address"`
+	ContactName        string `json:"contact_name"`
+	ContactPhone       string `json:"contact_phone"`
+}
+
+type verificationReview struct {
+	Decision string `json:"decision"`
+	Notes    string `json:"notes"`
+}
+
+// canManageCompany reports whether the caller may act on behalf of a company
+// (admin, owner or recruiter member)
+func canManageCompany(c *gin.Context, co db.Company) bool {
+	role := companyRoleFor(c, co.ID)
+	return role == "admin" || role == db.MemberOwner || role == db.MemberRecruiter
+}
+
+// companyVerified reports whether a company may publish jobs
+func companyVerified(ctx context.Context, companyID string) bool {
+	co, err := db.GetCompany(ctx, companyID)
+	return err == nil && (co.Verified || co.Approved)
+}
+
+// SubmitVerificationRequest opens a verification request for a company.
+// Only one request may be open (pending or changes_requested) at a time.
+func SubmitVerificationRequest(c *gin.Context) {
+	co, err := db.GetCompany(c, c.Param("id"))
+	if err != nil {
+		fail(c, http.StatusNotFound, "company not found")
+		return
+	}
+	if !canManageCompany(c, co) {
+		fail(c, http.StatusForbidden, "not allowed for this company")
+		return
+	}
+	if co.Verified {
+		fail(c, http.StatusConflict, "company already verified")
+		return
+	}
+	open, err := db.GetVerificationRequests(c, map[string]interface{}{"company_id": co.ID})
+	if err != nil {
+		failErr(c, err, "list failed")
+		return
+	}
+	for _, v := range open {
+		if v.Status == db.VerificationPending || v.Status == db.VerificationChangesRequested {
+			middleware.AbortWithError(c, http.StatusConflict, middleware.CodeConflict, "a verification request is already open", gin.H{"request": v})
+			return
+		}
+	}
+	var d verificationDetails
+	if err := c.BindJSON(&d); err != nil {
+		fail(c, http.StatusBadRequest, "invalid")
+		return
+	}
+	if d.RegistrationNumber == "" {
+		fail(c, http.StatusBadRequest, "registration_number required")
+		return
+	}
+	now := time.Now().Format(time.RFC3339)
+	v := db.VerificationRequest{
+		ID:                 uuid.New().String(),
+		CompanyID:          co.ID,
+		SubmittedBy:        c.GetString("userID"),
+		RegistrationNumber: d.RegistrationNumber,
+		TaxID:              d.TaxID,
+		Address:            d.Address,
+		ContactName:        d.ContactName,
+		ContactPhone:       d.ContactPhone,
+		Documents:          []db.VerificationDocument{},
+		Status:             db.VerificationPending,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+		Version:            db.FirstVersion,
+	}
+	if err := db.CreateVerificationRequest(c, v); err != nil {
+		failErr(c, err, "insert failed")
+		return
+	}
+	recordAudit(c, "verification.submit", "verification_request", v.ID, nil, v)
+	respondCreated(c, v, v.Version)
+}
+
+// GetVerificationRequests lists requests. Admins may list everything; other
+// callers must pass a company_id they manage.
+func GetVerificationRequests(c *gin.Context) {
+	filter := map[string]interface{}{}
+	company := c.Query("company_id")
+	if company != "" {
+		filter["company_id"] = company
+	}
+	if s := c.Query("status"); s != "" {
+		filter["status"] = s
+	}
+	if c.GetString("role") != "admin" {
+		co, err := db.GetCompany(c, company)
+		if company == "" || err != nil || !canManageCompany(c, co) {
+			fail(c, http.StatusForbidden, "company_id for a company you manage is required")
+			return
+		}
+	}
+	out, err := db.GetVerificationRequests(c, filter)
+	if err != nil {
+		failErr(c, err, "list failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out})
+}
+
+// loadManagedVerificationRequest fetches a request and checks the caller may
+// act on its company. It writes the error response itself.
+func loadManagedVerificationRequest(c *gin.Context) (db.VerificationRequest, bool) {
+	v, err := db.GetVerificationRequest(c, c.Param("id"))
+	if err != nil {
+		fail(c, http.StatusNotFound, "not found")
+		return v, false
+	}
+	co, err := db.GetCompany(c, v.CompanyID)
+	if err != nil || !canManageCompany(c, co) {
+		fail(c, http.StatusForbidden, "not allowed for this company")
+		return v, false
+	}
+	return v, true
+}
+
+// GetVerificationRequest returns one request to its company's managers
+func GetVerificationRequest(c *gin.Context) {
+	v, ok := loadManagedVerificationRequest(c)
+	if !ok {
+		return
+	}
+	respondResource(c, v, v.Version)
+}
+
+// UpdateVerificationRequest lets the recruiter amend details while the
+// request is open. Amending a changes_requested request resubmits it.
+func UpdateVerificationRequest(c *gin.Context) {
+	before, ok := loadManagedVerificationRequest(c)
+	if !ok {
+		return
+	}
+	if before.Status != db.VerificationPending && before.Status != db.VerificationChangesRequested {
+		fail(c, http.StatusConflict, "verification request is closed")
+		return
+	}
+	var ifVersion *int64
+	if !expectVersion(c, before.Version, &ifVersion) {
+		return
+	}
+	var d verificationDetails
+	if err := c.BindJSON(&d); err != nil {
+		fail(c, http.StatusBadRequest, "invalid")
+		return
+	}
+	patch := map[string]interface{}{
+		"status":     db.VerificationPending,
+		"updated_at": time.Now().Format(time.RFC3339),
+	}
+	if d.RegistrationNumber != "" {
+		patch["registration_number"] = d.RegistrationNumber
+	}
+	if d.TaxID != "" {
+		patch["tax_id"] = d.TaxID
+	}
+	if d.Address != "" {
+		patch["address"] = d.Address
+	}
+	if d.ContactName != "" {
+		patch["contact_name"] = d.ContactName
+	}
+	if d.ContactPhone != "" {
+		patch["contact_phone"] = d.ContactPhone
+	}
+	if err := db.UpdateVerificationRequest(c, before.ID, patch, ifVersion); err != nil {
+		failErr(c, err, "update failed")
+		return
+	}
+	after, _ := db.GetVerificationRequest(c, before.ID)
+	recordAudit(c, "verification.update", "verification_request", before.ID, before, after)
+	respondUpdated(c, after, after, after.Version)
+}
+
+// UploadVerificationDocument stores a multipart "file" (PDF or DOCX) in the
+// blob store and attaches it to an open verification request.
+func UploadVerificationDocument(c *gin.Context) {
+	v, ok := loadManagedVerificationRequest(c)
+	if !ok {
+		return
+	}
+	if v.Status != db.VerificationPending && v.Status != db.VerificationChangesRequested {
+		fail(c, http.StatusConflict, "verification request is closed")
+		return
+	}
+	file, header, contentType, ok := formDocument(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	doc := db.VerificationDocument{
+		ID:          uuid.New().String(),
+		Name:        filepath.Base(header.Filename),
+		ContentType: contentType,
+		UploadedAt:  time.Now().Format(time.RFC3339),
+	}
+	doc.BlobKey = fmt.Sprintf("verification/%s/%s%s", v.ID, doc.ID, documentExt[contentType])
+	n, err := storage.Put(doc.BlobKey, file)
+	if err != nil {
+		failErr(c, err, "upload failed")
+		return
+	}
+	doc.Size = n
+	if err := db.AddVerificationDocument(c, v.ID, doc, doc.UploadedAt); err != nil {
+		_ = storage.Delete(doc.BlobKey)
+		failErr(c, err, "update failed")
+		return
+	}
+	recordAudit(c, "verification.document_upload", "verification_request", v.ID, nil, doc)
+	c.JSON(http.StatusCreated, gin.H{"data": doc})
+}
+
+// DownloadVerificationDocument streams a document from the blob store
+func DownloadVerificationDocument(c *gin.Context) {
+	v, ok := loadManagedVerificationRequest(c)
+	if !ok {
+		return
+	}
+	docID := c.Param("doc_id")
+	for _, d := range v.Documents {
+		if d.ID != docID {
+			continue
+		}
+		r, err := storage.Open(d.BlobKey)
+		if err != nil {
+			fail(c, http.StatusNotFound, "document missing from blob store")
+			return
+		}
+		defer r.Close()
+		ct := d.ContentType
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", d.Name))
+		c.DataFromReader(http.StatusOK, d.Size, ct, r, nil)
+		return
+	}
+	fail(c, http.StatusNotFound, "document not found")
+}
+
+// ReviewVerificationRequest records an admin decision (approve, reject or
+// request_changes), updates the company's verified flag and notifies the
+// member who submitted the request. Notes are required unless approving.
+func ReviewVerificationRequest(c *gin.Context) {
+	v, err := db.GetVerificationRequest(c, c.Param("id"))
+	if err != nil {
+		failErr(c, err, "lookup failed")
+		return
+	}
+	if v.Status != db.VerificationPending {
+		fail(c, http.StatusConflict, "only pending requests can be reviewed")
+		return
+	}
+	var req verificationReview
+	if err := c.BindJSON(&req); err != nil {
+		fail(c, http.StatusBadRequest, "invalid")
+		return
+	}
+	var status, title string
+	switch req.Decision {
+	case "approve":
+		status, title = db.VerificationApproved, "Company verified"
+	case "reject":
+		status, title = db.VerificationRejected, "Company verification rejected"
+	case "request_changes":
+		status, title = db.VerificationChangesRequested, "Changes requested for company verification"
+	default:
+		fail(c, http.StatusBadRequest, "decision must be approve, reject or request_changes")
+		return
+	}
+	if status != db.VerificationApproved && req.Notes == "" {
+		fail(c, http.StatusBadRequest, "notes required")
+		return
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	patch := map[string]interface{}{
+		"status":       status,
+		"review_notes": req.Notes,
+		"reviewed_by":  c.GetString("userID"),
+		"reviewed_at":  now,
+		"updated_at":   now,
+	}
+	// the company's flag follows the decision in the same unit of work
+	coBefore, err := db.GetCompany(c, v.CompanyID)
+	if err != nil {
+		failErr(c, err, "lookup failed")
+		return
+	}
+	verified := status == db.VerificationApproved
+	var coPatch *db.CompanyPatch
+	var events []db.Event
+	if coBefore.Verified != verified {
+		coPatch = &db.CompanyPatch{Verified: &verified, Approved: &verified, UpdatedAt: &now, IfVersion: &coBefore.Version}
+		if verified {
+			events = append(events, db.CompanyVerifiedEvent(coBefore))
+		}
+	}
+	// decided on the request and company as read; a concurrent change fails it
+	if err := db.ReviewVerificationRequest(c, v.ID, patch, &v.Version, v.CompanyID, coPatch, events...); err != nil {
+		failErr(c, err, "update failed")
+		return
+	}
+	after, err := db.GetVerificationRequest(c, v.ID)
+	if err != nil {
+		failErr(c, err, "lookup failed")
+		return
+	}
+	recordAudit(c, "verification.review", "verification_request", v.ID, v, after)
+	if coPatch != nil {
+		coAfter, err := db.GetCompany(c, v.CompanyID)
+		if err != nil {
+			failErr(c, err, "lookup failed")
+			return
+		}
+		recordAudit(c, "company.verify", "company", v.CompanyID, coBefore, coAfter)
+	}
+
+	msg := fmt.Sprintf("Your verification request for %s was reviewed: %s.", coBefore.Name, status)
+	if req.Notes != "" {
+		msg += " Notes: " + req.Notes
+	}
+	notifyUser(c, v.SubmittedBy, title, msg, "general")
+	c.JSON(http.StatusOK, gin.H{"data": after})
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"backend/db"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The decision goes to the member who submitted the request, not to the
+// company's creator
+func TestReviewNotifiesSubmitter(t *testing.T) {
+	withMemory(t)
+	ctx := context.Background()
+	if err := db.CreateCompany(ctx, db.Company{ID: "c1", Name: "Acme", RecruiterID: "creator", Version: db.FirstVersion}); err != nil {
+		t.Fatal(err)
+	}
+	v := db.VerificationRequest{ID: "v1", CompanyID: "c1", Status: db.VerificationPending, SubmittedBy: "member", Version: db.FirstVersion}
+	if err := db.CreateVerificationRequest(ctx, v); err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", "admin-1")
+		c.Set("role", "admin")
+	})
+	r.POST("/api/admin/verification_requests/:id/review", ReviewVerificationRequest)
+	review := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/verification_requests/"+id+"/review", strings.NewReader(`{"decision":"approve"}`))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := review("v9"); w.Code != http.StatusNotFound {
+		t.Fatalf("review of a missing request answered %d: %s", w.Code, w.Body)
+	}
+	if w := review("v1"); w.Code != http.StatusOK {
+		t.Fatalf("review answered %d: %s", w.Code, w.Body)
+	}
+	for user, want := range map[string]int{"member": 1, "creator": 0} {
+		got, err := db.GetNotifications(ctx, map[string]interface{}{"user_id": user})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != want {
+			t.Errorf("%s has %d notifications, want %d", user, len(got), want)
+		}
+	}
+}
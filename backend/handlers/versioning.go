@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"backend/middleware"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The handlers serve two API versions. /api is the original API, kept as is
+// for existing clients: updates echo the patch they were sent and deletes
+// answer with a message. /api/v1 answers every write with the stored
+// resource, or 204 when nothing is left to return, and adds reads by id.
+// Both send the record's version as its ETag and honour If-Match on updates.
+
+const apiVersionKey = "apiVersion"
+
+// V1 marks the requests of the /api/v1 routes
+func V1() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, 1)
+		c.Next()
+	}
+}
+
+// isV1 reports whether the request came in under /api/v1
+func isV1(c *gin.Context) bool {
+	return c.GetInt(apiVersionKey) >= 1
+}
+
+// etag is the ETag of a record at version
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// expectVersion makes an update conditional on the record's current
+// version, the one the handler read and checked, storing it in ifVersion.
+// An If-Match header must name that version: a stale one writes a 409 with
+// the current ETag. Without the header the check still catches writes that
+// land between the handler's read and its update.
+func expectVersion(c *gin.Context, current int64, ifVersion **int64) bool {
+	if header := c.GetHeader("If-Match"); header != "" {
+		matched := false
+		for _, t := range strings.Split(header, ",") {
+			if t = strings.TrimSpace(t); t == "*" || t == etag(current) {
+				matched = true
+			}
+		}
+		if !matched {
+			c.Header("ETag", etag(current))
+			middleware.AbortWithError(c, http.StatusConflict, middleware.CodeConflict,
+				"resource has changed since it was read", gin.H{"field": "version"})
+			return false
+		}
+	}
+	*ifVersion = &current
+	return true
+}
+
+// respondCreated writes a new record with its ETag
+func respondCreated(c *gin.Context, v interface{}, version int64) {
+	c.Header("ETag", etag(version))
+	c.JSON(http.StatusCreated, gin.H{"data": v})
+}
+
+// respondResource writes one record with its ETag
+func respondResource(c *gin.Context, v interface{}, version int64) {
+	c.Header("ETag", etag(version))
+	c.JSON(http.StatusOK, gin.H{"data": v})
+}
+
+// respondUpdated answers an update with the stored resource on v1 and with
+// the applied patch on /api
+func respondUpdated(c *gin.Context, after, patch interface{}, version int64) {
+	c.Header("ETag", etag(version))
+	if isV1(c) {
+		c.JSON(http.StatusOK, gin.H{"data": after})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": patch})
+}
+
+// respondNoContent answers a write that leaves nothing to return: 204 on v1,
+// message on /api
+func respondNoContent(c *gin.Context, message string) {
+	if isV1(c) {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": message})
+}
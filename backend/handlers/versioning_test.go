@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"backend/config"
+	"backend/db"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+// withMemory gives the test an empty in-memory store
+func withMemory(t *testing.T) {
+	t.Helper()
+	db.Init(config.DB{Driver: db.ModeMemory, Fallback: db.FallbackMemory})
+}
+
+// errorField returns details.field of an error response
+func errorField(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Error struct {
+			Details struct {
+				Field string `json:"field"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("error body %q: %v", w.Body.String(), err)
+	}
+	return body.Error.Details.Field
+}
+
+func TestExpectVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		ok      bool
+	}{
+		{name: "no header", ok: true},
+		{name: "current version", ifMatch: `"4"`, ok: true},
+		{name: "any version", ifMatch: `*`, ok: true},
+		{name: "one of several", ifMatch: `"3", "4"`, ok: true},
+		{name: "stale version", ifMatch: `"3"`},
+		{name: "unquoted", ifMatch: `4`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPut, "/", nil)
+			if tt.ifMatch != "" {
+				c.Request.Header.Set("If-Match", tt.ifMatch)
+			}
+			var ifVersion *int64
+			if got := expectVersion(c, 4, &ifVersion); got != tt.ok {
+				t.Fatalf("expectVersion = %v, want %v", got, tt.ok)
+			}
+			if !tt.ok {
+				if w.Code != http.StatusConflict || w.Header().Get("ETag") != `"4"` || errorField(t, w) != "version" {
+					t.Fatalf("stale If-Match answered %d, ETag %q: %s", w.Code, w.Header().Get("ETag"), w.Body)
+				}
+				return
+			}
+			// the update is still conditional on the version the handler read
+			if ifVersion == nil || *ifVersion != 4 {
+				t.Fatalf("ifVersion = %v, want 4", ifVersion)
+			}
+		})
+	}
+}
+
+func TestUpdateIfMatch(t *testing.T) {
+	withMemory(t)
+	co := db.Company{ID: "c1", Name: "Acme", Version: db.FirstVersion}
+	if err := db.CreateCompany(context.Background(), co); err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", "admin-1")
+		c.Set("role", "admin")
+	})
+	r.PUT("/api/v1/companies/:id", V1(), UpdateCompany)
+
+	put := func(ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/companies/c1", strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := put(`"1"`, `{"name":"Acme Labs"}`); w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("update at the current version answered %d, ETag %q: %s", w.Code, w.Header().Get("ETag"), w.Body)
+	}
+	w := put(`"1"`, `{"name":"Lost Update"}`)
+	if w.Code != http.StatusConflict || w.Header().Get("ETag") != `"2"` || errorField(t, w) != "version" {
+		t.Fatalf("update at a stale version answered %d, ETag %q: %s", w.Code, w.Header().Get("ETag"), w.Body)
+	}
+	if got, _ := db.GetCompany(context.Background(), "c1"); got.Name != "Acme Labs" || got.Version != 2 {
+		t.Fatalf("company after the stale update = %+v", got)
+	}
+	if w := put("", `{"name":"Acme Inc"}`); w.Code != http.StatusOK || w.Header().Get("ETag") != `"3"` {
+		t.Fatalf("unconditional update answered %d, ETag %q: %s", w.Code, w.Header().Get("ETag"), w.Body)
+	}
+}
+
+// A write that lands between a handler's read and its update is a 409 on
+// version even without If-Match
+func TestStaleVersionConflict(t *testing.T) {
+	withMemory(t)
+	ctx := context.Background()
+	if err := db.CreateCompany(ctx, db.Company{ID: "c1", Name: "Acme", Version: db.FirstVersion}); err != nil {
+		t.Fatal(err)
+	}
+	read := db.FirstVersion
+	name := "Concurrent"
+	if err := db.UpdateCompany(ctx, "c1", db.CompanyPatch{Name: &name}); err != nil {
+		t.Fatal(err)
+	}
+	err := db.UpdateCompany(ctx, "c1", db.CompanyPatch{Name: &name, IfVersion: &read})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/", nil)
+	failErr(c, err, "update failed")
+	if w.Code != http.StatusConflict || errorField(t, w) != "version" {
+		t.Fatalf("stale update answered %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/webhooks"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type webhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+// createdWebhook is a new webhook with its secret, which is only shown once
+type createdWebhook struct {
+	db.Webhook
+	Secret string `json:"secret"`
+}
+
+// validWebhookURL checks that u is an absolute http(s) URL, https unless
+// the webhooks package allows private receivers
+func validWebhookURL(u string) bool {
+	p, err := url.Parse(u)
+	if err != nil || p.Host == "" || p.User != nil {
+		return false
+	}
+	return p.Scheme == "https" || (p.Scheme == "http" && webhooks.AllowPrivate())
+}
+
+// newWebhookSecret returns a random signing secret
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// GetWebhooks lists a company's webhooks; owners and recruiters may view them
+func GetWebhooks(c *gin.Context) {
+	companyID := c.Param("id")
+	if !requireCompanyRole(c, companyID, companyManagers...) {
+		return
+	}
+	out, err := db.GetWebhooks(c, companyID)
+	if err != nil {
+		failErr(c, err, "list failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out})
+}
+
+// CreateWebhook registers a webhook for some of the company's events. The
+// response carries the signing secret, which later reads leave out.
+func CreateWebhook(c *gin.Context) {
+	companyID := c.Param("id")
+	if _, err := db.GetCompany(c, companyID); err != nil {
+		fail(c, http.StatusNotFound, "company not found")
+		return
+	}
+	if !requireCompanyRole(c, companyID, companyManagers...) {
+		return
+	}
+	var req webhookRequest
+	if err := c.BindJSON(&req); err != nil {
+		fail(c, http.StatusBadRequest, "invalid")
+		return
+	}
+	if !validWebhookURL(req.URL) {
+		if webhooks.AllowPrivate() {
+			fail(c, http.StatusBadRequest, "url must be an absolute http or https URL")
+		} else {
+			fail(c, http.StatusBadRequest, "url must be an absolute https URL")
+		}
+		return
+	}
+	if err := webhooks.CheckURL(c, req.URL); errors.Is(err, webhooks.ErrPrivateAddress) {
+		fail(c, http.StatusBadRequest, "url must point to a public address")
+		return
+	} else if err != nil {
+		fail(c, http.StatusBadRequest, "url host could not be resolved")
+		return
+	}
+	if len(req.EventTypes) == 0 {
+		fail(c, http.StatusBadRequest, "event_types required")
+		return
+	}
+	for _, t := range req.EventTypes {
+		if !slices.Contains(db.WebhookEventTypes, t) {
+			fail(c, http.StatusBadRequest, "event_types must be among "+strings.Join(db.WebhookEventTypes, ", "))
+			return
+		}
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		failErr(c, err, "insert failed")
+		return
+	}
+	w := db.Webhook{
+		ID:         uuid.New().String(),
+		CompanyID:  companyID,
+		URL:        req.URL,
+		EventTypes: slices.Compact(slices.Sorted(slices.Values(req.EventTypes))),
+		Secret:     secret,
+		CreatedBy:  c.GetString("userID"),
+		CreatedAt:  time.Now().Format(time.RFC3339),
+	}
+	if err := db.CreateWebhook(c, w); err != nil {
+		failErr(c, err, "insert failed")
+		return
+	}
+	recordAudit(c, "company.webhook_create", "company", companyID, nil, w)
+	c.JSON(http.StatusCreated, gin.H{"data": createdWebhook{Webhook: w, Secret: secret}})
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func DeleteWebhook(c *gin.Context) {
+	companyID := c.Param("id")
+	if !requireCompanyRole(c, companyID, companyManagers...) {
+		return
+	}
+	before, err := db.GetWebhook(c, c.Param("webhook_id"))
+	if err != nil || before.CompanyID != companyID {
+		fail(c, http.StatusNotFound, "webhook not found")
+		return
+	}
+	if err := db.DeleteWebhook(c, companyID, before.ID); err != nil {
+		failErr(c, err, "delete failed")
+		return
+	}
+	recordAudit(c, "company.webhook_delete", "company", companyID, before, nil)
+	respondNoContent(c, "deleted")
+}
+
+// GetWebhookDeliveries is the company's delivery log, newest first,
+// optionally narrowed to one webhook or status
+func GetWebhookDeliveries(c *gin.Context) {
+	companyID := c.Param("id")
+	if !requireCompanyRole(c, companyID, companyManagers...) {
+		return
+	}
+	f := db.DeliveryFilter{
+		CompanyID: companyID,
+		WebhookID: c.Query("webhook_id"),
+		Status:    c.Query("status"),
+		Limit:     100,
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			fail(c, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		f.Limit = n
+	}
+	out, err := db.GetWebhookDeliveries(c, f)
+	if err != nil {
+		failErr(c, err, "list failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out})
+}
+
+// ReplayWebhookDelivery sends a delivery's event again as a new delivery,
+// whatever the outcome of the original. The send happens in the background,
+// so the answer is 202 with the new delivery.
+func ReplayWebhookDelivery(c *gin.Context) {
+	companyID := c.Param("id")
+	if !requireCompanyRole(c, companyID, companyManagers...) {
+		return
+	}
+	orig, err := db.GetWebhookDelivery(c, c.Param("delivery_id"))
+	if err != nil || orig.CompanyID != companyID {
+		fail(c, http.StatusNotFound, "delivery not found")
+		return
+	}
+	if _, err := db.GetWebhook(c, orig.WebhookID); errors.Is(err, db.ErrNotFound) {
+		fail(c, http.StatusNotFound, "webhook not found")
+		return
+	} else if err != nil {
+		failErr(c, err, "lookup failed")
+		return
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	d := db.WebhookDelivery{
+		ID:            uuid.New().String(),
+		WebhookID:     orig.WebhookID,
+		CompanyID:     orig.CompanyID,
+		EventID:       orig.EventID,
+		EventType:     orig.EventType,
+		Body:          orig.Body,
+		Status:        db.DeliveryPending,
+		NextAttemptAt: now,
+		ReplayOf:      orig.ID,
+		CreatedAt:     now,
+	}
+	if err := db.CreateWebhookDelivery(c, d); err != nil {
+		failErr(c, err, "insert failed")
+		return
+	}
+	recordAudit(c, "company.webhook_replay", "company", companyID, nil, d)
+	c.JSON(http.StatusAccepted, gin.H{"data": d})
+}
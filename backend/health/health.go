@@ -0,0 +1,142 @@
+// Package health runs the dependency checks behind the readiness probe and
+// the healthcheck subcommand, and reports the build version and uptime.
+package health
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Version is the build version, set at link time with
+// -ldflags "-X backend/health.Version=v1.2.3". Without it the VCS revision
+// recorded by the Go toolchain is reported.
+var Version = ""
+
+var started = time.Now()
+
+// Check statuses and overall report statuses
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDegraded = "degraded"
+)
+
+// Timeout bounds each check
+const Timeout = 3 * time.Second
+
+// Check is one dependency check. A failing critical check makes the service
+// not ready; other failures only mark it degraded.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Critical   bool    `json:"critical"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Report is the body of the health endpoints
+type Report struct {
+	Status        string   `json:"status"`
+	Version       string   `json:"version"`
+	StartedAt     string   `json:"started_at"`
+	UptimeSeconds int64    `json:"uptime_seconds"`
+	Checks        []Result `json:"checks,omitempty"`
+}
+
+// Ready reports whether every critical check passed
+func (r Report) Ready() bool {
+	return r.Status != StatusFail
+}
+
+// BuildVersion returns Version, or the VCS revision (suffixed "-dirty" for
+// modified trees) when no version was linked in
+func BuildVersion() string {
+	if Version != "" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	rev, dirty := "", false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			rev = s.Value
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	if rev == "" {
+		return "dev"
+	}
+	if len(rev) > 12 {
+		rev = rev[:12]
+	}
+	if dirty {
+		rev += "-dirty"
+	}
+	return rev
+}
+
+// Live is the report of a running process, without dependency checks
+func Live() Report {
+	return Report{
+		Status:        StatusOK,
+		Version:       BuildVersion(),
+		StartedAt:     started.UTC().Format(time.RFC3339),
+		UptimeSeconds: int64(time.Since(started).Seconds()),
+	}
+}
+
+// Run executes checks concurrently, each bounded by Timeout, and returns
+// them in the order given
+func Run(ctx context.Context, checks []Check) Report {
+	rep := Live()
+	rep.Checks = make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Add(1)
+		go func(i int, chk Check) {
+			defer wg.Done()
+			rep.Checks[i] = run(ctx, chk)
+		}(i, chk)
+	}
+	wg.Wait()
+	for _, res := range rep.Checks {
+		switch {
+		case res.Status == StatusOK:
+		case res.Critical:
+			rep.Status = StatusFail
+		case rep.Status == StatusOK:
+			rep.Status = StatusDegraded
+		}
+	}
+	return rep
+}
+
+func run(ctx context.Context, chk Check) (res Result) {
+	res = Result{Name: chk.Name, Status: StatusOK, Critical: chk.Critical}
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			res.Status, res.Error = StatusFail, "check panicked"
+		}
+		res.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+	}()
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	if err := chk.Run(ctx); err != nil {
+		res.Status, res.Error = StatusFail, err.Error()
+	}
+	return res
+}
@@ -1,129 +1,156 @@
 package main
 
 import (
+	"backend/config"
 	"backend/db"
+	"backend/events"
 	"backend/handlers"
+	"backend/health"
 	"backend/middleware"
+	"backend/scheduler"
+	"backend/storage"
+	"backend/telemetry"
+	"backend/webhooks"
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
+// Server timeouts. Handlers that stream large bodies (backups, snapshots)
+// lift the read and write deadlines for their own request.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = time.Minute
+	writeTimeout      = time.Minute
+	idleTimeout       = 2 * time.Minute
+	// shutdownTimeout bounds how long in-flight requests may drain
+	shutdownTimeout = 30 * time.Second
+)
+
+// Outbox delivery. Dispatched events are kept for a week for inspection.
+const (
+	dispatchInterval = 5 * time.Second
+	outboxRetention  = 7 * 24 * time.Hour
+	webhookInterval  = 10 * time.Second
+)
+
 func main() {
-	// load .env if present so developers can keep local settings in backend/.env
-	loadDotEnv()
+	// settings come from flags, the environment and backend/.env (or -config)
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := telemetry.InitLogging(cfg.LogFormat, cfg.LogLevel); err != nil {
+		log.Fatal(err)
+	}
+
+	// administrative subcommands run and exit without starting the server
+	if len(args) > 0 {
+		os.Exit(runCommand(cfg, args))
+	}
+	slog.Info("config loaded", "env", cfg.Env, "file", cfg.File, "settings", cfg.Effective())
+
+	// spans go to the collector at OTEL_EXPORTER_OTLP_ENDPOINT, if set
+	shutdownTracing := telemetry.InitTracing(cfg.OTLPEndpoint)
 
 	// init DB
-	db.Init()
+	db.Init(cfg.DB)
 
 	// init firebase and jwt
-	middleware.InitFirebase()
-
-	r := gin.Default()
-
-	// CORS
-	allowed := []string{"http://localhost:5173", "http://localhost:5174"}
-	if v := os.Getenv("FRONTEND_ORIGINS"); v != "" {
-		// split comma-separated origins and trim spaces
-		parts := strings.Split(v, ",")
-		allowed = make([]string, 0, len(parts))
-		for _, p := range parts {
-			p = strings.TrimSpace(p)
-			if p != "" {
-				allowed = append(allowed, p)
-			}
-		}
+	middleware.InitFirebase(cfg.FirebaseServiceAccount, cfg.JWTSecret)
+
+	// blob store for uploaded documents
+	storage.Init(cfg.BlobDir)
+
+	// webhook receivers must have public https addresses unless
+	// WEBHOOK_ALLOW_PRIVATE, which production refuses
+	webhooks.Init(cfg.Webhooks)
+
+	// SIGINT or SIGTERM stops the background tasks and drains the server
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// domain events go from the outbox to their subscribers
+	dispatcher := events.NewDispatcher(handlers.EventSubscribers()...)
+
+	// background tasks: audit retention, job publish/close transitions and
+	// event delivery. Audit entries older than AUDIT_RETENTION_DAYS are
+	// purged; 0 keeps them forever.
+	waitTasks := scheduler.Start(ctx,
+		scheduler.Task{Name: "audit-retention", Interval: time.Hour, Run: handlers.AuditRetentionTask(time.Duration(cfg.AuditRetentionDays) * 24 * time.Hour)},
+		scheduler.Task{Name: "job-lifecycle", Interval: time.Minute, Run: handlers.RunJobLifecycle},
+		scheduler.Task{Name: "event-dispatch", Interval: dispatchInterval, Run: dispatcher.Run},
+		scheduler.Task{Name: "outbox-retention", Interval: time.Hour, Run: events.RetentionTask(outboxRetention)},
+		scheduler.Task{Name: "webhook-delivery", Interval: webhookInterval, Run: webhooks.Run},
+	)
+
+	// reconnects to the primary database and tracks its health
+	waitMonitor := db.StartMonitor(ctx)
+
+	// gin's own debug output (route table, warnings) is plain text; keep it
+	// for LOG_LEVEL=debug only
+	if cfg.LogLevel != "debug" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	r := newRouter(cfg)
+
+	port := strconv.Itoa(cfg.Port)
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
 	}
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     allowed,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-
-	api := r.Group("/api")
-	{
-		api.GET("/health", handlers.Health)
-		api.POST("/auth/google", handlers.GoogleAuth)
-
-		// public-ish profiles listing
-		api.GET("/profiles", handlers.GetProfiles)
-		api.POST("/profiles", handlers.CreateProfile)
-		api.PUT("/profiles/:id", handlers.UpdateProfile)
-
-		// student profiles
-		api.GET("/student_profiles", handlers.GetStudentProfiles)
-		api.POST("/student_profiles", handlers.CreateStudentProfile)
-		api.PUT("/student_profiles/:id", handlers.UpdateStudentProfile)
-
-		// companies
-		api.GET("/companies", handlers.GetCompanies)
-		api.POST("/companies", handlers.CreateCompany)
-		api.PUT("/companies/:id", handlers.UpdateCompany)
-
-		// jobs
-		api.GET("/job_postings", handlers.GetJobPostings)
-		api.POST("/job_postings", handlers.CreateJobPosting)
-		api.PUT("/job_postings/:id", handlers.UpdateJobPosting)
-		api.DELETE("/job_postings/:id", handlers.DeleteJobPosting)
-
-		// resumes
-		api.POST("/resumes/upload", handlers.UploadResume)
-		api.GET("/resumes", handlers.GetResumes)
-
-		// applications
-		api.GET("/applications", handlers.GetApplications)
-		api.POST("/applications", handlers.CreateApplication)
-		api.PUT("/applications/:id", handlers.UpdateApplication)
-
-		// interviews
-		api.POST("/interviews", handlers.CreateInterview)
+	errc := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "port", port)
+		errc <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-errc:
+		telemetry.Fatal("server failed", "error", err)
+	case <-ctx.Done():
 	}
+	stop()
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		// default to 8081 for local development to match frontend VITE_API_URL
-		port = "8081"
+	slog.Info("shutting down: draining in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("server did not drain in time", "error", err)
 	}
-	log.Println("listening on :", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal(err)
+	waitTasks()
+	waitMonitor()
+	if err := db.Close(); err != nil {
+		slog.Warn("closing the database", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Warn("flushing traces", "error", err)
 	}
+	slog.Info("server stopped")
 }
 
-// loadDotEnv reads a simple KEY=VALUE .env file in the backend folder and sets
-// the values into the process environment. It is intentionally small and
-// dependency-free to avoid adding a new module just for local development.
-func loadDotEnv() {
-	data, err := os.ReadFile(".env")
-	if err != nil {
-		return
-	}
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		// split on first '=' only
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		key := strings.TrimSpace(parts[0])
-		val := strings.TrimSpace(parts[1])
-		// strip surrounding quotes if present
-		if len(val) >= 2 && ((val[0] == '"' && val[len(val)-1] == '"') || (val[0] == '\'' && val[len(val)-1] == '\'')) {
-			val = val[1 : len(val)-1]
-		}
-		if key != "" {
-			_ = os.Setenv(key, val)
-		}
+// dependencyChecks are the readiness checks. The identity provider only
+// affects Google sign-in, so its failure degrades rather than fails readiness.
+func dependencyChecks() []health.Check {
+	return []health.Check{
+		{Name: "database", Critical: true, Run: db.Ping},
+		{Name: "blob_store", Critical: true, Run: func(context.Context) error { return storage.Probe() }},
+		{Name: "identity_provider", Run: middleware.CheckIdentityProvider},
 	}
 }
@@ -2,10 +2,12 @@ package middleware
 
 import (
 	"backend/db"
+	"backend/telemetry"
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
-	"os"
 	"strings"
 
 	firebase "firebase.google.com/go"
@@ -18,28 +20,58 @@ import (
 var FirebaseAuth *auth.Client
 var jwtSecret []byte
 
-func InitFirebase() {
-	optFile := os.Getenv("FIREBASE_SERVICE_ACCOUNT")
-	if optFile == "" {
-		optFile = "firebase_service_account.json"
-	}
-	opt := option.WithCredentialsFile(optFile)
+// InitFirebase sets up token verification with the service account at
+// credentialsFile and the key that signs the API's own JWTs
+func InitFirebase(credentialsFile, secret string) {
+	opt := option.WithCredentialsFile(credentialsFile)
 	app, err := firebase.NewApp(context.Background(), nil, opt)
 	if err != nil {
-		log.Fatal("Failed to initialize Firebase app:", err)
+		telemetry.Fatal("failed to initialize Firebase app", "error", err)
 	}
 
 	FirebaseAuth, err = app.Auth(context.Background())
 	if err != nil {
-		log.Fatal("Failed to initialize Firebase auth:", err)
-	}
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your-secret-key"
+		telemetry.Fatal("failed to initialize Firebase auth", "error", err)
 	}
+	InitTokens(secret)
+}
+
+// InitTokens sets the key that signs and verifies the API's own JWTs. On
+// its own, without InitFirebase, only those tokens are accepted.
+func InitTokens(secret string) {
 	jwtSecret = []byte(secret)
 }
 
+// googleCertsURL serves the keys Firebase ID tokens are signed with
+const googleCertsURL = "https://www.googleapis.com/robot/v1/metadata/x509/securetoken@system.gserviceaccount.com"
+
+// CheckIdentityProvider reports whether Google sign-in can work: the
+// Firebase client is initialized and the token signing keys can be fetched
+func CheckIdentityProvider(ctx context.Context) error {
+	if FirebaseAuth == nil {
+		return errors.New("firebase auth is not initialized")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, googleCertsURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching token signing keys: %s", resp.Status)
+	}
+	return nil
+}
+
+// SignToken issues a JWT for claims, signed with the key given to InitFirebase
+func SignToken(claims jwt.MapClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+}
+
 type AuthContext struct {
 	UserID string
 	Role   string
@@ -50,48 +82,26 @@ func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			AbortWithError(c, http.StatusUnauthorized, "", "Authorization header required", nil)
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Bearer token required"})
-			c.Abort()
+			AbortWithError(c, http.StatusUnauthorized, "", "Bearer token required", nil)
 			return
 		}
 
-		// Try JWT first
-		jwtToken, jwtErr := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-
-		var userID string
-		if jwtErr == nil && jwtToken != nil && jwtToken.Valid {
-			if claims, ok := jwtToken.Claims.(jwt.MapClaims); ok {
-				if uid, ok := claims["user_id"].(string); ok {
-					userID = uid
-				}
-			}
-		}
-
-		if userID == "" {
-			// fallback to Firebase verification
-			tok, err := FirebaseAuth.VerifyIDToken(context.Background(), tokenString)
-			if err != nil {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-				c.Abort()
-				return
-			}
-			userID = tok.UID
+		userID, err := resolveUserID(c, tokenString)
+		if err != nil {
+			AbortWithError(c, http.StatusUnauthorized, "", "Invalid token", nil)
+			return
 		}
 
 		// load profile (supports in-memory fallback)
-		profile, err := db.GetProfile(userID)
+		profile, err := db.GetProfile(c, userID)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Profile not found"})
-			c.Abort()
+			AbortWithError(c, http.StatusUnauthorized, "", "Profile not found", nil)
 			return
 		}
 
@@ -101,6 +111,68 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// OptionalAuthMiddleware resolves the caller when a valid bearer token is sent
+// but lets anonymous requests through. Handlers use it to attribute actions
+// (e.g. in the audit log) without requiring every client to authenticate.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+		if authHeader == "" || tokenString == authHeader {
+			c.Next()
+			return
+		}
+		userID, err := resolveUserID(c, tokenString)
+		if err != nil {
+			c.Next()
+			return
+		}
+		if profile, err := db.GetProfile(c, userID); err == nil {
+			c.Set("userID", userID)
+			c.Set("role", profile.Role)
+		}
+		c.Next()
+	}
+}
+
+// RequireRole rejects requests whose authenticated role is not in roles. It
+// must run after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		r, _ := role.(string)
+		for _, allowed := range roles {
+			if r == allowed {
+				c.Next()
+				return
+			}
+		}
+		AbortWithError(c, http.StatusForbidden, "", "Insufficient permissions", nil)
+	}
+}
+
+// resolveUserID verifies a custom JWT first and falls back to a Firebase ID token
+func resolveUserID(ctx context.Context, tokenString string) (string, error) {
+	jwtToken, jwtErr := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	})
+	if jwtErr == nil && jwtToken != nil && jwtToken.Valid {
+		if claims, ok := jwtToken.Claims.(jwt.MapClaims); ok {
+			if uid, ok := claims["user_id"].(string); ok && uid != "" {
+				return uid, nil
+			}
+		}
+	}
+	if FirebaseAuth == nil {
+		return "", errors.New("invalid token")
+	}
+	tok, err := FirebaseAuth.VerifyIDToken(ctx, tokenString)
+	if err != nil {
+		return "", err
+	}
+	return tok.UID, nil
+}
+
 func GetAuthContext(c *gin.Context) (string, string) {
 	userID, _ := c.Get("userID")
 	role, _ := c.Get("role")
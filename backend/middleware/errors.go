@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error codes used in the "code" field of error responses
+const (
+	CodeBadRequest      = "bad_request"
+	CodeUnauthorized    = "unauthorized"
+	CodeForbidden       = "forbidden"
+	CodeNotFound        = "not_found"
+	CodeConflict        = "conflict"
+	CodePayloadTooLarge = "payload_too_large"
+	CodeValidation      = "validation_failed"
+	CodeRateLimited     = "rate_limited"
+	CodeInternal        = "internal_error"
+	CodeUnavailable     = "unavailable"
+)
+
+// ErrorBody is the payload under "error" in every error response
+type ErrorBody struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// CodeForStatus returns the default error code for an HTTP status
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnprocessableEntity:
+		return CodeValidation
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// AbortWithError writes {error:{code,message,details,request_id}} and stops
+// the handler chain
+func AbortWithError(c *gin.Context, status int, code, message string, details interface{}) {
+	if code == "" {
+		code = CodeForStatus(status)
+	}
+	c.Set(errorCodeKey, code)
+	c.AbortWithStatusJSON(status, gin.H{"error": ErrorBody{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: c.GetString(RequestIDKey),
+	}})
+}
+
+// Recovery turns panics into a 500 in the standard error shape. The panic
+// and its stack are logged here rather than by gin, so they stay in JSON.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered interface{}) {
+		slog.ErrorContext(c, "panic recovered", "panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+		AbortWithError(c, http.StatusInternalServerError, CodeInternal, "internal error", nil)
+	})
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"backend/telemetry"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// errorCodeKey holds the code of an error response written by AbortWithError
+const errorCodeKey = "errorCode"
+
+var (
+	httpRequests = telemetry.NewCounter("http_requests_total",
+		"HTTP requests by route and status.", "method", "route", "status")
+	httpDuration = telemetry.NewHistogram("http_request_duration_seconds",
+		"HTTP request latency by route and status class.", telemetry.DurationBuckets, "method", "route", "status_class")
+	httpErrors = telemetry.NewCounter("http_request_errors_total",
+		"HTTP error responses by route and error code.", "method", "route", "code")
+)
+
+// route is the matched route pattern, which keeps metric labels and span
+// names bounded; requests that match nothing share one value
+func route(c *gin.Context) string {
+	if r := c.FullPath(); r != "" {
+		return r
+	}
+	return "unmatched"
+}
+
+// Logger writes one structured access log record per request. The request
+// and user ids are added by the telemetry log handler.
+func Logger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", route(c)),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+		}
+		if code := c.GetString(errorCodeKey); code != "" {
+			attrs = append(attrs, slog.String("error_code", code))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		slog.LogAttrs(c, level, "request", attrs...)
+	}
+}
+
+// Metrics counts requests and records their latency per route
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		status := c.Writer.Status()
+		method, r := c.Request.Method, route(c)
+		httpRequests.Inc(method, r, strconv.Itoa(status))
+		httpDuration.ObserveSince(start, method, r, strconv.Itoa(status/100)+"xx")
+		if status >= 400 {
+			code := c.GetString(errorCodeKey)
+			if code == "" {
+				code = CodeForStatus(status)
+			}
+			httpErrors.Inc(method, r, code)
+		}
+	}
+}
+
+// Trace wraps each request in a server span, continuing a trace passed in
+// the traceparent header. The span rides on the request context, so the
+// db calls a handler makes with the gin context become its children.
+func Trace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		r := route(c)
+		ctx, span := telemetry.Tracer().Start(ctx, c.Request.Method+" "+r,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", r),
+				attribute.String("url.path", c.Request.URL.Path),
+				attribute.String("request_id", c.GetString(RequestIDKey)),
+			))
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if uid := c.GetString(telemetry.UserIDKey); uid != "" {
+			span.SetAttributes(attribute.String("enduser.id", uid))
+		}
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
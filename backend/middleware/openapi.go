@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"backend/openapi"
+	"backend/telemetry"
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPI validation modes (config OPENAPI_VALIDATION)
+const (
+	ValidationOff     = "off"
+	ValidationWarn    = "warn"
+	ValidationEnforce = "enforce"
+)
+
+// maxValidatedResponse bounds how much of a response is kept for validation
+const maxValidatedResponse = 4 << 20
+
+var openapiViolations = telemetry.NewCounter("openapi_violations_total",
+	"Payloads that departed from the OpenAPI document, by route and direction.", "route", "direction")
+
+// teeWriter keeps a copy of the response body for validation
+type teeWriter struct {
+	gin.ResponseWriter
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (w *teeWriter) keep(n int) bool {
+	if w.overflow || w.buf.Len()+n > maxValidatedResponse {
+		w.overflow = true
+		w.buf.Reset()
+		return false
+	}
+	return true
+}
+
+func (w *teeWriter) Write(b []byte) (int, error) {
+	if w.keep(len(b)) {
+		w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *teeWriter) WriteString(s string) (int, error) {
+	if w.keep(len(s)) {
+		w.buf.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// ValidateOpenAPI checks JSON request and response bodies against spec. In
+// enforce mode a request that does not match is refused with a 422 listing
+// each violation; in warn mode it is logged and let through. Responses have
+// been sent by the time they are checked, so their violations are logged in
+// both modes, as errors when enforcing. Meant for development and test: the
+// frontend's drift from the Go types shows up in the logs.
+func ValidateOpenAPI(spec *openapi.Spec, mode string) gin.HandlerFunc {
+	if mode == ValidationOff {
+		return func(c *gin.Context) { c.Next() }
+	}
+	enforce := mode == ValidationEnforce
+	var undocumented sync.Map
+	return func(c *gin.Context) {
+		op, ok := spec.Operation(c.Request.Method, c.FullPath())
+		if !ok {
+			if _, seen := undocumented.LoadOrStore(c.Request.Method+" "+route(c), true); !seen && c.FullPath() != "" {
+				slog.WarnContext(c, "route missing from the OpenAPI document", "method", c.Request.Method, "route", route(c))
+			}
+			c.Next()
+			return
+		}
+
+		if c.Request.Body != nil && op.RequestBody != nil && !strings.HasPrefix(c.ContentType(), "multipart/") {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					AbortWithError(c, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "request body too large", nil)
+				} else {
+					AbortWithError(c, http.StatusBadRequest, CodeBadRequest, "reading request body failed", nil)
+				}
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			if len(body) > 0 {
+				if vs := spec.ValidateRequest(op, body); len(vs) > 0 {
+					openapiViolations.Inc(route(c), "request")
+					if enforce {
+						AbortWithError(c, http.StatusUnprocessableEntity, CodeValidation, "request does not match the API schema", vs)
+						return
+					}
+					slog.WarnContext(c, "request does not match the OpenAPI document", "route", route(c), "violations", violationStrings(vs))
+				}
+			}
+		}
+
+		w := &teeWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		if w.overflow || w.buf.Len() == 0 || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+			return
+		}
+		if vs := spec.ValidateResponse(op, w.Status(), w.buf.Bytes()); len(vs) > 0 {
+			openapiViolations.Inc(route(c), "response")
+			level := slog.LevelWarn
+			if enforce {
+				level = slog.LevelError
+			}
+			slog.Log(c, level, "response does not match the OpenAPI document", "route", route(c), "status", w.Status(), "violations", violationStrings(vs))
+		}
+	}
+}
+
+func violationStrings(vs []openapi.Violation) []string {
+	out := make([]string, len(vs))
+	for i, v := range vs {
+		out[i] = v.String()
+	}
+	return out
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"backend/config"
+	"backend/telemetry"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+var rateLimited = telemetry.NewCounter("http_rate_limited_total",
+	"Requests refused by a rate limiter, by route group and bucket scope.", "group", "scope")
+
+// sweepInterval is how often idle buckets are dropped
+const sweepInterval = time.Minute
+
+// buckets holds one token bucket per key (a client IP or a user id)
+type buckets struct {
+	limit rate.Limit
+	burst int
+	// idle is how long an unused bucket takes to refill; after that it is
+	// indistinguishable from a new one and can be dropped
+	idle time.Duration
+
+	mu        sync.Mutex
+	m         map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	lim  *rate.Limiter
+	seen time.Time
+}
+
+// newBuckets returns nil for an unlimited rate
+func newBuckets(r config.Rate) *buckets {
+	if r.IsZero() {
+		return nil
+	}
+	return &buckets{
+		limit: rate.Limit(float64(r.Count) / r.Per.Seconds()),
+		burst: r.Count,
+		idle:  r.Per,
+		m:     map[string]*bucket{},
+	}
+}
+
+// reserve takes a token from key's bucket
+func (b *buckets) reserve(key string, now time.Time) *rate.Reservation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.Sub(b.lastSweep) > sweepInterval {
+		for k, e := range b.m {
+			if now.Sub(e.seen) > b.idle {
+				delete(b.m, k)
+			}
+		}
+		b.lastSweep = now
+	}
+	e, ok := b.m[key]
+	if !ok {
+		e = &bucket{lim: rate.NewLimiter(b.limit, b.burst)}
+		b.m[key] = e
+	}
+	e.seen = now
+	return e.lim.ReserveN(now, 1)
+}
+
+// RateLimit enforces a route group's budget with token buckets per client
+// IP and, for signed-in callers, per user. A request needs a token from
+// each bucket that applies; when one is empty nothing is taken and the
+// client gets a 429 with Retry-After set to when a token will be free.
+// Mount it after OptionalAuthMiddleware so the user is known.
+func RateLimit(group string, l config.RateLimit) gin.HandlerFunc {
+	byIP, byUser := newBuckets(l.IP), newBuckets(l.User)
+	return func(c *gin.Context) {
+		now := time.Now()
+		var taken []*rate.Reservation
+		var wait time.Duration
+		scope := ""
+		take := func(b *buckets, name, key string) {
+			if b == nil || key == "" {
+				return
+			}
+			r := b.reserve(key, now)
+			taken = append(taken, r)
+			if d := r.DelayFrom(now); d > wait {
+				wait, scope = d, name
+			}
+		}
+		take(byIP, "ip", c.ClientIP())
+		take(byUser, "user", c.GetString("userID"))
+		if wait == 0 {
+			c.Next()
+			return
+		}
+		for _, r := range taken {
+			r.CancelAt(now)
+		}
+		rateLimited.Inc(group, scope)
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		AbortWithError(c, http.StatusTooManyRequests, CodeRateLimited,
+			fmt.Sprintf("too many requests, retry in %s", wait.Round(time.Second)), gin.H{"group": group, "scope": scope})
+	}
+}
+
+// OnWrites runs h for POST, PUT, PATCH and DELETE requests only
+func OnWrites(h gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isWrite(c.Request.Method) {
+			h(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// MaxBodySize refuses request bodies over n bytes with a 413. Routes in
+// exempt (gin route patterns) take large bodies, uploads and restores, and
+// apply their own limits.
+func MaxBodySize(n int64, exempt ...string) gin.HandlerFunc {
+	skip := map[string]bool{}
+	for _, r := range exempt {
+		skip[r] = true
+	}
+	return func(c *gin.Context) {
+		if skip[c.FullPath()] || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > n {
+			AbortWithError(c, http.StatusRequestEntityTooLarge, CodePayloadTooLarge,
+				fmt.Sprintf("request body exceeds %d bytes", n), nil)
+			return
+		}
+		// bodies without a declared length fail while being read
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, n)
+		c.Next()
+	}
+}
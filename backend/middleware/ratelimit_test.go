@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"backend/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+// limited serves GET / behind RateLimit with the user taken from X-User
+func limited(l config.RateLimit) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if u := c.GetHeader("X-User"); u != "" {
+			c.Set("userID", u)
+		}
+	})
+	r.Use(RateLimit("test", l))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestRateLimit(t *testing.T) {
+	perIP := config.Rate{Count: 2, Per: time.Minute}
+	perUser := config.Rate{Count: 3, Per: time.Minute}
+	type request struct {
+		ip, user string
+		want     int
+	}
+	tests := []struct {
+		name     string
+		limit    config.RateLimit
+		requests []request
+	}{
+		{
+			name:  "unlimited",
+			limit: config.RateLimit{},
+			requests: []request{
+				{ip: "10.0.0.1", want: 200}, {ip: "10.0.0.1", want: 200}, {ip: "10.0.0.1", want: 200},
+			},
+		},
+		{
+			name:  "per ip burst",
+			limit: config.RateLimit{IP: perIP},
+			requests: []request{
+				{ip: "10.0.0.1", want: 200}, {ip: "10.0.0.1", want: 200}, {ip: "10.0.0.1", want: 429},
+				// each client has its own bucket
+				{ip: "10.0.0.2", want: 200},
+			},
+		},
+		{
+			name:  "per user across addresses",
+			limit: config.RateLimit{User: perUser},
+			requests: []request{
+				{ip: "10.0.0.1", user: "u1", want: 200}, {ip: "10.0.0.2", user: "u1", want: 200},
+				{ip: "10.0.0.3", user: "u1", want: 200}, {ip: "10.0.0.4", user: "u1", want: 429},
+				{ip: "10.0.0.4", user: "u2", want: 200},
+				// anonymous callers have no user bucket
+				{ip: "10.0.0.4", want: 200}, {ip: "10.0.0.4", want: 200}, {ip: "10.0.0.4", want: 200},
+			},
+		},
+		{
+			name:  "a refused request takes no token",
+			limit: config.RateLimit{IP: perIP, User: config.Rate{Count: 1, Per: time.Minute}},
+			requests: []request{
+				{ip: "10.0.0.1", user: "u1", want: 200},
+				// the user bucket is empty, so the ip token is given back
+				{ip: "10.0.0.1", user: "u1", want: 429},
+				{ip: "10.0.0.1", user: "u2", want: 200},
+				{ip: "10.0.0.1", user: "u3", want: 429},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := limited(tt.limit)
+			for i, rq := range tt.requests {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = rq.ip + ":1234"
+				if rq.user != "" {
+					req.Header.Set("X-User", rq.user)
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if w.Code != rq.want {
+					t.Fatalf("request %d from %s/%s answered %d, want %d", i, rq.ip, rq.user, w.Code, rq.want)
+				}
+				if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+					t.Errorf("request %d: 429 without Retry-After", i)
+				}
+			}
+		})
+	}
+}
+
+func TestBucketsSweepIdle(t *testing.T) {
+	b := newBuckets(config.Rate{Count: 1, Per: time.Second})
+	now := time.Now()
+	b.reserve("10.0.0.1", now)
+	if r := b.reserve("10.0.0.1", now); r.DelayFrom(now) == 0 {
+		t.Fatal("second request within the period was not delayed")
+	}
+	later := now.Add(sweepInterval + time.Second)
+	b.reserve("10.0.0.2", later)
+	if _, ok := b.m["10.0.0.1"]; ok {
+		t.Error("idle bucket was not swept")
+	}
+	if newBuckets(config.Rate{}) != nil {
+		t.Error("an unlimited rate has buckets")
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"backend/telemetry"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request id in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey is the gin context key holding the current request id
+const RequestIDKey = telemetry.RequestIDKey
+
+// RequestID reuses a client-supplied X-Request-ID or generates one, stores it
+// in the context and echoes it back on the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.New().String()
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
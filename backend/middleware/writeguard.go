@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"backend/db"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireWritable answers 503 to state-changing requests while the database
+// refuses writes (the primary is down and DB_FALLBACK=readonly). Retry-After
+// points at the next reconnect attempt.
+func RequireWritable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isWrite(c.Request.Method) && db.Writable() != nil {
+			retry := 1
+			if next := db.CurrentStatus().NextCheck; next != nil {
+				if s := int(time.Until(*next).Seconds() + 1); s > retry {
+					retry = s
+				}
+			}
+			c.Header("Retry-After", strconv.Itoa(retry))
+			AbortWithError(c, http.StatusServiceUnavailable, CodeUnavailable, "database unavailable, writes are paused", nil)
+			return
+		}
+		c.Next()
+	}
+}
+
+// isWrite reports whether a request method may change state
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,214 @@
+package migrations
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// All lists every migration. Append new ones with the next version number;
+// never renumber or edit one that has shipped.
+var All = []Migration{
+	{Version: 1, Name: "secondary_indexes", Up: upSecondaryIndexes, Down: downSecondaryIndexes},
+	{Version: 2, Name: "validators", Up: upValidators, Down: downValidators},
+	{Version: 3, Name: "company_verified_backfill", Up: upCompanyVerified},
+	{Version: 4, Name: "company_owner_memberships", Up: upOwnerMemberships, Down: downOwnerMemberships},
+	{Version: 5, Name: "student_profile_defaults", Up: upStudentDefaults},
+	{Version: 6, Name: "record_versions", Up: upRecordVersions},
+	{Version: 7, Name: "outbox_index", Up: upOutboxIndex, Down: downOutboxIndex},
+	{Version: 8, Name: "webhook_indexes", Up: upWebhookIndexes, Down: downWebhookIndexes},
+}
+
+var secondaryIndexes = []index{
+	{"applications", "idx_applications_student", bson.D{{Key: "student_id", Value: 1}}},
+	{"applications", "idx_applications_job_status", bson.D{{Key: "job_id", Value: 1}, {Key: "status", Value: 1}}},
+	{"job_postings", "idx_job_postings_company", bson.D{{Key: "company_id", Value: 1}}},
+	{"job_postings", "idx_job_postings_status", bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+	{"companies", "idx_companies_recruiter", bson.D{{Key: "recruiter_id", Value: 1}}},
+	{"company_members", "idx_company_members_user", bson.D{{Key: "user_id", Value: 1}}},
+	{"resumes", "idx_resumes_student", bson.D{{Key: "student_id", Value: 1}}},
+	{"interviews", "idx_interviews_application", bson.D{{Key: "application_id", Value: 1}}},
+	{"notifications", "idx_notifications_user", bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	{"verification_requests", "idx_verification_requests_company", bson.D{{Key: "company_id", Value: 1}}},
+	{"audit_log", "idx_audit_log_created", bson.D{{Key: "created_at", Value: -1}}},
+	{"audit_log", "idx_audit_log_entity", bson.D{{Key: "entity", Value: 1}, {Key: "entity_id", Value: 1}}},
+}
+
+func upSecondaryIndexes(ctx context.Context, d *mongo.Database) error {
+	return createIndexes(ctx, d, secondaryIndexes)
+}
+
+func downSecondaryIndexes(ctx context.Context, d *mongo.Database) error {
+	return dropIndexes(ctx, d, secondaryIndexes)
+}
+
+// validators mirror the CHECK constraints of the SQL schema
+var validators = map[string]bson.M{
+	"applications": {
+		"bsonType": "object",
+		"required": bson.A{"job_id", "student_id", "status"},
+		"properties": bson.M{
+			"status": bson.M{"enum": bson.A{"applied", "shortlisted", "interview_scheduled", "selected",
+				"rejected", "offer_accepted", "offer_rejected", "closed"}},
+		},
+	},
+	"job_postings": {
+		"bsonType": "object",
+		"required": bson.A{"company_id", "title"},
+		"properties": bson.M{
+			"status": bson.M{"enum": bson.A{"draft", "active", "closed"}},
+		},
+	},
+	"student_profiles": {
+		"bsonType": "object",
+		"required": bson.A{"user_id"},
+		"properties": bson.M{
+			"cgpa": bson.M{"bsonType": bson.A{"double", "int", "long"}, "minimum": 0, "maximum": 10},
+		},
+	},
+	"profiles": {
+		"bsonType": "object",
+		"properties": bson.M{
+			"role": bson.M{"enum": bson.A{"student", "recruiter", "admin"}},
+		},
+	},
+}
+
+func upValidators(ctx context.Context, d *mongo.Database) error {
+	for coll, schema := range validators {
+		if err := setValidator(ctx, d, coll, schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downValidators(ctx context.Context, d *mongo.Database) error {
+	for coll := range validators {
+		if err := clearValidator(ctx, d, coll); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upCompanyVerified merges the legacy approved flag into verified so both
+// always agree; reads no longer have to reconcile them.
+func upCompanyVerified(ctx context.Context, d *mongo.Database) error {
+	col := d.Collection("companies")
+	if _, err := col.UpdateMany(ctx, bson.M{"approved": true, "verified": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"verified": true}}); err != nil {
+		return err
+	}
+	_, err := col.UpdateMany(ctx, bson.M{"verified": true, "approved": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"approved": true}})
+	return err
+}
+
+// backfilledBy marks memberships created by migration 4 so Down removes only those
+const backfilledBy = "migration:company_owner_memberships"
+
+// upOwnerMemberships turns each company's legacy recruiter_id into an
+// explicit owner membership
+func upOwnerMemberships(ctx context.Context, d *mongo.Database) error {
+	cur, err := d.Collection("companies").Find(ctx, bson.M{"recruiter_id": bson.M{"$gt": ""}})
+	if err != nil {
+		return err
+	}
+	var companies []struct {
+		ID          string `bson:"_id"`
+		RecruiterID string `bson:"recruiter_id"`
+		CreatedAt   string `bson:"created_at"`
+	}
+	if err := cur.All(ctx, &companies); err != nil {
+		return err
+	}
+	members := d.Collection("company_members")
+	for _, co := range companies {
+		n, err := members.CountDocuments(ctx, bson.M{"company_id": co.ID, "user_id": co.RecruiterID})
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		createdAt := co.CreatedAt
+		if createdAt == "" {
+			createdAt = time.Now().Format(time.RFC3339)
+		}
+		if _, err := members.InsertOne(ctx, bson.M{
+			"_id":        uuid.New().String(),
+			"company_id": co.ID,
+			"user_id":    co.RecruiterID,
+			"role":       "owner",
+			"invited_by": backfilledBy,
+			"created_at": createdAt,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downOwnerMemberships(ctx context.Context, d *mongo.Database) error {
+	_, err := d.Collection("company_members").DeleteMany(ctx, bson.M{"invited_by": backfilledBy})
+	return err
+}
+
+// upStudentDefaults gives older student profiles explicit zero counters
+func upStudentDefaults(ctx context.Context, d *mongo.Database) error {
+	col := d.Collection("student_profiles")
+	for _, f := range []string{"backlogs", "gap_months"} {
+		if _, err := col.UpdateMany(ctx, bson.M{f: bson.M{"$exists": false}}, bson.M{"$set": bson.M{f: 0}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionedCollections hold the records that carry an optimistic
+// concurrency version
+var versionedCollections = []string{"profiles", "student_profiles", "companies", "job_postings", "applications", "verification_requests"}
+
+// upRecordVersions starts existing records at version 1, as new ones are
+func upRecordVersions(ctx context.Context, d *mongo.Database) error {
+	for _, coll := range versionedCollections {
+		if _, err := d.Collection(coll).UpdateMany(ctx, bson.M{"version": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"version": 1}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outboxIndexes serve the event dispatcher's query for due events
+var outboxIndexes = []index{
+	{"outbox", "idx_outbox_due", bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+}
+
+func upOutboxIndex(ctx context.Context, d *mongo.Database) error {
+	return createIndexes(ctx, d, outboxIndexes)
+}
+
+func downOutboxIndex(ctx context.Context, d *mongo.Database) error {
+	return dropIndexes(ctx, d, outboxIndexes)
+}
+
+// webhookIndexes serve the company webhook list, the delivery log and the
+// sender's query for due deliveries
+var webhookIndexes = []index{
+	{"webhooks", "idx_webhooks_company", bson.D{{Key: "company_id", Value: 1}}},
+	{"webhook_deliveries", "idx_webhook_deliveries_company", bson.D{{Key: "company_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	{"webhook_deliveries", "idx_webhook_deliveries_due", bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+}
+
+func upWebhookIndexes(ctx context.Context, d *mongo.Database) error {
+	return createIndexes(ctx, d, webhookIndexes)
+}
+
+func downWebhookIndexes(ctx context.Context, d *mongo.Database) error {
+	return dropIndexes(ctx, d, webhookIndexes)
+}
@@ -0,0 +1,181 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection records which migrations have been applied
+const Collection = "schema_migrations"
+
+// Migration is one versioned schema change. Down may be nil for changes
+// that cannot be undone (data backfills); reverting them is then a no-op.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, d *mongo.Database) error
+	Down    func(ctx context.Context, d *mongo.Database) error
+}
+
+// Record is a row in schema_migrations
+type Record struct {
+	Version   int    `bson:"_id" json:"version"`
+	Name      string `bson:"name" json:"name"`
+	AppliedAt string `bson:"applied_at" json:"applied_at"`
+}
+
+// applied returns the applied migrations keyed by version
+func applied(ctx context.Context, d *mongo.Database) (map[int]Record, error) {
+	cur, err := d.Collection(Collection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var rows []Record
+	if err := cur.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	out := make(map[int]Record, len(rows))
+	for _, r := range rows {
+		out[r.Version] = r
+	}
+	return out, nil
+}
+
+func sorted() []Migration {
+	out := append([]Migration(nil), All...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
+
+// Up applies every pending migration in version order, stopping at the first
+// failure. It returns the number applied.
+func Up(ctx context.Context, d *mongo.Database, log io.Writer) (int, error) {
+	done, err := applied(ctx, d)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, m := range sorted() {
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+		fmt.Fprintf(log, "applying %03d %s\n", m.Version, m.Name)
+		if err := m.Up(ctx, d); err != nil {
+			return n, fmt.Errorf("migration %03d %s: %w", m.Version, m.Name, err)
+		}
+		rec := Record{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC().Format(time.RFC3339)}
+		if _, err := d.Collection(Collection).InsertOne(ctx, rec); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Down reverts the most recently applied steps migrations
+func Down(ctx context.Context, d *mongo.Database, steps int, log io.Writer) (int, error) {
+	done, err := applied(ctx, d)
+	if err != nil {
+		return 0, err
+	}
+	all := sorted()
+	n := 0
+	for i := len(all) - 1; i >= 0 && n < steps; i-- {
+		m := all[i]
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+		fmt.Fprintf(log, "reverting %03d %s\n", m.Version, m.Name)
+		if m.Down != nil {
+			if err := m.Down(ctx, d); err != nil {
+				return n, fmt.Errorf("migration %03d %s: %w", m.Version, m.Name, err)
+			}
+		}
+		if _, err := d.Collection(Collection).DeleteOne(ctx, bson.M{"_id": m.Version}); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Status writes one line per known migration with its applied time
+func Status(ctx context.Context, d *mongo.Database, w io.Writer) error {
+	done, err := applied(ctx, d)
+	if err != nil {
+		return err
+	}
+	for _, m := range sorted() {
+		state := "pending"
+		if r, ok := done[m.Version]; ok {
+			state = "applied " + r.AppliedAt
+		}
+		fmt.Fprintf(w, "%03d  %-32s %s\n", m.Version, m.Name, state)
+	}
+	return nil
+}
+
+// index describes a plain (non-unique) secondary index
+type index struct {
+	Collection string
+	Name       string
+	Keys       bson.D
+}
+
+func createIndexes(ctx context.Context, d *mongo.Database, idx []index) error {
+	for _, i := range idx {
+		model := mongo.IndexModel{Keys: i.Keys, Options: options.Index().SetName(i.Name)}
+		if _, err := d.Collection(i.Collection).Indexes().CreateOne(ctx, model); err != nil {
+			return fmt.Errorf("index %s: %w", i.Name, err)
+		}
+	}
+	return nil
+}
+
+func dropIndexes(ctx context.Context, d *mongo.Database, idx []index) error {
+	for _, i := range idx {
+		if _, err := d.Collection(i.Collection).Indexes().DropOne(ctx, i.Name); err != nil && !isNotFound(err) {
+			return fmt.Errorf("index %s: %w", i.Name, err)
+		}
+	}
+	return nil
+}
+
+// setValidator attaches a $jsonSchema validator, creating the collection when
+// it does not exist yet. Validation is "moderate" so legacy documents that
+// already violate the schema can still be updated.
+func setValidator(ctx context.Context, d *mongo.Database, collection string, schema bson.M) error {
+	cmd := bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "validator", Value: bson.M{"$jsonSchema": schema}},
+		{Key: "validationLevel", Value: "moderate"},
+	}
+	err := d.RunCommand(ctx, cmd).Err()
+	if isNotFound(err) {
+		opts := options.CreateCollection().SetValidator(bson.M{"$jsonSchema": schema}).SetValidationLevel("moderate")
+		return d.CreateCollection(ctx, collection, opts)
+	}
+	return err
+}
+
+func clearValidator(ctx context.Context, d *mongo.Database, collection string) error {
+	cmd := bson.D{{Key: "collMod", Value: collection}, {Key: "validator", Value: bson.M{}}}
+	if err := d.RunCommand(ctx, cmd).Err(); err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// isNotFound matches NamespaceNotFound (26) and IndexNotFound (27)
+func isNotFound(err error) bool {
+	var ce mongo.CommandError
+	return errors.As(err, &ce) && (ce.Code == 26 || ce.Code == 27)
+}
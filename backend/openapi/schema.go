@@ -0,0 +1,271 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON Schema (as embedded in OpenAPI 3.1) that the
+// backend's types need
+type Schema struct {
+	Ref         string        `json:"$ref,omitempty"`
+	Type        Types         `json:"type,omitempty"`
+	Format      string        `json:"format,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty"`
+	AnyOf       []*Schema     `json:"anyOf,omitempty"`
+
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	// AdditionalProperties is a *Schema for maps, false for structs (keys
+	// outside Properties are refused) or nil for anything
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+	Items                *Schema     `json:"items,omitempty"`
+}
+
+// Types is a schema's "type": one name, or several when a value may take
+// more than one form (["string","null"])
+type Types []string
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// Type returns a schema allowing the given JSON types
+func Type(names ...string) *Schema { return &Schema{Type: names} }
+
+// AnyOf documents a payload that takes one of several shapes
+type AnyOf []interface{}
+
+// ListOf documents an array of Item where no Go slice type exists
+type ListOf struct{ Item interface{} }
+
+// Doc documents a stored record as the join queries return it: V's bson
+// field names (the id under "_id") with the joined records added under
+// Joins. Name is the component name.
+type Doc struct {
+	Name  string
+	V     interface{}
+	Joins map[string]interface{}
+}
+
+// Generator derives schemas from Go types by reflection, using the same
+// rules as encoding/json. Named structs become components referenced with
+// $ref.
+type Generator struct {
+	Components map[string]*Schema
+	overrides  map[reflect.Type]*Schema
+	names      map[string]reflect.Type
+}
+
+// NewGenerator returns a generator with no components
+func NewGenerator() *Generator {
+	return &Generator{
+		Components: map[string]*Schema{},
+		overrides:  map[reflect.Type]*Schema{},
+		names:      map[string]reflect.Type{},
+	}
+}
+
+// Override uses s for every value of v's type, for types with custom JSON
+// encodings the reflection rules would get wrong
+func (g *Generator) Override(v interface{}, s *Schema) {
+	g.overrides[reflect.TypeOf(v)] = s
+}
+
+// Output returns the schema of v as the server writes it: every field
+// encoding/json always emits is required
+func (g *Generator) Output(v interface{}) *Schema { return g.value(v, false) }
+
+// Input returns the schema of v as a request body: no field is required
+// (the handlers report missing ones), but unknown fields are refused
+func (g *Generator) Input(v interface{}) *Schema { return g.value(v, true) }
+
+func (g *Generator) value(v interface{}, input bool) *Schema {
+	switch t := v.(type) {
+	case nil:
+		return &Schema{}
+	case *Schema:
+		return t
+	case AnyOf:
+		s := &Schema{}
+		for _, alt := range t {
+			s.AnyOf = append(s.AnyOf, g.value(alt, input))
+		}
+		return s
+	case ListOf:
+		return &Schema{Type: Types{"array"}, Items: g.value(t.Item, input)}
+	case Doc:
+		return g.doc(t)
+	}
+	return g.schema(reflect.TypeOf(v), input)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *Generator) schema(t reflect.Type, input bool) *Schema {
+	if s, ok := g.overrides[t]; ok {
+		c := *s
+		return &c
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return nullable(g.schema(t.Elem(), input))
+	case reflect.Bool:
+		return Type("boolean")
+	case reflect.String:
+		return Type("string")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Type("integer")
+	case reflect.Float32, reflect.Float64:
+		return Type("number")
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: Types{"string"}, Format: "byte"}
+		}
+		s := &Schema{Type: Types{"array"}, Items: g.schema(t.Elem(), input)}
+		if t.Kind() == reflect.Slice {
+			// a nil slice encodes as null
+			s.Type = append(s.Type, "null")
+		}
+		return s
+	case reflect.Map:
+		return &Schema{Type: Types{"object", "null"}, AdditionalProperties: g.schema(t.Elem(), input)}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: Types{"string"}, Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return g.object(t, input, "json")
+		}
+		return g.ref(t, input)
+	}
+	// interfaces and anything else: any value
+	return &Schema{}
+}
+
+// nullable also allows null, wrapping references since $ref takes no siblings
+func nullable(s *Schema) *Schema {
+	switch {
+	case s.Ref != "" || len(s.AnyOf) > 0:
+		return &Schema{AnyOf: []*Schema{s, Type("null")}}
+	case len(s.Type) == 0:
+		// any value, null included
+		return s
+	}
+	for _, n := range s.Type {
+		if n == "null" {
+			return s
+		}
+	}
+	s.Type = append(s.Type, "null")
+	return s
+}
+
+// ref registers a named struct as a component. The request form of a
+// struct with required fields is a separate component, suffixed Input.
+func (g *Generator) ref(t reflect.Type, input bool) *Schema {
+	name := t.Name()
+	if input && g.hasRequired(t) {
+		name += "Input"
+	}
+	if prev, ok := g.names[name]; ok && prev != t {
+		// same name in two packages
+		p := pkgName(t)
+		name = strings.ToUpper(p[:1]) + p[1:] + name
+	}
+	if _, ok := g.Components[name]; !ok {
+		g.names[name] = t
+		// placeholder first, so recursive types terminate
+		g.Components[name] = &Schema{}
+		*g.Components[name] = *g.object(t, input, "json")
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func pkgName(t reflect.Type) string {
+	p := t.PkgPath()
+	return p[strings.LastIndex(p, "/")+1:]
+}
+
+// field is one encoded struct field
+type field struct {
+	name      string
+	omitEmpty bool
+	typ       reflect.Type
+}
+
+// fields lists the encoded fields of t under the names of the json or bson
+// tag, flattening embedded structs as both encoders do
+func fields(t reflect.Type, tagKey string) []field {
+	var out []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get(tagKey)
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				out = append(out, fields(ft, tagKey)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+			if tagKey == "bson" {
+				name = strings.ToLower(name)
+			}
+		}
+		out = append(out, field{name: name, omitEmpty: strings.Contains(opts, "omitempty"), typ: ft})
+	}
+	return out
+}
+
+func (g *Generator) hasRequired(t reflect.Type) bool {
+	for _, f := range fields(t, "json") {
+		if !f.omitEmpty {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Generator) object(t reflect.Type, input bool, tagKey string) *Schema {
+	s := &Schema{Type: Types{"object"}, Properties: map[string]*Schema{}, AdditionalProperties: false}
+	for _, f := range fields(t, tagKey) {
+		s.Properties[f.name] = g.schema(f.typ, input)
+		if !input && !f.omitEmpty && tagKey == "json" {
+			s.Required = append(s.Required, f.name)
+		}
+	}
+	return s
+}
+
+// doc registers the component for a joined document. Stored documents skip
+// empty fields, so none is required.
+func (g *Generator) doc(d Doc) *Schema {
+	if _, ok := g.Components[d.Name]; !ok {
+		g.Components[d.Name] = &Schema{}
+		s := g.object(reflect.TypeOf(d.V), false, "bson")
+		for k, v := range d.Joins {
+			s.Properties[k] = g.value(v, false)
+		}
+		*g.Components[d.Name] = *s
+	}
+	return &Schema{Ref: "#/components/schemas/" + d.Name}
+}
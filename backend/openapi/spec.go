@@ -0,0 +1,271 @@
+// Package openapi builds the API's OpenAPI 3.1 document from the Go types
+// the handlers read and write, and validates payloads against it. The route
+// table lives with the handlers; this package only knows how to turn it into
+// a document.
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is the OpenAPI version of the generated document
+const Version = "3.1.0"
+
+// Route documents one gin route
+type Route struct {
+	// Method and Path as registered with gin, e.g. "/api/companies/:id"
+	Method, Path string
+	Summary      string
+	Tag          string
+	// Query lists the query parameters the handler reads
+	Query []string
+	// Body is the JSON request body, decoded from the Go value's type
+	Body interface{}
+	// OptionalBody marks a Body that may be left out
+	OptionalBody bool
+	// Ignored are body keys the handler accepts and drops
+	Ignored []string
+	// Form lists the multipart fields of an upload; "file" is the file
+	Form []string
+	// Status is the success status, 200 when zero
+	Status int
+	// Data is the payload of the {"data": ...} success envelope
+	Data interface{}
+	// Bare is a success body sent without the envelope
+	Bare interface{}
+	// Other documents further statuses answered with a bare body rather
+	// than an error
+	Other map[int]interface{}
+	// File is the content type of a streamed download
+	File string
+	// Public routes need no bearer token
+	Public bool
+	// ETag marks a single resource answered with an ETag; on a PUT the
+	// If-Match header is honoured too
+	ETag bool
+}
+
+// Info is the document's info object
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Spec is the OpenAPI document
+type Spec struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+
+	// ops indexes operations by method and gin path
+	ops map[string]*Operation
+}
+
+// Components holds the named schemas and the security scheme
+type Components struct {
+	Schemas         map[string]*Schema     `json:"schemas"`
+	SecuritySchemes map[string]interface{} `json:"securitySchemes"`
+}
+
+// Operation is one method on one path
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody maps content types to schemas
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is one documented status
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header is a documented response header
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType wraps the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+const jsonType = "application/json"
+
+var pathParam = regexp.MustCompile(`[:*]([A-Za-z_]+)`)
+
+// Build documents routes. errorBody is the value under "error" in error
+// responses, documented as every operation's default response.
+func Build(info Info, g *Generator, errorBody interface{}, routes []Route) *Spec {
+	s := &Spec{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]map[string]*Operation{},
+		ops:     map[string]*Operation{},
+	}
+	errSchema := envelope("error", g.Output(errorBody))
+	for _, r := range routes {
+		path := pathParam.ReplaceAllString(r.Path, "{$1}")
+		op := &Operation{
+			OperationID: operationID(r.Method, r.Path),
+			Summary:     r.Summary,
+			Responses:   map[string]*Response{},
+		}
+		if r.Tag != "" {
+			op.Tags = []string{r.Tag}
+		}
+		if !r.Public {
+			op.Security = []map[string][]string{{"bearer": {}}}
+		}
+		for _, m := range pathParam.FindAllStringSubmatch(r.Path, -1) {
+			op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: Type("string")})
+		}
+		for _, q := range r.Query {
+			op.Parameters = append(op.Parameters, Parameter{Name: q, In: "query", Schema: Type("string")})
+		}
+		if r.ETag && r.Method == http.MethodPut {
+			op.Parameters = append(op.Parameters, Parameter{Name: "If-Match", In: "header", Schema: Type("string")})
+		}
+		switch {
+		case r.Body != nil:
+			body := g.Input(r.Body)
+			if len(r.Ignored) > 0 {
+				body = withIgnored(g, body, r.Ignored)
+			}
+			op.RequestBody = &RequestBody{Required: !r.OptionalBody, Content: map[string]MediaType{jsonType: {Schema: body}}}
+		case len(r.Form) > 0:
+			form := &Schema{Type: Types{"object"}, Properties: map[string]*Schema{}}
+			for _, f := range r.Form {
+				if f == "file" {
+					form.Properties[f] = &Schema{Type: Types{"string"}, Format: "binary"}
+					form.Required = append(form.Required, f)
+				} else {
+					form.Properties[f] = Type("string")
+				}
+			}
+			op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{"multipart/form-data": {Schema: form}}}
+		}
+		status := r.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		ok := &Response{Description: http.StatusText(status)}
+		switch {
+		case r.File != "":
+			ok.Content = map[string]MediaType{r.File: {Schema: &Schema{Type: Types{"string"}, Format: "binary"}}}
+		case r.Bare != nil:
+			ok.Content = map[string]MediaType{jsonType: {Schema: g.Output(r.Bare)}}
+		case status != http.StatusNoContent:
+			ok.Content = map[string]MediaType{jsonType: {Schema: envelope("data", g.Output(r.Data))}}
+		}
+		if r.ETag {
+			ok.Headers = map[string]Header{"ETag": {Description: "Version of the resource", Schema: Type("string")}}
+		}
+		op.Responses[strconv.Itoa(status)] = ok
+		for st, v := range r.Other {
+			op.Responses[strconv.Itoa(st)] = &Response{Description: http.StatusText(st), Content: map[string]MediaType{jsonType: {Schema: g.Output(v)}}}
+		}
+		op.Responses["default"] = &Response{Description: "Error", Content: map[string]MediaType{jsonType: {Schema: errSchema}}}
+
+		if s.Paths[path] == nil {
+			s.Paths[path] = map[string]*Operation{}
+		}
+		s.Paths[path][strings.ToLower(r.Method)] = op
+		s.ops[r.Method+" "+r.Path] = op
+	}
+	s.Components = Components{
+		Schemas: g.Components,
+		SecuritySchemes: map[string]interface{}{
+			"bearer": map[string]string{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+		},
+	}
+	return s
+}
+
+// Operation returns the operation for a method and gin route pattern
+func (s *Spec) Operation(method, route string) (*Operation, bool) {
+	op, ok := s.ops[method+" "+route]
+	return op, ok
+}
+
+// Routes lists the documented routes as "METHOD /path", sorted
+func (s *Spec) Routes() []string {
+	out := make([]string, 0, len(s.ops))
+	for k := range s.ops {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// envelope wraps a payload schema as {key: payload}
+func envelope(key string, payload *Schema) *Schema {
+	return &Schema{
+		Type:                 Types{"object"},
+		Properties:           map[string]*Schema{key: payload},
+		Required:             []string{key},
+		AdditionalProperties: false,
+	}
+}
+
+// withIgnored adds keys the handler drops to an object schema. A $ref is
+// replaced by an inline copy of its component, which stays as it is.
+func withIgnored(g *Generator, body *Schema, keys []string) *Schema {
+	target := body
+	if body.Ref != "" {
+		target = g.Components[strings.TrimPrefix(body.Ref, "#/components/schemas/")]
+	}
+	if target == nil || target.Properties == nil {
+		return body
+	}
+	c := *target
+	c.Properties = map[string]*Schema{}
+	for k, v := range target.Properties {
+		c.Properties[k] = v
+	}
+	for _, k := range keys {
+		if _, ok := c.Properties[k]; !ok {
+			c.Properties[k] = &Schema{Description: "accepted and ignored"}
+		}
+	}
+	return &c
+}
+
+// operationID derives a stable id: POST /api/companies/:id/members becomes
+// post_companies_id_members
+func operationID(method, path string) string {
+	path = strings.TrimPrefix(path, "/api")
+	var parts []string
+	for _, p := range strings.Split(path, "/") {
+		p = strings.TrimLeft(p, ":*")
+		if p != "" {
+			parts = append(parts, strings.ReplaceAll(p, ".", "_"))
+		}
+	}
+	return strings.ToLower(method) + "_" + strings.Join(parts, "_")
+}
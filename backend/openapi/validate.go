@@ -0,0 +1,171 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Violation is one place a payload departs from the document. The shape
+// matches the field errors of 422 responses.
+type Violation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string { return v.Field + ": " + v.Message }
+
+// ValidateRequest checks a JSON request body against op. Operations without
+// a JSON body accept anything.
+func (s *Spec) ValidateRequest(op *Operation, body []byte) []Violation {
+	if op.RequestBody == nil {
+		return nil
+	}
+	mt, ok := op.RequestBody.Content[jsonType]
+	if !ok {
+		return nil
+	}
+	return s.validateJSON(mt.Schema, body, "body")
+}
+
+// ValidateResponse checks a JSON response body against the response op
+// documents for status, or its default response
+func (s *Spec) ValidateResponse(op *Operation, status int, body []byte) []Violation {
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		if status < 400 {
+			return []Violation{{Field: "status", Message: fmt.Sprintf("%d is not documented", status)}}
+		}
+		resp = op.Responses["default"]
+	}
+	if resp == nil {
+		return nil
+	}
+	mt, ok := resp.Content[jsonType]
+	if !ok {
+		return nil
+	}
+	return s.validateJSON(mt.Schema, body, "response")
+}
+
+func (s *Spec) validateJSON(schema *Schema, body []byte, root string) []Violation {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return []Violation{{Field: root, Message: "is not valid JSON"}}
+	}
+	var out []Violation
+	s.check(schema, v, root, &out)
+	return out
+}
+
+// check appends every violation of schema by v, found at path
+func (s *Spec) check(schema *Schema, v interface{}, path string, out *[]Violation) {
+	if schema.Ref != "" {
+		ref, ok := s.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+		if !ok {
+			*out = append(*out, Violation{Field: path, Message: "unknown schema " + schema.Ref})
+			return
+		}
+		schema = ref
+	}
+	if len(schema.AnyOf) > 0 {
+		// report the alternative that came closest
+		var best []Violation
+		for i, alt := range schema.AnyOf {
+			var got []Violation
+			s.check(alt, v, path, &got)
+			if len(got) == 0 {
+				return
+			}
+			if i == 0 || len(got) < len(best) {
+				best = got
+			}
+		}
+		*out = append(*out, best...)
+		return
+	}
+	if len(schema.Type) > 0 && !hasType(schema.Type, v) {
+		*out = append(*out, Violation{Field: path, Message: "must be " + strings.Join(schema.Type, " or ")})
+		return
+	}
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, v) {
+		*out = append(*out, Violation{Field: path, Message: fmt.Sprintf("must be one of %v", schema.Enum)})
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, k := range schema.Required {
+			if _, ok := t[k]; !ok {
+				*out = append(*out, Violation{Field: path + "." + k, Message: "is required"})
+			}
+		}
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if p, ok := schema.Properties[k]; ok {
+				s.check(p, t[k], path+"."+k, out)
+				continue
+			}
+			switch ap := schema.AdditionalProperties.(type) {
+			case bool:
+				if !ap {
+					*out = append(*out, Violation{Field: path + "." + k, Message: "is not a documented field"})
+				}
+			case *Schema:
+				s.check(ap, t[k], path+"."+k, out)
+			}
+		}
+	case []interface{}:
+		if schema.Items != nil {
+			for i, item := range t {
+				s.check(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), out)
+			}
+		}
+	}
+}
+
+func hasType(types Types, v interface{}) bool {
+	for _, name := range types {
+		switch t := v.(type) {
+		case nil:
+			if name == "null" {
+				return true
+			}
+		case bool:
+			if name == "boolean" {
+				return true
+			}
+		case string:
+			if name == "string" {
+				return true
+			}
+		case float64:
+			if name == "number" || (name == "integer" && t == math.Trunc(t)) {
+				return true
+			}
+		case []interface{}:
+			if name == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if name == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func inEnum(enum []interface{}, v interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"backend/config"
+	"backend/handlers"
+	"backend/middleware"
+	"backend/telemetry"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// newRouter registers the middleware chain and every route. The handlers'
+// dependencies (database, blob store, token keys) must be initialized.
+func newRouter(cfg *config.Config) *gin.Engine {
+	r := gin.New()
+	// handlers hand the gin context to the db layer; with the fallback it
+	// carries the request's cancellation, so abandoned requests stop querying
+	r.ContextWithFallback = true
+	// client IPs (logged and rate limited) come from X-Forwarded-For only
+	// when the peer is a trusted proxy
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		telemetry.Fatal("TRUSTED_PROXIES", "error", err)
+	}
+	// every request gets an id that is echoed back, logged and included in
+	// error bodies, plus a trace span, an access log record and metrics
+	r.Use(middleware.RequestID(), middleware.Trace(), middleware.Logger(), middleware.Metrics(), middleware.Recovery())
+	r.NoRoute(func(c *gin.Context) {
+		middleware.AbortWithError(c, http.StatusNotFound, middleware.CodeNotFound, "route not found", nil)
+	})
+
+	// CORS
+	r.Use(cors.New(cors.Config{
+		AllowOrigins:     cfg.FrontendOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match", middleware.RequestIDHeader, "traceparent"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After", "ETag", middleware.RequestIDHeader},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}))
+
+	// Prometheus scrape endpoint; it sits outside /api and needs no token
+	r.GET("/metrics", gin.WrapH(telemetry.MetricsHandler()))
+
+	api := r.Group("/api")
+	// attribute requests to a user when a token is sent; anonymous calls still pass
+	api.Use(middleware.OptionalAuthMiddleware())
+	// writes get a 503 while the database is down with DB_FALLBACK=readonly
+	api.Use(middleware.RequireWritable())
+	// bodies are capped at MAX_BODY_BYTES except where the handler sets its own limit
+	var ownLimit []string
+	for _, prefix := range []string{"/api", "/api/v1"} {
+		ownLimit = append(ownLimit, prefix+"/resumes/upload", prefix+"/verification_requests/:id/documents",
+			prefix+"/admin/restore", prefix+"/admin/backup/restore")
+	}
+	api.Use(middleware.MaxBodySize(int64(cfg.MaxBodyBytes), ownLimit...))
+	// every write shares one budget; sign-in and uploads also have their own
+	api.Use(middleware.OnWrites(middleware.RateLimit("write", cfg.RateLimits.Write)))
+	handlers.MaxUploadSize = int64(cfg.MaxUploadBytes)
+	// payloads are checked against the OpenAPI document outside production
+	spec := handlers.OpenAPISpec()
+	api.Use(middleware.ValidateOpenAPI(spec, cfg.OpenAPIValidation))
+
+	// the document and the probes are not versioned
+	api.GET("/openapi.json", handlers.ServeOpenAPI(spec))
+	api.GET("/health", handlers.Health)
+	api.GET("/health/live", handlers.LiveHealth)
+	api.GET("/health/ready", handlers.ReadyHealth(dependencyChecks()))
+
+	limits := routeLimits{
+		auth:   middleware.RateLimit("auth", cfg.RateLimits.Auth),
+		upload: middleware.RateLimit("upload", cfg.RateLimits.Upload),
+	}
+	// /api stays as the compatibility layer for existing clients
+	registerRoutes(api, cfg, limits, false)
+	registerRoutes(api.Group("/v1", handlers.V1()), cfg, limits, true)
+	return r
+}
+
+// routeLimits are the rate limiters shared by both API versions, so a
+// client cannot double its budget by switching between them
+type routeLimits struct {
+	auth, upload gin.HandlerFunc
+}
+
+// registerRoutes adds the resource routes to an API version's group. v1
+// also gets the reads by id.
+func registerRoutes(api *gin.RouterGroup, cfg *config.Config, limits routeLimits, v1 bool) {
+	api.POST("/auth/google", limits.auth, handlers.GoogleAuth)
+	// routes on authed need a valid token; the rest also serve anonymous calls
+	authed := api.Group("", middleware.AuthMiddleware())
+
+	// public-ish profiles listing
+	api.GET("/profiles", handlers.GetProfiles)
+	api.POST("/profiles", handlers.CreateProfile)
+	authed.PUT("/profiles/:id", handlers.UpdateProfile)
+
+	// student profiles
+	api.GET("/student_profiles", handlers.GetStudentProfiles)
+	api.POST("/student_profiles", handlers.CreateStudentProfile)
+	authed.PUT("/student_profiles/:id", handlers.UpdateStudentProfile)
+
+	// companies
+	api.GET("/companies", handlers.GetCompanies)
+	authed.POST("/companies", handlers.CreateCompany)
+	api.PUT("/companies/:id", handlers.UpdateCompany)
+	api.GET("/companies/:id/members", handlers.GetCompanyMembers)
+	api.POST("/companies/:id/members", handlers.InviteCompanyMember)
+	api.DELETE("/companies/:id/members/:user_id", handlers.RemoveCompanyMember)
+	api.GET("/companies/:id/webhooks", handlers.GetWebhooks)
+	api.POST("/companies/:id/webhooks", handlers.CreateWebhook)
+	api.DELETE("/companies/:id/webhooks/:webhook_id", handlers.DeleteWebhook)
+	api.GET("/companies/:id/webhooks/deliveries", handlers.GetWebhookDeliveries)
+	api.POST("/companies/:id/webhooks/deliveries/:delivery_id/replay", handlers.ReplayWebhookDelivery)
+
+	// jobs
+	api.GET("/job_postings", handlers.GetJobPostings)
+	api.POST("/job_postings", handlers.CreateJobPosting)
+	api.PUT("/job_postings/:id", handlers.UpdateJobPosting)
+	api.DELETE("/job_postings/:id", handlers.DeleteJobPosting)
+	api.POST("/job_postings/:id/publish", handlers.PublishJobPosting)
+	api.POST("/job_postings/:id/close", handlers.CloseJobPosting)
+
+	// resumes
+	authed.POST("/resumes/upload", limits.upload, handlers.UploadResume)
+	authed.GET("/resumes", handlers.GetResumes)
+	authed.GET("/resumes/:id/file", handlers.DownloadResume)
+
+	// applications
+	api.GET("/applications", handlers.GetApplications)
+	authed.POST("/applications", handlers.CreateApplication)
+	api.PUT("/applications/:id", handlers.UpdateApplication)
+
+	// interviews
+	api.POST("/interviews", handlers.CreateInterview)
+
+	// notifications
+	authed.GET("/notifications", handlers.GetNotifications)
+
+	// company verification (recruiter side)
+	authed.POST("/companies/:id/verification", handlers.SubmitVerificationRequest)
+	authed.GET("/verification_requests", handlers.GetVerificationRequests)
+	authed.PUT("/verification_requests/:id", handlers.UpdateVerificationRequest)
+	authed.POST("/verification_requests/:id/documents", limits.upload, handlers.UploadVerificationDocument)
+	authed.GET("/verification_requests/:id/documents/:doc_id", handlers.DownloadVerificationDocument)
+
+	if v1 {
+		api.GET("/profiles/:id", handlers.GetProfile)
+		api.GET("/student_profiles/:id", handlers.GetStudentProfile)
+		api.GET("/companies/:id", handlers.GetCompany)
+		api.GET("/job_postings/:id", handlers.GetJobPosting)
+		api.GET("/applications/:id", handlers.GetApplication)
+		authed.GET("/verification_requests/:id", handlers.GetVerificationRequest)
+	}
+
+	// admin-only
+	admin := api.Group("/admin", middleware.AuthMiddleware(), middleware.RequireRole("admin"))
+	admin.GET("/audit", handlers.GetAuditLog)
+	admin.POST("/job_postings/:id/restore", handlers.RestoreJobPosting)
+	admin.POST("/verification_requests/:id/review", handlers.ReviewVerificationRequest)
+	admin.GET("/snapshot", handlers.DownloadSnapshot)
+	admin.POST("/restore", handlers.RestoreSnapshot)
+	admin.GET("/config", handlers.GetConfig(cfg))
+	admin.GET("/backup", handlers.DownloadBackup)
+	admin.POST("/backup/restore", handlers.RestoreBackup)
+}
@@ -0,0 +1,60 @@
+// Package scheduler runs periodic background tasks inside the API process.
+// It is deliberately simple: each task gets its own ticker goroutine and runs
+// once immediately at startup so state is reconciled after a restart.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Task is a named unit of periodic work. Run receives the scheduler's
+// context, which is cancelled on shutdown; tasks are rerun at startup, so
+// an interrupted run is simply picked up again.
+type Task struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context, now time.Time) error
+}
+
+// Start launches every task in the background until ctx is cancelled. A
+// failing run is logged and retried on the next tick. The returned function
+// blocks until every task has stopped.
+func Start(ctx context.Context, tasks ...Task) (wait func()) {
+	var wg sync.WaitGroup
+	for _, t := range tasks {
+		wg.Add(1)
+		go func(t Task) {
+			defer wg.Done()
+			run(ctx, t)
+		}(t)
+	}
+	return wg.Wait
+}
+
+func run(ctx context.Context, t Task) {
+	tick := func() {
+		defer func() {
+			if r := recover(); r != nil {
+				slog.Error("scheduled task panicked", "task", t.Name, "panic", fmt.Sprint(r))
+			}
+		}()
+		if err := t.Run(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			slog.Error("scheduled task failed", "task", t.Name, "error", err)
+		}
+	}
+	tick()
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tick()
+		}
+	}
+}
@@ -0,0 +1,619 @@
+// Package seed generates a synthetic campus for development and demos:
+// students across branches, companies with recruiters and jobs, and
+// applications with interviews and offers. Everything is written through the
+// db package, so it works with every storage backend. The same seed always
+// produces the same people, companies and outcomes; dates are laid out
+// around the time the seed runs so jobs are open and interviews upcoming.
+package seed
+
+import (
+	"backend/db"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AdminEmail is the placement office account created by every run
+const AdminEmail = "placement.office@campus.test"
+
+// ErrAlreadySeeded is returned when the store already holds seed data
+var ErrAlreadySeeded = errors.New("the store already contains seed data; seed an empty store")
+
+// Options controls the size and randomness of the generated campus
+type Options struct {
+	Seed      int64
+	Students  int
+	Companies int
+	// Now anchors every generated date; zero means time.Now()
+	Now time.Time
+}
+
+// Summary counts the records created
+type Summary struct {
+	Profiles     int
+	Students     int
+	Companies    int
+	Jobs         int
+	Applications int
+	Interviews   int
+	Offers       int
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%d profiles, %d students, %d companies, %d jobs, %d applications, %d interviews, %d offers",
+		s.Profiles, s.Students, s.Companies, s.Jobs, s.Applications, s.Interviews, s.Offers)
+}
+
+type branch struct {
+	Name   string
+	Code   string
+	Weight int
+	// CGPA distribution
+	Mean, StdDev float64
+	Skills       []string
+}
+
+var branches = []branch{
+	{"Computer Science", "CS", 30, 7.8, 0.9, []string{"Go", "Java", "Python", "React", "SQL", "Docker", "Kubernetes", "Data Structures", "System Design", "TypeScript"}},
+	{"Information Technology", "IT", 20, 7.5, 0.9, []string{"Java", "Python", "JavaScript", "SQL", "Linux", "Networking", "AWS", "Spring Boot"}},
+	{"Electronics and Communication", "EC", 18, 7.3, 1.0, []string{"Embedded C", "VLSI", "MATLAB", "Signal Processing", "Verilog", "IoT", "Python"}},
+	{"Electrical Engineering", "EE", 12, 7.1, 1.0, []string{"MATLAB", "Power Systems", "PLC", "Simulink", "Control Systems", "Python"}},
+	{"Mechanical Engineering", "ME", 12, 6.9, 1.0, []string{"AutoCAD", "SolidWorks", "ANSYS", "Thermodynamics", "CNC", "Six Sigma"}},
+	{"Civil Engineering", "CE", 8, 6.8, 1.0, []string{"AutoCAD", "STAAD Pro", "Revit", "Surveying", "Project Management"}},
+}
+
+var firstNames = []string{"Aarav", "Vivaan", "Aditya", "Ishaan", "Arjun", "Rohan", "Kabir", "Reyansh", "Karthik", "Nikhil",
+	"Saanvi", "Ananya", "Diya", "Aadhya", "Isha", "Meera", "Priya", "Riya", "Sneha", "Kavya", "Zara", "Tanvi", "Neha", "Farhan", "Rahul"}
+
+var lastNames = []string{"Sharma", "Verma", "Iyer", "Reddy", "Nair", "Gupta", "Patel", "Singh", "Khan", "Das",
+	"Menon", "Joshi", "Kulkarni", "Banerjee", "Chatterjee", "Rao", "Mehta", "Pillai", "Agarwal", "Fernandes"}
+
+type companyKind struct {
+	Industry string
+	Names    []string
+	Roles    []jobRole
+}
+
+type jobRole struct {
+	Title, Role string
+	// annual salary range in lakhs
+	MinLPA, MaxLPA float64
+	Branches       []string
+}
+
+var companyKinds = []companyKind{
+	{"Software", []string{"Nimbus Labs", "Quantive Systems", "Bytecraft Technologies", "Cloudpeak Software", "Orbital Apps"},
+		[]jobRole{
+			{"Software Engineer", "SDE", 8, 18, []string{"CS", "IT", "EC"}},
+			{"Backend Developer", "Backend", 7, 14, []string{"CS", "IT"}},
+			{"Frontend Developer", "Frontend", 6, 12, []string{"CS", "IT"}},
+			{"Site Reliability Engineer", "SRE", 9, 16, []string{"CS", "IT"}},
+		}},
+	{"Analytics", []string{"Insightful Analytics", "DataGrove", "Metricwise"},
+		[]jobRole{
+			{"Data Analyst", "Analyst", 5, 9, nil},
+			{"Data Engineer", "Data Engineering", 8, 14, []string{"CS", "IT"}},
+		}},
+	{"Semiconductors", []string{"Silicon Arc", "Voltaic Devices", "Microwave Circuits"},
+		[]jobRole{
+			{"VLSI Design Engineer", "VLSI", 8, 15, []string{"EC", "EE"}},
+			{"Embedded Systems Engineer", "Embedded", 6, 11, []string{"EC", "EE", "CS"}},
+		}},
+	{"Manufacturing", []string{"Ironbridge Engineering", "Apex Automotive", "Greenline Energy"},
+		[]jobRole{
+			{"Graduate Engineer Trainee", "GET", 4, 7, []string{"ME", "EE", "CE"}},
+			{"Design Engineer", "Design", 5, 8, []string{"ME"}},
+			{"Site Engineer", "Site", 4, 6.5, []string{"CE"}},
+		}},
+	{"Consulting", []string{"Northstar Consulting", "Keystone Advisory"},
+		[]jobRole{
+			{"Business Analyst", "Analyst", 7, 12, nil},
+			{"Technology Consultant", "Consultant", 8, 13, []string{"CS", "IT", "EC"}},
+		}},
+}
+
+type student struct {
+	profile db.StudentProfile
+	userID  string
+	branch  branch
+}
+
+type job struct {
+	db.JobPosting
+	criteria map[string]interface{}
+	offers   int
+}
+
+type generator struct {
+	ctx context.Context
+	rng *rand.Rand
+	now time.Time
+	sum Summary
+}
+
+// Run generates the campus described by opts
+func Run(ctx context.Context, opts Options) (Summary, error) {
+	if opts.Students < 0 || opts.Companies < 0 {
+		return Summary{}, errors.New("seed: counts must not be negative")
+	}
+	if existing, err := db.ListProfiles(ctx, map[string]interface{}{"email": AdminEmail}); err != nil {
+		return Summary{}, err
+	} else if len(existing) > 0 {
+		return Summary{}, ErrAlreadySeeded
+	}
+	g := &generator{ctx: ctx, rng: rand.New(rand.NewSource(opts.Seed)), now: opts.Now}
+	if g.now.IsZero() {
+		g.now = time.Now()
+	}
+	g.now = g.now.UTC().Truncate(time.Minute)
+
+	if _, err := g.profile("Placement Office", AdminEmail, "admin", 120*24*time.Hour); err != nil {
+		return g.sum, err
+	}
+	students := make([]student, 0, opts.Students)
+	for i := 1; i <= opts.Students; i++ {
+		s, err := g.student(i)
+		if err != nil {
+			return g.sum, err
+		}
+		students = append(students, s)
+	}
+	jobs := make([]*job, 0)
+	for i := 1; i <= opts.Companies; i++ {
+		js, err := g.company(i)
+		if err != nil {
+			return g.sum, err
+		}
+		jobs = append(jobs, js...)
+	}
+	for _, s := range students {
+		if err := g.applications(s, jobs); err != nil {
+			return g.sum, err
+		}
+	}
+	return g.sum, nil
+}
+
+// id returns a UUID drawn from the seeded source
+func (g *generator) id() string {
+	u, err := uuid.NewRandomFromReader(g.rng)
+	if err != nil {
+		panic(err)
+	}
+	return u.String()
+}
+
+func (g *generator) ts(offset time.Duration) string {
+	return g.now.Add(offset).Format(time.RFC3339)
+}
+
+func (g *generator) days(min, max int) time.Duration {
+	return time.Duration(min+g.rng.Intn(max-min+1)) * 24 * time.Hour
+}
+
+func (g *generator) chance(p float64) bool {
+	return g.rng.Float64() < p
+}
+
+// sample picks between min and max distinct items from pool
+func (g *generator) sample(pool []string, min, max int) []interface{} {
+	n := min + g.rng.Intn(max-min+1)
+	if n > len(pool) {
+		n = len(pool)
+	}
+	out := make([]interface{}, 0, n)
+	for _, i := range g.rng.Perm(len(pool))[:n] {
+		out = append(out, pool[i])
+	}
+	return out
+}
+
+func (g *generator) pick(pool []string) string {
+	return pool[g.rng.Intn(len(pool))]
+}
+
+func (g *generator) profile(name, email, role string, age time.Duration) (string, error) {
+	p := db.Profile{
+		ID:        g.id(),
+		Email:     email,
+		FullName:  name,
+		Role:      role,
+		CreatedAt: g.ts(-age),
+		UpdatedAt: g.ts(-age),
+	}
+	if err := db.CreateProfile(g.ctx, p); err != nil {
+		return "", fmt.Errorf("profile %s: %w", email, err)
+	}
+	g.sum.Profiles++
+	return p.ID, nil
+}
+
+func (g *generator) branch() branch {
+	total := 0
+	for _, b := range branches {
+		total += b.Weight
+	}
+	n := g.rng.Intn(total)
+	for _, b := range branches {
+		if n < b.Weight {
+			return b
+		}
+		n -= b.Weight
+	}
+	return branches[0]
+}
+
+func (g *generator) student(i int) (student, error) {
+	first, last := g.pick(firstNames), g.pick(lastNames)
+	email := fmt.Sprintf("%s.%s%d@students.campus.test", strings.ToLower(first), strings.ToLower(last), i)
+	age := g.days(60, 90)
+	uid, err := g.profile(first+" "+last, email, "student", age)
+	if err != nil {
+		return student{}, err
+	}
+
+	b := g.branch()
+	cgpa := math.Round((g.rng.NormFloat64()*b.StdDev+b.Mean)*100) / 100
+	cgpa = math.Max(5, math.Min(10, cgpa))
+	backlogs := 0
+	if cgpa < 6.5 && g.chance(0.5) {
+		backlogs = 1 + g.rng.Intn(3)
+	} else if g.chance(0.05) {
+		backlogs = 1
+	}
+	gap := 0
+	if g.chance(0.08) {
+		gap = 6 * (1 + g.rng.Intn(2))
+	}
+	year := g.now.Year()
+	if g.chance(0.3) {
+		year++
+	}
+
+	projects := []interface{}{}
+	for _, skill := range g.sample(b.Skills, 1, 2) {
+		projects = append(projects, map[string]interface{}{
+			"title":        fmt.Sprintf("%s %s", skill, g.pick([]string{"Portfolio Project", "Capstone", "Hackathon Entry", "Course Project"})),
+			"description":  "Built as part of coursework and open-sourced.",
+			"technologies": skill,
+			"duration":     fmt.Sprintf("%d months", 1+g.rng.Intn(5)),
+		})
+	}
+	internships := []interface{}{}
+	if g.chance(0.4) {
+		kind := companyKinds[g.rng.Intn(len(companyKinds))]
+		internships = append(internships, map[string]interface{}{
+			"company":     g.pick(kind.Names),
+			"role":        "Intern",
+			"duration":    fmt.Sprintf("%d months", 2+g.rng.Intn(4)),
+			"description": "Summer internship.",
+		})
+	}
+
+	sp := db.StudentProfile{
+		ID:             g.id(),
+		UserID:         uid,
+		RollNumber:     fmt.Sprintf("%s%02d%04d", b.Code, year%100, i),
+		CGPA:           cgpa,
+		Branch:         b.Name,
+		GraduationYear: year,
+		Skills:         g.sample(b.Skills, 3, 6),
+		Projects:       projects,
+		Internships:    internships,
+		Backlogs:       backlogs,
+		GapMonths:      gap,
+		CreatedAt:      g.ts(-age),
+		UpdatedAt:      g.ts(-age),
+	}
+	if err := db.CreateStudentProfile(g.ctx, sp); err != nil {
+		return student{}, fmt.Errorf("student %s: %w", sp.RollNumber, err)
+	}
+	g.sum.Students++
+	return student{profile: sp, userID: uid, branch: b}, nil
+}
+
+func (g *generator) company(i int) ([]*job, error) {
+	kind := companyKinds[(i-1)%len(companyKinds)]
+	name := kind.Names[((i-1)/len(companyKinds))%len(kind.Names)]
+	if round := (i - 1) / (len(companyKinds) * len(kind.Names)); round > 0 {
+		name = fmt.Sprintf("%s %d", name, round+1)
+	}
+	domain := strings.ToLower(strings.ReplaceAll(name, " ", "")) + ".example"
+	age := g.days(30, 60)
+
+	first, last := g.pick(firstNames), g.pick(lastNames)
+	recruiter, err := g.profile(first+" "+last, fmt.Sprintf("%s.%s@%s", strings.ToLower(first), strings.ToLower(last), domain), "recruiter", age)
+	if err != nil {
+		return nil, err
+	}
+	verified := g.chance(0.85)
+	co := db.Company{
+		ID:          g.id(),
+		Name:        name,
+		Email:       "careers@" + domain,
+		RecruiterID: recruiter,
+		Description: fmt.Sprintf("%s is a %s company hiring from campus.", name, strings.ToLower(kind.Industry)),
+		Website:     "https://" + domain,
+		Industry:    kind.Industry,
+		Verified:    verified,
+		Approved:    verified,
+		CreatedAt:   g.ts(-age),
+		UpdatedAt:   g.ts(-age),
+	}
+	if err := db.CreateCompany(g.ctx, co); err != nil {
+		return nil, fmt.Errorf("company %s: %w", name, err)
+	}
+	g.sum.Companies++
+	if err := g.member(co, recruiter, db.MemberOwner, ""); err != nil {
+		return nil, err
+	}
+	if g.chance(0.4) {
+		first, last := g.pick(firstNames), g.pick(lastNames)
+		uid, err := g.profile(first+" "+last, fmt.Sprintf("%s.%s@%s", strings.ToLower(first), strings.ToLower(last), domain), "recruiter", age)
+		if err != nil {
+			return nil, err
+		}
+		if err := g.member(co, uid, db.MemberInterviewer, recruiter); err != nil {
+			return nil, err
+		}
+	}
+
+	// unverified companies can only prepare drafts
+	jobs := make([]*job, 0)
+	for n := 1 + g.rng.Intn(3); n > 0; n-- {
+		status := "active"
+		switch {
+		case !verified:
+			status = "draft"
+		case g.chance(0.35):
+			status = "closed"
+		case g.chance(0.1):
+			status = "draft"
+		}
+		j, err := g.job(co, kind.Roles[g.rng.Intn(len(kind.Roles))], status)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+func (g *generator) member(co db.Company, userID, role, invitedBy string) error {
+	m := db.CompanyMember{ID: g.id(), CompanyID: co.ID, UserID: userID, Role: role, InvitedBy: invitedBy, CreatedAt: co.CreatedAt}
+	if err := db.AddCompanyMember(g.ctx, m); err != nil {
+		return fmt.Errorf("member of %s: %w", co.Name, err)
+	}
+	return nil
+}
+
+func (g *generator) job(co db.Company, r jobRole, status string) (*job, error) {
+	criteria := map[string]interface{}{}
+	if g.chance(0.8) {
+		criteria["minCGPA"] = 6 + float64(g.rng.Intn(5))*0.5
+	}
+	if g.chance(0.6) {
+		criteria["maxBacklogs"] = g.rng.Intn(2)
+	}
+	if len(r.Branches) > 0 {
+		allowed := make([]interface{}, 0, len(r.Branches))
+		for _, code := range r.Branches {
+			for _, b := range branches {
+				if b.Code == code {
+					allowed = append(allowed, b.Name)
+				}
+			}
+		}
+		criteria["allowedBranches"] = allowed
+	}
+	if g.chance(0.3) {
+		criteria["graduationYear"] = g.now.Year()
+	}
+
+	min := r.MinLPA + g.rng.Float64()*(r.MaxLPA-r.MinLPA)/2
+	max := min + g.rng.Float64()*(r.MaxLPA-min)
+	created := g.days(10, 30)
+	j := &job{criteria: criteria, JobPosting: db.JobPosting{
+		ID:                  g.id(),
+		CompanyID:           co.ID,
+		Title:               r.Title,
+		Description:         fmt.Sprintf("%s joins the %s team at %s.", r.Title, r.Role, co.Name),
+		Role:                r.Role,
+		Openings:            1 + g.rng.Intn(8),
+		SalaryMin:           math.Round(min*10) * 10000,
+		SalaryMax:           math.Round(max*10) * 10000,
+		JobLocation:         g.pick([]string{"Bengaluru", "Hyderabad", "Pune", "Chennai", "Gurugram", "Remote"}),
+		EligibilityCriteria: criteria,
+		Status:              status,
+		CreatedAt:           g.ts(-created),
+		UpdatedAt:           g.ts(-created),
+	}}
+	switch status {
+	case "active":
+		j.ApplicationDeadline = g.ts(g.days(7, 45))
+		j.PublishedAt = j.CreatedAt
+	case "closed":
+		j.ApplicationDeadline = g.ts(-g.days(1, 5))
+		j.PublishedAt = j.CreatedAt
+		j.ClosedAt = j.ApplicationDeadline
+	case "draft":
+		if g.chance(0.5) {
+			j.PublishAt = g.ts(g.days(2, 10))
+		}
+		j.ApplicationDeadline = g.ts(g.days(20, 60))
+	}
+	if g.chance(0.2) {
+		j.BondTerms = fmt.Sprintf("%d year service agreement", 1+g.rng.Intn(2))
+	}
+	if err := db.CreateJobPosting(g.ctx, j.JobPosting); err != nil {
+		return nil, fmt.Errorf("job %s at %s: %w", r.Title, co.Name, err)
+	}
+	g.sum.Jobs++
+	return j, nil
+}
+
+// eligibility applies the same rules as the student job list
+func eligibility(s student, j *job) string {
+	c := j.criteria
+	if min, ok := c["minCGPA"].(float64); ok && s.profile.CGPA < min {
+		return ""
+	}
+	if max, ok := c["maxBacklogs"].(int); ok && s.profile.Backlogs > max {
+		return ""
+	}
+	if allowed, ok := c["allowedBranches"].([]interface{}); ok {
+		found := false
+		for _, b := range allowed {
+			found = found || b == s.branch.Name
+		}
+		if !found {
+			return ""
+		}
+	}
+	if year, ok := c["graduationYear"].(int); ok && s.profile.GraduationYear != year {
+		return "conditional"
+	}
+	return "eligible"
+}
+
+// outcome draws a final application status; decided outcomes only happen
+// once a job has closed
+func (g *generator) outcome(j *job) string {
+	p := g.rng.Float64()
+	if j.Status == "active" {
+		switch {
+		case p < 0.55:
+			return "applied"
+		case p < 0.8:
+			return "shortlisted"
+		case p < 0.92:
+			return "interview_scheduled"
+		}
+		return "rejected"
+	}
+	switch {
+	case p < 0.45:
+		return "rejected"
+	case p < 0.6:
+		return "interview_scheduled"
+	case p < 0.72:
+		return "selected"
+	case p < 0.92:
+		return "offer_accepted"
+	}
+	return "offer_rejected"
+}
+
+func isOffer(status string) bool {
+	return status == "selected" || status == "offer_accepted" || status == "offer_rejected"
+}
+
+func (g *generator) applications(s student, jobs []*job) error {
+	want := g.rng.Intn(6)
+	for _, i := range g.rng.Perm(len(jobs)) {
+		if want == 0 {
+			return nil
+		}
+		j := jobs[i]
+		if j.Status == "draft" {
+			continue
+		}
+		elig := eligibility(s, j)
+		if elig == "" {
+			continue
+		}
+		want--
+
+		status := g.outcome(j)
+		if isOffer(status) {
+			if j.offers >= j.Openings {
+				status = "rejected"
+			} else {
+				j.offers++
+			}
+		}
+		applied, _ := db.ParseTimestamp(j.CreatedAt)
+		appliedAt := applied.Add(g.days(0, 5)).Format(time.RFC3339)
+		a := db.Application{
+			ID:                g.id(),
+			JobID:             j.ID,
+			StudentID:         s.profile.ID,
+			Status:            "applied",
+			EligibilityStatus: elig,
+			AppliedAt:         appliedAt,
+			CreatedAt:         appliedAt,
+			UpdatedAt:         appliedAt,
+		}
+		if elig == "conditional" {
+			a.EligibilityNotes = "Different graduation year"
+		}
+		if err := db.CreateApplication(g.ctx, a); err != nil {
+			return fmt.Errorf("application: %w", err)
+		}
+		g.sum.Applications++
+
+		interviewed := status == "interview_scheduled" || isOffer(status) || (status == "rejected" && g.chance(0.3))
+		if interviewed {
+			if err := g.interview(s, j, a); err != nil {
+				return err
+			}
+		}
+		if status != "applied" {
+			updated := g.now.Format(time.RFC3339)
+			if err := db.UpdateApplication(g.ctx, a.ID, db.ApplicationPatch{Status: &status, UpdatedAt: &updated}); err != nil {
+				return fmt.Errorf("application status: %w", err)
+			}
+		}
+		if isOffer(status) {
+			g.sum.Offers++
+			g.notify(s.userID, "Offer from "+j.Title, fmt.Sprintf("You have been selected for %s.", j.Title), "offer")
+		} else if status == "shortlisted" {
+			g.notify(s.userID, "Shortlisted", fmt.Sprintf("You have been shortlisted for %s.", j.Title), "shortlist")
+		}
+	}
+	return nil
+}
+
+func (g *generator) interview(s student, j *job, a db.Application) error {
+	when := g.now.Add(g.days(1, 14))
+	if j.Status == "closed" {
+		when = g.now.Add(-g.days(1, 10))
+	}
+	when = time.Date(when.Year(), when.Month(), when.Day(), 9+g.rng.Intn(8), 0, 0, 0, time.UTC)
+	mode, location := "online", "Video call (link shared by email)"
+	if g.chance(0.4) {
+		mode, location = "offline", fmt.Sprintf("Placement Cell, Room %d", 101+g.rng.Intn(20))
+	}
+	rec := map[string]interface{}{
+		"id":             g.id(),
+		"application_id": a.ID,
+		"scheduled_at":   when.Format(time.RFC3339),
+		"location":       location,
+		"mode":           mode,
+		"created_at":     a.AppliedAt,
+	}
+	if err := db.CreateInterviewRecord(g.ctx, rec); err != nil {
+		return fmt.Errorf("interview: %w", err)
+	}
+	g.sum.Interviews++
+	g.notify(s.userID, "Interview scheduled", fmt.Sprintf("Your interview for %s is on %s.", j.Title, when.Format("2 Jan 15:04 MST")), "interview")
+	return nil
+}
+
+// notify records a notification; failures are not fatal to the seed
+func (g *generator) notify(userID, title, message, kind string) {
+	_ = db.CreateNotification(g.ctx, db.Notification{
+		ID:        g.id(),
+		UserID:    userID,
+		Title:     title,
+		Message:   message,
+		Type:      kind,
+		CreatedAt: g.now.Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,131 @@
+// Package storage is the blob store for uploaded files (verification
+// documents, resumes). Blobs live on the local filesystem under BLOB_DIR and
+// are addressed by slash-separated keys such as "verification/<id>/<file>".
+package storage
+
+import (
+	"backend/telemetry"
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var root string
+
+// ErrInvalidKey is returned for keys that are empty or escape the blob root
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// Init prepares the blob directory dir (config BLOB_DIR)
+func Init(dir string) {
+	root = dir
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		telemetry.Fatal("failed to create blob directory", "dir", root, "error", err)
+	}
+	slog.Info("blob store ready", "dir", root)
+}
+
+// Root returns the directory blobs are stored in
+func Root() string {
+	return root
+}
+
+func pathFor(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || clean == "." || filepath.IsAbs(clean) || strings.HasPrefix(clean, "..") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(root, clean), nil
+}
+
+// Put writes r to key, replacing any existing blob, and returns the bytes written.
+// The blob is written to a temp file first so readers never see a partial file.
+func Put(key string, r io.Reader) (int64, error) {
+	p, err := pathFor(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		_ = os.Remove(tmp.Name())
+		return 0, err
+	}
+	return n, nil
+}
+
+// Open returns a reader for key. The caller must close it.
+func Open(key string) (io.ReadCloser, error) {
+	p, err := pathFor(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// Size returns the length in bytes of the blob at key
+func Size(key string) (int64, error) {
+	p, err := pathFor(key)
+	if err != nil {
+		return 0, err
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+// Delete removes key. Deleting a missing blob is not an error.
+func Delete(key string) error {
+	p, err := pathFor(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Probe writes a small blob, reads it back and deletes it, proving the
+// store is usable
+func Probe() error {
+	key := ".healthcheck/" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	want := []byte("probe " + key)
+	if _, err := Put(key, bytes.NewReader(want)); err != nil {
+		return err
+	}
+	defer Delete(key)
+	rc, err := Open(key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want) {
+		return errors.New("blob read back differs from what was written")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Package telemetry holds the backend's observability plumbing: structured
+// logging, Prometheus metrics and OpenTelemetry tracing. It depends on the
+// standard library and the OpenTelemetry SDK only, so every other package can
+// use it.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Context keys under which the HTTP middleware stores the request and user
+// ids. Handlers pass the gin context down, which resolves string keys
+// through c.Get, so every log record made with it carries both.
+const (
+	RequestIDKey = "requestID"
+	UserIDKey    = "userID"
+)
+
+// InitLogging makes a JSON (or, with format "text", logfmt) slog logger the
+// default at level. The standard library log package is routed through it
+// too.
+func InitLogging(format, level string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("log level %q: %w", level, err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "text":
+		h = slog.NewTextHandler(os.Stderr, opts)
+	default:
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(contextHandler{h}))
+	log.SetFlags(0)
+	return nil
+}
+
+// contextHandler adds the request id, user id and trace ids found in the
+// record's context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id, ok := ctx.Value(RequestIDKey).(string); ok && id != "" {
+			r.AddAttrs(slog.String("request_id", id))
+		}
+		if id, ok := ctx.Value(UserIDKey).(string); ok && id != "" {
+			r.AddAttrs(slog.String("user_id", id))
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Fatal logs msg at error level and exits
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
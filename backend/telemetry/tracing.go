@@ -0,0 +1,183 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName identifies the backend in exported traces
+const ServiceName = "placement-backend"
+
+// Tracer creates the spans around handlers and storage calls. Until
+// InitTracing installs a provider it is a no-op.
+func Tracer() trace.Tracer {
+	return otel.Tracer("backend")
+}
+
+// InitTracing exports spans to the OTLP/HTTP collector at endpoint (for a
+// local collector, http://localhost:4318). An empty endpoint leaves tracing
+// off. The returned function flushes pending spans and must be called on
+// shutdown.
+func InitTracing(endpoint string) (shutdown func(context.Context) error) {
+	// W3C trace context is read from and forwarded in request headers either way
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if endpoint == "" {
+		return func(context.Context) error { return nil }
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(&otlpExporter{
+		url:    strings.TrimRight(endpoint, "/") + "/v1/traces",
+		client: &http.Client{Timeout: 10 * time.Second},
+	}))
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown
+}
+
+// otlpExporter posts spans to a collector using the OTLP/HTTP JSON encoding.
+// It covers what the backend records (string, bool and numeric attributes,
+// events and status), which keeps the gRPC and protobuf exporters out of
+// the build.
+type otlpExporter struct {
+	url    string
+	client *http.Client
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string          `json:"timeUnixNano"`
+	Name         string          `json:"name"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Events            []otlpEvent     `json:"events,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+func otlpAttributes(kvs []attribute.KeyValue) []otlpAttribute {
+	out := make([]otlpAttribute, 0, len(kvs))
+	for _, kv := range kvs {
+		var v otlpValue
+		switch kv.Value.Type() {
+		case attribute.BOOL:
+			b := kv.Value.AsBool()
+			v.BoolValue = &b
+		case attribute.INT64:
+			i := strconv.FormatInt(kv.Value.AsInt64(), 10)
+			v.IntValue = &i
+		case attribute.FLOAT64:
+			f := kv.Value.AsFloat64()
+			v.DoubleValue = &f
+		default:
+			s := kv.Value.Emit()
+			v.StringValue = &s
+		}
+		out = append(out, otlpAttribute{Key: string(kv.Key), Value: v})
+	}
+	return out
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// otlpStatusCode maps the API's status codes (Unset, Error, Ok) to OTLP's
+// (UNSET, OK, ERROR)
+func otlpStatusCode(c codes.Code) int {
+	switch c {
+	case codes.Ok:
+		return 1
+	case codes.Error:
+		return 2
+	}
+	return 0
+}
+
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		sp := otlpSpan{
+			TraceID:           s.SpanContext().TraceID().String(),
+			SpanID:            s.SpanContext().SpanID().String(),
+			Name:              s.Name(),
+			Kind:              int(s.SpanKind()),
+			StartTimeUnixNano: unixNano(s.StartTime()),
+			EndTimeUnixNano:   unixNano(s.EndTime()),
+			Attributes:        otlpAttributes(s.Attributes()),
+			Status:            otlpStatus{Code: otlpStatusCode(s.Status().Code), Message: s.Status().Description},
+		}
+		if p := s.Parent(); p.IsValid() {
+			sp.ParentSpanID = p.SpanID().String()
+		}
+		for _, ev := range s.Events() {
+			sp.Events = append(sp.Events, otlpEvent{TimeUnixNano: unixNano(ev.Time), Name: ev.Name, Attributes: otlpAttributes(ev.Attributes)})
+		}
+		out = append(out, sp)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource":   map[string]interface{}{"attributes": otlpAttributes([]attribute.KeyValue{attribute.String("service.name", ServiceName)})},
+			"scopeSpans": []interface{}{map[string]interface{}{"scope": map[string]string{"name": "backend"}, "spans": out}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp export: %s", resp.Status)
+	}
+	return nil
+}
+
+func (e *otlpExporter) Shutdown(context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
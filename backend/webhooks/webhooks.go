@@ -0,0 +1,261 @@
+// Package webhooks sends domain events to the webhooks companies register
+// for their ATS integrations. Each event a webhook wants becomes a stored
+// delivery; the sender posts it, signed with the webhook's secret, and
+// retries a failed attempt with exponential backoff. Receivers should
+// deduplicate on the event id in the body, since an attempt that timed out
+// may still have arrived, and a replay sends the same event again.
+package webhooks
+
+import (
+	"backend/config"
+	"backend/db"
+	"backend/events"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// batchSize bounds the deliveries one run sends
+	batchSize = 25
+	// maxAttempts is how often a delivery is tried before it is marked failed
+	maxAttempts = 8
+	// timeout bounds one attempt, including reading the response
+	timeout = 10 * time.Second
+)
+
+// Request headers. The signature is "sha256=" and the hex HMAC-SHA256 of
+// the timestamp, a dot and the body, keyed with the webhook's secret;
+// receivers should also reject timestamps too far from their own clock.
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// allowPrivate lets receivers use plain http and sit at loopback, private
+// and link-local addresses. It is set by Init from WEBHOOK_ALLOW_PRIVATE; in
+// production a webhook must not reach internal services.
+var allowPrivate = false
+
+// Init sets the receiver policy from the configuration
+func Init(cfg config.Webhooks) {
+	allowPrivate = cfg.AllowPrivate
+}
+
+// AllowPrivate reports whether receivers may use plain http and addresses
+// that are not public
+func AllowPrivate() bool {
+	return allowPrivate
+}
+
+// ErrPrivateAddress is returned for a receiver whose address is not public
+var ErrPrivateAddress = errors.New("webhook address is not public")
+
+// reservedPrefixes are not private in the netip sense but still not
+// reachable on the public internet
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// publicAddr reports whether a receiver may be at a
+func publicAddr(a netip.Addr) bool {
+	a = a.Unmap()
+	if !a.IsGlobalUnicast() || a.IsPrivate() {
+		return false
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(a) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL returns ErrPrivateAddress when the host of u resolves to an
+// address that is not public, unless private receivers are allowed
+func CheckURL(ctx context.Context, u string) error {
+	if allowPrivate {
+		return nil
+	}
+	p, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", p.Hostname())
+	if err != nil {
+		return err
+	}
+	for _, a := range addrs {
+		if !publicAddr(a) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
+
+// dialControl refuses to connect to an address that is not public. The name
+// checked at registration may resolve elsewhere by the time of sending.
+func dialControl(_, address string, _ syscall.RawConn) error {
+	if allowPrivate {
+		return nil
+	}
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(ap.Addr()) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// client does not follow redirects: a receiver answering 3xx has moved and
+// the company should update its webhook rather than have payloads follow
+// the redirect elsewhere. It uses no proxy, so that dialControl sees the
+// receiver's own address.
+var client = &http.Client{
+	Timeout: timeout,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: timeout, Control: dialControl}).DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: timeout,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// payload is the JSON body sent for an event
+type payload struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	CreatedAt string                 `json:"created_at"`
+	CompanyID string                 `json:"company_id"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// NewDelivery builds the pending delivery of event e to webhook w, due at
+// now. id identifies it, so creating it again for the same event conflicts.
+func NewDelivery(id string, w db.Webhook, e db.Event, now time.Time) (db.WebhookDelivery, error) {
+	body, err := json.Marshal(payload{ID: e.ID, Type: e.Type, CreatedAt: e.CreatedAt, CompanyID: e.CompanyID, Data: e.Data})
+	if err != nil {
+		return db.WebhookDelivery{}, err
+	}
+	ts := now.UTC().Format(time.RFC3339)
+	return db.WebhookDelivery{
+		ID:            id,
+		WebhookID:     w.ID,
+		CompanyID:     w.CompanyID,
+		EventID:       e.ID,
+		EventType:     e.Type,
+		Body:          string(body),
+		Status:        db.DeliveryPending,
+		NextAttemptAt: ts,
+		CreatedAt:     ts,
+	}, nil
+}
+
+// Sign returns the signature header value of body sent at timestamp
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run is the scheduler task that sends the deliveries due at now
+func Run(ctx context.Context, now time.Time) error {
+	due, err := db.DueWebhookDeliveries(ctx, now.UTC().Format(time.RFC3339), batchSize)
+	if err != nil {
+		return err
+	}
+	for _, d := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		w, err := db.GetWebhook(ctx, d.WebhookID)
+		if errors.Is(err, db.ErrNotFound) {
+			// deleted since; its deliveries went with it
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		attempt(ctx, w, &d, now)
+		if err := db.SaveWebhookDeliveryAttempt(ctx, d); err != nil && !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// attempt sends d once and records the outcome: succeeded, a retry or,
+// after maxAttempts, failed
+func attempt(ctx context.Context, w db.Webhook, d *db.WebhookDelivery, now time.Time) {
+	status, err := send(ctx, w, *d)
+	d.Attempts++
+	d.ResponseStatus = status
+	if err == nil {
+		d.Status, d.LastError, d.NextAttemptAt = db.DeliverySucceeded, "", ""
+		d.DeliveredAt = now.UTC().Format(time.RFC3339)
+		return
+	}
+	d.LastError = err.Error()
+	if d.Attempts >= maxAttempts {
+		d.Status, d.NextAttemptAt = db.DeliveryFailed, ""
+		slog.WarnContext(ctx, "webhook delivery abandoned", "delivery", d.ID, "webhook", w.ID, "attempts", d.Attempts, "error", err)
+		return
+	}
+	d.NextAttemptAt = now.Add(events.Backoff(d.Attempts)).UTC().Format(time.RFC3339)
+}
+
+// send posts d to w and returns the response status; any status outside
+// 2xx is an error
+func send(ctx context.Context, w db.Webhook, d db.WebhookDelivery) (int, error) {
+	body := []byte(d.Body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	// the time of sending, not of the run, which may have started a while ago
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "placement-backend-webhooks")
+	req.Header.Set(HeaderID, d.ID)
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, Sign(w.Secret, ts, body))
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain a little so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,156 @@
+package webhooks
+
+import (
+	"backend/db"
+	"backend/events"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"e1"}`)
+	const want = "sha256=8ee46ed58974c7df02c09b735c40376cd8352878cced403d2e3004133ef56ded"
+	if got := Sign("whsec_test", "1760000000", body); got != want {
+		t.Fatalf("Sign = %s, want %s", got, want)
+	}
+	for name, got := range map[string]string{
+		"secret":    Sign("whsec_other", "1760000000", body),
+		"timestamp": Sign("whsec_test", "1760000001", body),
+		"body":      Sign("whsec_test", "1760000000", []byte(`{"id":"e2"}`)),
+	} {
+		if got == want {
+			t.Errorf("signature does not cover the %s", name)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{50, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := events.Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// receiver is a local webhook receiver answering with status; it checks the
+// signature of every request it gets
+func receiver(t *testing.T, secret string, status *int) *httptest.Server {
+	t.Helper()
+	allowPrivate = true
+	t.Cleanup(func() { allowPrivate = false })
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got := r.Header.Get(HeaderSignature); got != Sign(secret, r.Header.Get(HeaderTimestamp), body) {
+			t.Errorf("signature header %q does not match the body", got)
+		}
+		w.WriteHeader(*status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAttempt(t *testing.T) {
+	status := http.StatusInternalServerError
+	srv := receiver(t, "whsec_test", &status)
+	w := db.Webhook{ID: "w1", CompanyID: "c1", URL: srv.URL, Secret: "whsec_test"}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	d, err := NewDelivery("d1", w, db.Event{ID: "e1", Type: db.EventJobPublished, CompanyID: "c1"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempt(context.Background(), w, &d, now)
+	if d.Status != db.DeliveryPending || d.Attempts != 1 || d.ResponseStatus != 500 || d.LastError == "" {
+		t.Fatalf("after a failed attempt: %+v", d)
+	}
+	if want := now.Add(30 * time.Second).Format(time.RFC3339); d.NextAttemptAt != want {
+		t.Errorf("next attempt at %s, want %s", d.NextAttemptAt, want)
+	}
+
+	d.Attempts = maxAttempts - 1
+	attempt(context.Background(), w, &d, now)
+	if d.Status != db.DeliveryFailed || d.NextAttemptAt != "" {
+		t.Fatalf("after the last attempt: %+v", d)
+	}
+
+	status = http.StatusNoContent
+	d.Status, d.Attempts = db.DeliveryPending, 2
+	attempt(context.Background(), w, &d, now)
+	if d.Status != db.DeliverySucceeded || d.LastError != "" || d.NextAttemptAt != "" || d.DeliveredAt == "" {
+		t.Fatalf("after a successful attempt: %+v", d)
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.215.14", true},
+		{"2606:2800:21f:cb07:6820:80da:af6b:8b2c", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+	}
+	for _, tt := range tests {
+		if got := publicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	ctx := context.Background()
+	for _, u := range []string{"https://127.0.0.1/hook", "https://169.254.169.254/latest/meta-data", "http://[::1]:8080/", "https://localhost/hook"} {
+		if err := CheckURL(ctx, u); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("CheckURL(%s) = %v, want ErrPrivateAddress", u, err)
+		}
+	}
+	allowPrivate = true
+	defer func() { allowPrivate = false }()
+	if err := CheckURL(ctx, "https://127.0.0.1/hook"); err != nil {
+		t.Errorf("CheckURL with private networks allowed = %v", err)
+	}
+}
+
+// The sender checks the address it connects to, whatever the name resolved
+// to when the webhook was registered
+func TestSendRefusesPrivateAddress(t *testing.T) {
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hit = true }))
+	defer srv.Close()
+
+	w := db.Webhook{ID: "w1", URL: srv.URL, Secret: "whsec_test"}
+	d := db.WebhookDelivery{ID: "d1", EventType: db.EventJobPublished, Body: "{}"}
+	if _, err := send(context.Background(), w, d); !errors.Is(err, ErrPrivateAddress) || hit {
+		t.Fatalf("send to a loopback receiver = %v (reached: %v), want ErrPrivateAddress", err, hit)
+	}
+}
@@ -8,10 +8,26 @@ interface RequestOptions extends RequestInit {
 
 async function request(path: string, opts: RequestOptions = {}) {
   const url = path.startsWith('http') ? path : `${API_BASE}${path.startsWith('/') ? path : '/' + path}`;
-  const res = await fetch(url, opts);
+  // send the session token when we have one so the backend can apply role-based views
+  const token = localStorage.getItem('userSessionToken');
+  const headers = new Headers(opts.headers);
+  if (token && !headers.has('Authorization')) {
+    headers.set('Authorization', `Bearer ${token}`);
+  }
+  const res = await fetch(url, { ...opts, headers });
   if (!res.ok) {
     const text = await res.text().catch(() => '');
-    throw new Error(`HTTP ${res.status}: ${text}`);
+    // errors come back as { error: { code, message, details, request_id } }
+    let message = text;
+    try {
+      const body = JSON.parse(text);
+      if (body?.error?.message) {
+        message = body.error.request_id ? `${body.error.message} (request ${body.error.request_id})` : body.error.message;
+      }
+    } catch {
+      // not JSON; keep the raw text
+    }
+    throw new Error(`HTTP ${res.status}: ${message}`);
   }
   const contentType = res.headers.get('content-type') || '';
   if (contentType.includes('application/json')) {
@@ -0,0 +1,177 @@
+/*
+  # Align the schema with the Go backend's Postgres storage
+
+  1. Identity
+    - Profile ids are Firebase UIDs, which are not UUIDs: `profiles.id` and
+      every column referencing it become text
+    - The row level security policies were written against `auth.uid()`,
+      which is always NULL since authentication moved to Firebase. They are
+      dropped (they also block the column type changes); RLS stays enabled,
+      so only the backend's database role, which bypasses RLS, can reach
+      the data
+
+  2. Columns the backend stores
+    - `companies`: email, approved, updated_at
+    - `job_postings`: publish_at, published_at, closed_at, updated_at,
+      deleted_at (soft delete); role becomes optional
+    - `applications`: created_at; status also allows 'closed' (set when a
+      job posting is withdrawn)
+    - `student_profiles.roll_number` and `resumes.file_name` become optional
+
+  3. New tables
+    - `company_members` (company team roles)
+    - `verification_requests` (company verification workflow)
+    - `audit_log` (append-only change history)
+
+  4. Constraints and indexes
+    - Unique constraints are renamed to the names the backend reports
+      conflicts by; profile emails are unique case-insensitively
+    - Secondary indexes match the Mongo backend's
+*/
+
+DROP POLICY IF EXISTS "Users can view own profile" ON profiles;
+DROP POLICY IF EXISTS "Users can update own profile" ON profiles;
+DROP POLICY IF EXISTS "Admins can view all profiles" ON profiles;
+DROP POLICY IF EXISTS "Students can view own profile" ON student_profiles;
+DROP POLICY IF EXISTS "Students can update own profile" ON student_profiles;
+DROP POLICY IF EXISTS "Students can insert own profile" ON student_profiles;
+DROP POLICY IF EXISTS "Recruiters can view student profiles for their applications" ON student_profiles;
+DROP POLICY IF EXISTS "Students can view own resumes" ON resumes;
+DROP POLICY IF EXISTS "Students can insert own resumes" ON resumes;
+DROP POLICY IF EXISTS "Students can delete own resumes" ON resumes;
+DROP POLICY IF EXISTS "Anyone can view verified companies" ON companies;
+DROP POLICY IF EXISTS "Recruiters can view own companies" ON companies;
+DROP POLICY IF EXISTS "Recruiters can insert own companies" ON companies;
+DROP POLICY IF EXISTS "Recruiters can update own companies" ON companies;
+DROP POLICY IF EXISTS "Admins can update all companies" ON companies;
+DROP POLICY IF EXISTS "Students can view active jobs" ON job_postings;
+DROP POLICY IF EXISTS "Recruiters can view own jobs" ON job_postings;
+DROP POLICY IF EXISTS "Recruiters can insert jobs" ON job_postings;
+DROP POLICY IF EXISTS "Recruiters can update own jobs" ON job_postings;
+DROP POLICY IF EXISTS "Students can view own applications" ON applications;
+DROP POLICY IF EXISTS "Students can insert own applications" ON applications;
+DROP POLICY IF EXISTS "Recruiters can view applications for their jobs" ON applications;
+DROP POLICY IF EXISTS "Recruiters can update applications for their jobs" ON applications;
+DROP POLICY IF EXISTS "Students can view own interviews" ON interviews;
+DROP POLICY IF EXISTS "Recruiters can manage interviews for their jobs" ON interviews;
+DROP POLICY IF EXISTS "Users can view own notifications" ON notifications;
+DROP POLICY IF EXISTS "Users can update own notifications" ON notifications;
+DROP POLICY IF EXISTS "Admins can view all placement stats" ON placement_stats;
+DROP POLICY IF EXISTS "Students can view own placement stats" ON placement_stats;
+
+-- Firebase UIDs as profile ids
+ALTER TABLE student_profiles DROP CONSTRAINT IF EXISTS student_profiles_user_id_fkey;
+ALTER TABLE companies DROP CONSTRAINT IF EXISTS companies_recruiter_id_fkey;
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_user_id_fkey;
+
+ALTER TABLE profiles ALTER COLUMN id TYPE text USING id::text;
+ALTER TABLE student_profiles ALTER COLUMN user_id TYPE text USING user_id::text;
+ALTER TABLE companies ALTER COLUMN recruiter_id TYPE text USING recruiter_id::text;
+ALTER TABLE notifications ALTER COLUMN user_id TYPE text USING user_id::text;
+
+ALTER TABLE student_profiles ADD CONSTRAINT student_profiles_user_id_fkey
+  FOREIGN KEY (user_id) REFERENCES profiles(id) ON DELETE CASCADE;
+ALTER TABLE companies ADD CONSTRAINT companies_recruiter_id_fkey
+  FOREIGN KEY (recruiter_id) REFERENCES profiles(id) ON DELETE CASCADE;
+ALTER TABLE notifications ADD CONSTRAINT notifications_user_id_fkey
+  FOREIGN KEY (user_id) REFERENCES profiles(id) ON DELETE CASCADE;
+
+-- Columns
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS email text;
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS approved boolean DEFAULT false;
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS updated_at timestamptz DEFAULT now();
+UPDATE companies SET approved = verified WHERE approved IS DISTINCT FROM verified;
+
+ALTER TABLE job_postings ALTER COLUMN role DROP NOT NULL;
+ALTER TABLE job_postings ADD COLUMN IF NOT EXISTS publish_at timestamptz;
+ALTER TABLE job_postings ADD COLUMN IF NOT EXISTS published_at timestamptz;
+ALTER TABLE job_postings ADD COLUMN IF NOT EXISTS closed_at timestamptz;
+ALTER TABLE job_postings ADD COLUMN IF NOT EXISTS updated_at timestamptz DEFAULT now();
+ALTER TABLE job_postings ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+ALTER TABLE applications ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT now();
+UPDATE applications SET created_at = applied_at WHERE applied_at IS NOT NULL;
+ALTER TABLE applications DROP CONSTRAINT IF EXISTS applications_status_check;
+ALTER TABLE applications ADD CONSTRAINT applications_status_check
+  CHECK (status IN ('applied', 'shortlisted', 'interview_scheduled', 'selected', 'rejected', 'offer_accepted', 'offer_rejected', 'closed'));
+
+ALTER TABLE student_profiles ALTER COLUMN roll_number DROP NOT NULL;
+ALTER TABLE resumes ALTER COLUMN file_name DROP NOT NULL;
+
+-- Company team roles
+CREATE TABLE IF NOT EXISTS company_members (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  company_id uuid NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+  user_id text NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+  role text NOT NULL CHECK (role IN ('owner', 'recruiter', 'interviewer')),
+  invited_by text,
+  created_at timestamptz DEFAULT now(),
+  CONSTRAINT uniq_company_members_company_user UNIQUE (company_id, user_id)
+);
+
+ALTER TABLE company_members ENABLE ROW LEVEL SECURITY;
+
+INSERT INTO company_members (company_id, user_id, role, invited_by, created_at)
+SELECT id, recruiter_id, 'owner', 'migration:company_owner_memberships', created_at
+FROM companies
+WHERE recruiter_id IS NOT NULL
+ON CONFLICT (company_id, user_id) DO NOTHING;
+
+-- Company verification
+CREATE TABLE IF NOT EXISTS verification_requests (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  company_id uuid NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+  submitted_by text REFERENCES profiles(id) ON DELETE SET NULL,
+  registration_number text,
+  tax_id text,
+  address text,
+  contact_name text,
+  contact_phone text,
+  documents jsonb DEFAULT '[]'::jsonb,
+  status text DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'changes_requested')),
+  review_notes text,
+  reviewed_by text REFERENCES profiles(id) ON DELETE SET NULL,
+  reviewed_at timestamptz,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now()
+);
+
+ALTER TABLE verification_requests ENABLE ROW LEVEL SECURITY;
+
+-- Audit trail. Actors and entities are plain text: entries outlive the
+-- records they describe, and system actions have no profile.
+CREATE TABLE IF NOT EXISTS audit_log (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  actor_id text,
+  actor_role text,
+  action text NOT NULL,
+  entity text NOT NULL,
+  entity_id text NOT NULL,
+  diff jsonb,
+  ip text,
+  request_id text,
+  created_at timestamptz DEFAULT now()
+);
+
+ALTER TABLE audit_log ENABLE ROW LEVEL SECURITY;
+
+-- Unique constraints, named as the backend reports them
+ALTER TABLE applications RENAME CONSTRAINT applications_job_id_student_id_key TO uniq_applications_job_student;
+ALTER TABLE student_profiles RENAME CONSTRAINT student_profiles_roll_number_key TO uniq_student_profiles_roll_number;
+ALTER TABLE student_profiles RENAME CONSTRAINT student_profiles_user_id_key TO uniq_student_profiles_user;
+ALTER TABLE profiles DROP CONSTRAINT IF EXISTS profiles_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_profiles_email ON profiles (lower(email));
+
+-- Secondary indexes
+CREATE INDEX IF NOT EXISTS idx_applications_student ON applications (student_id);
+CREATE INDEX IF NOT EXISTS idx_applications_job_status ON applications (job_id, status);
+CREATE INDEX IF NOT EXISTS idx_job_postings_company ON job_postings (company_id);
+CREATE INDEX IF NOT EXISTS idx_job_postings_status ON job_postings (status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_companies_recruiter ON companies (recruiter_id);
+CREATE INDEX IF NOT EXISTS idx_company_members_user ON company_members (user_id);
+CREATE INDEX IF NOT EXISTS idx_resumes_student ON resumes (student_id);
+CREATE INDEX IF NOT EXISTS idx_interviews_application ON interviews (application_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_verification_requests_company ON verification_requests (company_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log (entity, entity_id);
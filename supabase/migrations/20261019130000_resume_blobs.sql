@@ -0,0 +1,11 @@
+/*
+  # Store resume files in the backend's blob store
+
+  1. Columns
+    - `resumes.blob_key`: key of the uploaded file in the blob store
+    - `resumes.content_type`, `resumes.size`: served with downloads
+*/
+
+ALTER TABLE resumes ADD COLUMN IF NOT EXISTS blob_key text;
+ALTER TABLE resumes ADD COLUMN IF NOT EXISTS content_type text;
+ALTER TABLE resumes ADD COLUMN IF NOT EXISTS size bigint;
@@ -0,0 +1,16 @@
+/*
+  # Optimistic concurrency versions
+
+  1. Columns
+    - `version` on profiles, student_profiles, companies, job_postings,
+      applications and verification_requests: 1 when a row is created and
+      incremented by every update the backend makes. Updates conditional on
+      the version a client read fail instead of overwriting a newer change.
+*/
+
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE student_profiles ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE job_postings ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE applications ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE verification_requests ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,32 @@
+/*
+  # Transactional outbox for domain events
+
+  1. New tables
+    - `outbox`: domain events, written in the same transaction as the change
+      they describe and delivered to subscribers by the backend.
+      `delivered` lists the subscribers that have accepted an event;
+      `next_attempt_at` schedules the next retry of a pending one
+
+  2. Indexes
+    - Pending events by due time, for the dispatcher
+*/
+
+CREATE TABLE IF NOT EXISTS outbox (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  type text NOT NULL,
+  entity text NOT NULL,
+  entity_id text NOT NULL,
+  company_id text,
+  data jsonb,
+  created_at timestamptz DEFAULT now(),
+  status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'dispatched', 'failed')),
+  delivered jsonb,
+  attempts integer NOT NULL DEFAULT 0,
+  next_attempt_at timestamptz DEFAULT now(),
+  last_error text,
+  dispatched_at timestamptz
+);
+
+ALTER TABLE outbox ENABLE ROW LEVEL SECURITY;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON outbox (status, next_attempt_at);
@@ -0,0 +1,52 @@
+/*
+  # Company webhooks
+
+  1. New tables
+    - `webhooks`: a company's subscription to domain events, posted to `url`
+      and signed with `secret`; `event_types` lists the events it receives
+    - `webhook_deliveries`: one event sent to one webhook, with the body as
+      signed and the outcome of the attempts so far. A replay is a new row
+      naming the delivery it repeats in `replay_of`
+
+  2. Security
+    - RLS enabled on both tables; they are only reached through the backend
+
+  3. Indexes
+    - Webhooks by company
+    - Deliveries by company and time, for the delivery log
+    - Pending deliveries by due time, for the sender
+*/
+
+CREATE TABLE IF NOT EXISTS webhooks (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  company_id uuid NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+  url text NOT NULL,
+  event_types jsonb,
+  secret text NOT NULL,
+  created_by text,
+  created_at timestamptz DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  webhook_id uuid NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+  company_id uuid NOT NULL,
+  event_id text NOT NULL,
+  event_type text NOT NULL,
+  body text NOT NULL,
+  status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+  attempts integer NOT NULL DEFAULT 0,
+  next_attempt_at timestamptz DEFAULT now(),
+  response_status integer,
+  last_error text,
+  replay_of uuid,
+  created_at timestamptz DEFAULT now(),
+  delivered_at timestamptz
+);
+
+ALTER TABLE webhooks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhook_deliveries ENABLE ROW LEVEL SECURITY;
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_company ON webhooks (company_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_company ON webhook_deliveries (company_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);